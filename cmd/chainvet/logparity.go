@@ -7,6 +7,19 @@ import (
 	"go/types"
 )
 
+// logKVFuncs maps the full name of each function
+// taking key-value args to the number of args preceding them.
+var logKVFuncs = map[string]int{
+	"chain/log.Printkv":     1, // ctx
+	"chain/log.Fatalkv":     1, // ctx
+	"chain/log.RecordSince": 3, // ctx, name, t0
+	"chain/log.Deprecated":  2, // ctx, feature
+	"chain/log.Audit":       2, // ctx, event
+
+	"(*chain/log.Logger).Printkv": 1, // ctx
+	"(*chain/log.Logger).With":    0,
+}
+
 func init() {
 	register("logparity",
 		"check parity of key-value args in log invocations",
@@ -30,12 +43,13 @@ func checkLogCall(f *File, node ast.Node) {
 		return
 	}
 
-	if fun.FullName() != "chain/log.Printkv" && fun.FullName() != "chain/log.Fatalkv" {
+	nfixed, ok := logKVFuncs[fun.FullName()]
+	if !ok {
 		return
 	}
 
-	// Form of arguments is (ctx, key1, val1, key2, val2, ...)
-	narg := len(call.Args) - 1 // Subtract one for the context.
+	// Form of arguments is (ctx, [fixed args...,] key1, val1, key2, val2, ...)
+	narg := len(call.Args) - nfixed
 	if narg%2 == 1 && call.Ellipsis == 0 {
 		f.Badf(call.Pos(), "odd number of arguments in call to %s.%s", sel.X, sel.Sel)
 	}
@@ -2,7 +2,11 @@
 
 package testdata
 
-import "chain/log"
+import (
+	"time"
+
+	"chain/log"
+)
 
 // PrintkvTests never executes, but it serves as a simple test for the program.
 // Test with (cd ..; go test).
@@ -13,6 +17,10 @@ func PrintkvTests() {
 	log.Printkv(nil, "k")                 // ERROR "odd number of arguments in call to log.Printkv"
 	log.Printkv(nil, "k", "v", "k2")      // ERROR "odd number of arguments in call to log.Printkv"
 
+	log.RecordSince(nil, "op", time.Time{}, "k", "v") // ok
+	log.RecordSince(nil, "op", time.Time{})           // zero is ok too
+	log.RecordSince(nil, "op", time.Time{}, "k")      // ERROR "odd number of arguments in call to log.RecordSince"
+
 	var log writer
 	log.Printkv(nil, "k", "v")       // ok
 	log.Printkv(nil)                 // zero is ok too
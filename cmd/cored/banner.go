@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+
+	"chain/core/config"
+	"chain/env"
+	chainlog "chain/log"
+)
+
+// plainEnv lists config vars read directly with os.Getenv
+// rather than registered with package env.
+var plainEnv = []string{"SPLUNKADDR", "LOGFILE", "AUDIT_LOGFILE"}
+
+// secretEnv lists config vars holding credentials,
+// which configHash leaves out, so the hash doesn't change
+// when a token is rotated and can't be used to guess one.
+var secretEnv = map[string]bool{
+	"SPLUNK_HEC_TOKEN": true,
+	"LOG_HTTP_HEADER":  true, // typically an Authorization header
+}
+
+// logBanner writes a single entry describing this process:
+// its build, enabled features, a hash of its configuration,
+// and the addresses it listens on.
+func logBanner(ctx context.Context, addrs ...net.Addr) {
+	var listen []string
+	for _, a := range addrs {
+		listen = append(listen, a.String())
+	}
+	chainlog.Printkv(ctx,
+		chainlog.KeyMessage, "cored starting",
+		"buildtag", buildTag,
+		"commit", buildCommit,
+		"builddate", buildDate,
+		"goversion", runtime.Version(),
+		"features", strings.Join(features(), ","),
+		"confighash", configHash(),
+		"listen", strings.Join(listen, ","),
+	)
+}
+
+// features returns the names of the optional
+// features compiled into this binary.
+func features() []string {
+	var a []string
+	for _, f := range []struct {
+		name string
+		on   bool
+	}{
+		{"mockhsm", config.BuildConfig.MockHSM},
+		{"localhost_auth", config.BuildConfig.LocalhostAuth},
+		{"reset", config.BuildConfig.Reset},
+		{"http_ok", config.BuildConfig.HTTPOk},
+		{"init_cluster", config.BuildConfig.InitCluster},
+		{"race", len(race) > 0},
+	} {
+		if f.on {
+			a = append(a, f.name)
+		}
+	}
+	return a
+}
+
+// configHash returns a hash of the non-empty config vars
+// in the environment, so processes started with the same
+// configuration can be recognized in aggregated logs.
+// Vars in secretEnv are skipped, and passwords
+// in URL values are removed before hashing.
+func configHash() string {
+	names := append(env.Names(), plainEnv...)
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		v := os.Getenv(name)
+		if v == "" || secretEnv[name] {
+			continue
+		}
+		h.Write([]byte(name + "=" + redact(v) + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// redact returns v with the password removed,
+// if v is a URL containing one.
+func redact(v string) string {
+	u, err := url.Parse(v)
+	if err != nil || u.User == nil {
+		return v
+	}
+	if _, ok := u.User.Password(); !ok {
+		return v
+	}
+	u.User = url.User(u.User.Username())
+	return u.String()
+}
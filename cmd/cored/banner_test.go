@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestConfigHashSecrets(t *testing.T) {
+	for name := range secretEnv {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Setenv(name, "secret-1")
+	}
+	h1 := configHash()
+	for name := range secretEnv {
+		os.Setenv(name, "secret-2")
+	}
+	if h2 := configHash(); h2 != h1 {
+		t.Errorf("configHash = %s after changing secrets, want %s", h2, h1)
+	}
+
+	defer os.Setenv("LOGFILE", os.Getenv("LOGFILE"))
+	os.Setenv("LOGFILE", "/var/log/cored-test.log")
+	if h3 := configHash(); h3 == h1 {
+		t.Errorf("configHash = %s after changing LOGFILE, want a different hash", h3)
+	}
+}
@@ -2,4 +2,29 @@
 
 package main
 
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	chainlog "chain/log"
+	"chain/log/rotation"
+)
+
 func maybeMonitorIfOnWindows() {}
+
+// reopenLogsOnHangup reopens the log files
+// each time the process receives SIGHUP,
+// so that logrotate can rename them
+// and signal cored, without copytruncate.
+func reopenLogsOnHangup(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		for range sig {
+			rotation.ReopenAll()
+			chainlog.Printkv(ctx, chainlog.KeyMessage, "reopened log files")
+		}
+	}()
+}
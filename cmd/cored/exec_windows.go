@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/exec"
@@ -21,6 +22,9 @@ func maybeMonitorIfOnWindows() {
 	}
 }
 
+// reopenLogsOnHangup does nothing; there is no SIGHUP on Windows.
+func reopenLogsOnHangup(ctx context.Context) {}
+
 func inChild() bool {
 	return os.Args[0] == "coredchild"
 }
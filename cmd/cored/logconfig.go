@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"chain/errors"
+	chainlog "chain/log"
+)
+
+// logConfig is the format of the logging configuration
+// file named by LOG_CONFIG.
+// Empty fields keep the settings given by flags
+// and environment at startup.
+type logConfig struct {
+	Level   string          `json:"level"`  // as in -log-level
+	Format  string          `json:"format"` // as in -log-format
+	Outputs []logOutputSpec `json:"outputs"`
+
+	// Filters discard entries. Each discards those
+	// having every field it lists, as by chainlog.Match,
+	// such as {"path": "/health"}.
+	Filters []map[string]string `json:"filters"`
+}
+
+// logOutputSpec describes one log destination.
+// Entries are written to every destination in logConfig.Outputs,
+// wrapped as for -log-output, with LOG_FAILOVER,
+// and to LOG_ARCHIVE_URL and -log-ring, if set.
+type logOutputSpec struct {
+	Output   string `json:"output"`    // as in -log-output
+	MaxSize  int    `json:"max_size"`  // bytes per file, default LOGSIZE
+	MaxFiles int    `json:"max_files"` // rotated files kept, default LOGCOUNT
+}
+
+// logConfigWatcher applies a logging configuration file,
+// and applies it again whenever its contents change.
+type logConfigWatcher struct {
+	path string
+
+	// settings in effect at startup
+	level  chainlog.Level
+	format chainlog.Format
+	output io.Writer
+	tee    []io.Writer // added to configured outputs
+
+	prev    []byte                      // contents last loaded
+	lastErr string                      // last error reported
+	outputs map[logOutputSpec]io.Writer // in use; reused across reloads
+	filters []func()                    // remove the filters in use
+}
+
+// newLogConfigWatcher returns a watcher for the file at path.
+// Settings absent from the file are as given here;
+// output already includes tee, which gets every entry.
+func newLogConfigWatcher(path string, level chainlog.Level, format chainlog.Format, output io.Writer, tee ...io.Writer) *logConfigWatcher {
+	return &logConfigWatcher{
+		path:    path,
+		level:   level,
+		format:  format,
+		output:  output,
+		tee:     tee,
+		outputs: make(map[logOutputSpec]io.Writer),
+	}
+}
+
+// watch checks the configuration file for changes
+// every interval until ctx is canceled.
+// Errors are logged, and the previous configuration
+// stays in effect.
+func (w *logConfigWatcher) watch(ctx context.Context, interval time.Duration) {
+	ticks := time.NewTicker(interval)
+	defer ticks.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks.C:
+		}
+		err := w.load(ctx)
+		if err == nil {
+			w.lastErr = ""
+		} else if err.Error() != w.lastErr {
+			w.lastErr = err.Error()
+			chainlog.Error(ctx, err)
+		}
+	}
+}
+
+// load reads the configuration file and,
+// if its contents have changed, applies it.
+func (w *logConfigWatcher) load(ctx context.Context) error {
+	b, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		return errors.Wrap(err, "reading log config")
+	}
+	if bytes.Equal(b, w.prev) {
+		return nil
+	}
+
+	var c logConfig
+	err = json.Unmarshal(b, &c)
+	if err != nil {
+		return errors.Wrap(err, "parsing log config "+w.path)
+	}
+	level, format, output := w.level, w.format, w.output
+	if c.Level != "" {
+		level, err = chainlog.ParseLevel(c.Level)
+		if err != nil {
+			return errors.Wrap(err, "log config "+w.path)
+		}
+	}
+	if c.Format != "" {
+		format, err = chainlog.ParseFormat(c.Format)
+		if err != nil {
+			return errors.Wrap(err, "log config "+w.path)
+		}
+	}
+	for _, f := range c.Filters {
+		if len(f) == 0 {
+			return errors.New("log config " + w.path + ": empty filter")
+		}
+	}
+	used := make(map[logOutputSpec]bool)
+	if len(c.Outputs) > 0 {
+		var a []io.Writer
+		for _, spec := range c.Outputs {
+			spec, ow, err := w.open(spec)
+			if err != nil {
+				return errors.Wrap(err, "log config "+w.path)
+			}
+			used[spec] = true
+			a = append(a, ow)
+		}
+		a = append(a, w.tee...)
+		output = a[0]
+		if len(a) > 1 {
+			output = chainlog.FanOut(a...)
+		}
+	}
+
+	chainlog.SetLevel(level)
+	chainlog.SetFormat(format)
+	chainlog.SetOutput(output)
+	w.setFilters(c.Filters)
+	w.closeUnused(used)
+	w.prev = b
+	chainlog.Printkv(ctx,
+		chainlog.KeyMessage, "applied log config",
+		"path", w.path,
+		"minlevel", level,
+		"format", format,
+		"outputs", len(c.Outputs),
+		"filters", len(c.Filters),
+	)
+	return nil
+}
+
+// open returns spec, with defaults filled in, and a writer for it,
+// reusing the one opened by an earlier load, if any.
+func (w *logConfigWatcher) open(spec logOutputSpec) (logOutputSpec, io.Writer, error) {
+	if spec.MaxSize == 0 {
+		spec.MaxSize = *logSize
+	}
+	if spec.MaxFiles == 0 {
+		spec.MaxFiles = *logCount
+	}
+	if ow, ok := w.outputs[spec]; ok {
+		return spec, ow, nil
+	}
+	ow, err := openLogOutput(spec.Output, spec.MaxSize, spec.MaxFiles)
+	if err != nil {
+		return spec, nil, err
+	}
+	ow = withFailover(ow, spec.Output)
+	w.outputs[spec] = ow
+	return spec, ow, nil
+}
+
+// setFilters replaces the filters added by the last load
+// with those in a, adding the new ones first
+// so no entry escapes both.
+func (w *logConfigWatcher) setFilters(a []map[string]string) {
+	var remove []func()
+	for _, f := range a {
+		var keyval []interface{}
+		for k, v := range f {
+			keyval = append(keyval, k, v)
+		}
+		remove = append(remove, chainlog.AddFilter(chainlog.Match(keyval...)))
+	}
+	for _, r := range w.filters {
+		r()
+	}
+	w.filters = remove
+}
+
+// closeUnused closes the outputs, opened by earlier loads,
+// that aren't in used. Once SetOutput has replaced
+// the log output, nothing else writes to them.
+func (w *logConfigWatcher) closeUnused(used map[logOutputSpec]bool) {
+	for spec, ow := range w.outputs {
+		if used[spec] {
+			continue
+		}
+		delete(w.outputs, spec)
+		if spec.Output == "stdout" || spec.Output == "stderr" {
+			continue // the process's own; keep it open
+		}
+		if c, ok := ow.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	chainlog "chain/log"
+	"chain/log/logtest"
+)
+
+// writeLogConfig writes a log config file holding config
+// and returns its path and a function to remove it.
+func writeLogConfig(t *testing.T, config string) (path string, remove func()) {
+	dir, err := ioutil.TempDir("", "logconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, "log.json")
+	err = ioutil.WriteFile(path, []byte(config), 0644)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLogConfigLoad(t *testing.T) {
+	path, remove := writeLogConfig(t, `{"level":"debug"}`)
+	defer remove()
+
+	old := chainlog.Output()
+	defer chainlog.SetOutput(old)
+	defer chainlog.SetLevel(chainlog.CurrentLevel())
+	defer chainlog.SetFormat(chainlog.KV)
+
+	out := new(logtest.Recorder)
+	w := newLogConfigWatcher(path, chainlog.LevelInfo, chainlog.KV, out)
+	err := w.load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chainlog.CurrentLevel(); got != chainlog.LevelDebug {
+		t.Errorf("level = %v want debug", got)
+	}
+	if got := out.Find("minlevel", "debug"); len(got) != 1 {
+		t.Errorf("entries = %v, want one with minlevel=debug", out.Entries())
+	}
+}
+
+func TestLogConfigFilters(t *testing.T) {
+	path, remove := writeLogConfig(t, `{"filters":[{"module":"noisy"}]}`)
+	defer remove()
+
+	old := chainlog.Output()
+	defer chainlog.SetOutput(old)
+	defer chainlog.SetFormat(chainlog.KV)
+
+	ctx := context.Background()
+	out := new(logtest.Recorder)
+	w := newLogConfigWatcher(path, chainlog.LevelInfo, chainlog.KV, out)
+	err := w.load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.setFilters(nil)
+
+	chainlog.Printkv(ctx, "module", "noisy", "n", 1)
+	chainlog.Printkv(ctx, "module", "quiet", "n", 2)
+	if got := out.Find("module", "noisy"); len(got) != 0 {
+		t.Errorf("entries = %v, want module=noisy filtered", got)
+	}
+	if got := out.Find("module", "quiet"); len(got) != 1 {
+		t.Errorf("entries = %v, want module=quiet", out.Entries())
+	}
+}
+
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestLogConfigCloseUnused(t *testing.T) {
+	path, remove := writeLogConfig(t, `{}`)
+	defer remove()
+
+	old := chainlog.Output()
+	defer chainlog.SetOutput(old)
+	defer chainlog.SetFormat(chainlog.KV)
+
+	w := newLogConfigWatcher(path, chainlog.LevelInfo, chainlog.KV, new(bytes.Buffer))
+	prev := new(closeRecorder)
+	w.outputs[logOutputSpec{Output: "/tmp/prev.log"}] = prev
+	err := w.load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !prev.closed {
+		t.Error("output dropped from the config was not closed")
+	}
+	if len(w.outputs) != 0 {
+		t.Errorf("outputs = %v, want none", w.outputs)
+	}
+}
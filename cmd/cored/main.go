@@ -20,6 +20,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/kr/secureheader"
 
 	"chain/core"
@@ -38,11 +41,22 @@ import (
 	"chain/errors"
 	"chain/generated/rev"
 	chainlog "chain/log"
+	"chain/log/archive"
+	"chain/log/fluent"
+	"chain/log/gelf"
+	"chain/log/journald"
+	"chain/log/kafka"
+	"chain/log/ndjson"
+	logotlp "chain/log/otlp"
 	"chain/log/rotation"
 	"chain/log/splunk"
+	"chain/log/statsd"
+	"chain/log/syslog"
+	"chain/metrics/otlp"
 	"chain/net/http/authz"
 	"chain/net/http/limit"
 	"chain/net/http/reqid"
+	"chain/net/http/serverlog"
 	"chain/net/raft"
 	"chain/protocol"
 	"chain/protocol/bc"
@@ -60,13 +74,47 @@ var (
 	dbURL         = env.String("DATABASE_URL", "postgres:///core?sslmode=disable")
 	splunkAddr    = os.Getenv("SPLUNKADDR")
 	logFile       = os.Getenv("LOGFILE")
+	auditLogFile  = os.Getenv("AUDIT_LOGFILE")
 	logSize       = env.Int("LOGSIZE", 5e6) // 5MB
 	logCount      = env.Int("LOGCOUNT", 9)
+	logRotate     = env.Duration("LOG_ROTATE_INTERVAL", 0) // e.g. 24h; 0 to rotate by size only
+	logCompress   = env.Bool("LOG_COMPRESS", false)        // gzip rotated log files
+	logMaxAge     = env.Duration("LOG_MAX_AGE", 0)         // remove older rotated files; 0 to keep LOGCOUNT
+	logBuffer     = env.Int("LOG_BUFFER", 0)               // bytes of log lines to write at once; 0 to write each entry
+	logBufferTime = env.Duration("LOG_BUFFER_INTERVAL", time.Second)
+	logConfigFile = env.String("LOG_CONFIG", "")
 	logQueries    = env.Bool("LOG_QUERIES", false)
+	logQueriesPct = env.Int("LOG_QUERIES_PERCENT", 100)
+	logTxAdmits   = env.Int("LOG_TX_ADMISSIONS_PERCENT", 0)
 	maxDBConns    = env.Int("MAXDBCONNS", 10)           // set to 100 in prod
 	rpsToken      = env.Int("RATELIMIT_TOKEN", 0)       // reqs/sec
 	rpsRemoteAddr = env.Int("RATELIMIT_REMOTE_ADDR", 0) // reqs/sec
 	indexTxs      = env.Bool("INDEX_TRANSACTIONS", true)
+	connStatsFreq = env.Duration("CONN_STATS_INTERVAL", time.Minute) // 0 to disable
+	logConnReuse  = env.Bool("LOG_CONN_REUSE", false)
+	dbStatsFreq   = env.Duration("DB_POOL_STATS_INTERVAL", 0) // 0 to disable
+	slowQuery     = env.Duration("SLOW_QUERY_THRESHOLD", 0)   // 0 to disable
+	slowExplain   = env.Int("SLOW_QUERY_EXPLAIN_PERCENT", 0)
+	accessLog     = env.Bool("ACCESS_LOG", false)
+	accessExclude = env.StringSlice("ACCESS_LOG_EXCLUDE_PATHS", "/health")
+	accessAgents  = env.StringSlice("ACCESS_LOG_EXCLUDE_AGENTS")
+	logMaxBytes   = env.Int("LOG_VOLUME_ALERT_BYTES", 0)      // bytes/sec, 0 to disable
+	logMaxEntries = env.Int("LOG_VOLUME_ALERT_ENTRIES", 0)    // entries/sec, 0 to disable
+	statusFreq    = env.Duration("STATUS_LOG_INTERVAL", 0)    // 0 to disable
+	runtimeStats  = env.Duration("RUNTIME_STATS_INTERVAL", 0) // 0 to disable
+	heartbeatFreq = env.Duration("LOG_HEARTBEAT_INTERVAL", 0) // 0 to disable
+	statsdAddr    = env.String("STATSD_ADDR", "")             // host:port, empty to disable
+	statsdPrefix  = env.String("STATSD_PREFIX", "chain.")
+	statsdTags    = env.Bool("STATSD_DOGSTATSD", false)
+	otlpMetrics   = env.String("OTLP_METRICS_URL", "")                    // e.g. http://localhost:4318/v1/metrics
+	sinkFailLimit = env.Duration("LOG_SINK_FAILURE_LIMIT", 5*time.Minute) // 0 to disable
+	logDev        = env.Bool("LOG_DEV", false)                            // default -log-format to dev
+	hecToken      = env.String("SPLUNK_HEC_TOKEN", "")                    // for hec+https:// log outputs
+	logHTTPHeader = env.String("LOG_HTTP_HEADER", "")                     // "Name: value", for ndjson+https:// log outputs
+	logArchive    = env.String("LOG_ARCHIVE_URL", "")                     // s3://bucket/prefix or gs://bucket/prefix, empty to disable
+	archiveFreq   = env.Duration("LOG_ARCHIVE_INTERVAL", time.Hour)
+	archiveGzip   = env.Bool("LOG_ARCHIVE_COMPRESS", true)
+	logFailover   = env.Bool("LOG_FAILOVER", false) // write to stderr while the log output fails
 	home          = config.HomeDirFromEnvironment()
 
 	version string // initialized in init()
@@ -110,6 +158,27 @@ func init() {
 
 func main() {
 	v := flag.Bool("version", false, "print version information")
+	logFormat := flag.String("log-format", os.Getenv(chainlog.EnvFormat), "encoding of log entries: kv, logfmt, console, json, gelf, cef, leef, otlp, proto, rfc5424, journal, or dev (default CHAIN_LOG_FORMAT if set, else dev if LOG_DEV is set, console on a terminal, gelf, otlp, rfc5424, journal, or json for those outputs, kv otherwise)")
+	logOutput := flag.String("log-output", os.Getenv(chainlog.EnvOutput), "log destination: stdout, stderr, a file path, or a file://, tcp://, gelf+udp://, gelf+tcp://, otlp+http(s)://, hec+http(s)://, syslog://, syslog+udp/tcp/tls://, kafka://, fluent://, ndjson+http(s)://, or journald: URL (default CHAIN_LOG_OUTPUT if set, else from LOGFILE and SPLUNKADDR)")
+	logLevel := flag.String("log-level", chainlog.CurrentLevel().String(), "minimum severity of log entries: debug, info, warning, or error; CHAIN_LOG_LEVEL sets the default")
+	logVModule := flag.String("log-vmodule", "", "per-package minimum severity of log entries, overriding -log-level: comma-separated pattern=level rules matched against import paths, e.g. chain/protocol/...=debug")
+	logDrop := flag.String("log-drop", "", "comma-separated key=value fields, such as path=/health; log entries with any of them are discarded")
+	logSample := flag.Int("log-sample", 0, "keep 1 in this many non-error log entries from a call site beyond -log-sample-burst in a second (0 disables sampling)")
+	logSampleBurst := flag.Int("log-sample-burst", 100, "log entries per second from each call site kept before -log-sample applies")
+	logDedup := flag.Duration("log-dedup", 0, "coalesce identical consecutive log entries into one with a repeated=N field, written at least this often during a run (0 disables)")
+	logRequestRate := flag.Float64("log-request-rate", 0, "log entries per second allowed for each request beyond -log-request-burst (0 disables limiting)")
+	logRequestBurst := flag.Int("log-request-burst", 1000, "log entries each request may write before -log-request-rate applies")
+	logDebugBuffer := flag.Int("log-debug-buffer", 0, "debug log entries to keep for each request, below -log-level, and write if the request logs an error (0 disables)")
+	logBytes := flag.String("log-bytes", "hex", "encoding of byte-slice log values: hex or base64")
+	logBytesMax := flag.Int("log-bytes-max", chainlog.DefaultBytesMax, "longest byte-slice log value to encode in full, in bytes (0 for no limit)")
+	logShards := flag.Int("log-shards", 0, "spread log entries over this many buffers, written out together, to reduce lock contention (0 writes each entry at once)")
+	logShardInterval := flag.Duration("log-shard-interval", 100*time.Millisecond, "longest a log entry waits in a -log-shards buffer")
+	logRing := flag.Int("log-ring", 0, "keep this many of the latest log entries in memory, served at /debug/logring (0 keeps none)")
+	logRingFile := flag.String("log-ring-file", "", "on a fatal error, write the -log-ring entries to this file")
+	logDuplicateKeys := flag.String("log-duplicate-keys", "keep", "fields with the same key in one log entry: keep all, or write only the last or first")
+	logDurationUnit := flag.Duration("log-duration-unit", 0, "unit of duration log values, written as plain numbers, such as 1ms (0 writes them as Go durations such as 1.5s)")
+	logTimeFormat := flag.String("log-time-format", "rfc3339nano", "encoding of log entry times: rfc3339, rfc3339milli, rfc3339nano, epochmillis, epochnanos, or a Go time layout")
+	logTimeZone := flag.String("log-timezone", "UTC", "time zone of log entry times: UTC, Local, or an IANA zone name")
 	flag.Parse()
 
 	if !*v {
@@ -137,6 +206,131 @@ func main() {
 	env.Parse()
 	warnCompat(ctx)
 
+	level, err := chainlog.ParseLevel(*logLevel)
+	if err != nil {
+		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+	}
+	chainlog.SetLevel(level)
+	err = chainlog.SetVModule(*logVModule)
+	if err != nil {
+		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+	}
+	for _, kv := range strings.Split(*logDrop, ",") {
+		if kv == "" {
+			continue
+		}
+		i := strings.Index(kv, "=")
+		if i < 0 {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, fmt.Sprintf("-log-drop: %q is not key=value", kv))
+		}
+		chainlog.AddFilter(chainlog.Match(kv[:i], kv[i+1:]))
+	}
+	chainlog.SetSampling(*logSampleBurst, *logSample)
+	chainlog.SetDedup(*logDedup)
+	chainlog.SetRequestRateLimit(*logRequestRate, *logRequestBurst)
+	chainlog.SetDebugBuffer(*logDebugBuffer)
+	switch *logBytes {
+	case "hex":
+		chainlog.SetBytesEncoding(chainlog.BytesHex, *logBytesMax)
+	case "base64":
+		chainlog.SetBytesEncoding(chainlog.BytesBase64, *logBytesMax)
+	default:
+		chainlog.Fatalkv(ctx, chainlog.KeyError, fmt.Sprintf("-log-bytes: unknown encoding %q", *logBytes))
+	}
+	switch *logDuplicateKeys {
+	case "keep":
+		chainlog.SetDuplicateKeys(chainlog.KeepDuplicates)
+	case "last":
+		chainlog.SetDuplicateKeys(chainlog.LastWins)
+	case "first":
+		chainlog.SetDuplicateKeys(chainlog.FirstWins)
+	default:
+		chainlog.Fatalkv(ctx, chainlog.KeyError, fmt.Sprintf("-log-duplicate-keys: unknown policy %q", *logDuplicateKeys))
+	}
+	chainlog.SetDurationUnit(*logDurationUnit)
+	chainlog.SetWriteShards(*logShards, *logShardInterval)
+	loc, err := time.LoadLocation(*logTimeZone)
+	if err != nil {
+		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+	}
+	chainlog.SetTimeFormat(timeLayout(*logTimeFormat), loc)
+	logOut, err := logWriter(*logOutput)
+	if err != nil {
+		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+	}
+	logOut = withFailover(logOut, *logOutput)
+	var logTee []io.Writer // get every entry, whatever the outputs
+	var logArchiver io.Writer
+	if *logArchive != "" {
+		logArchiver, err = archiveWriter(*logArchive)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+		logTee = append(logTee, logArchiver)
+	}
+	if *logRing > 0 {
+		ring := chainlog.NewRing(*logRing)
+		chainlog.SetRing(ring, *logRingFile)
+		logTee = append(logTee, ring)
+	}
+	if len(logTee) > 0 {
+		logOut = chainlog.FanOut(append([]io.Writer{logOut}, logTee...)...)
+	}
+	term := logTerminal(*logOutput)
+	format := chainlog.KV
+	if *logFormat != "" {
+		format, err = chainlog.ParseFormat(*logFormat)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+	} else if *logDev {
+		format = chainlog.Dev
+	} else if term != nil {
+		format = chainlog.DefaultFormat(term)
+	} else if strings.HasPrefix(*logOutput, "gelf+") {
+		format = chainlog.GELF
+	} else if strings.HasPrefix(*logOutput, "otlp+") {
+		format = chainlog.OTLP
+	} else if strings.HasPrefix(*logOutput, "syslog") {
+		format = chainlog.RFC5424
+	} else if strings.HasPrefix(*logOutput, "journald") {
+		format = chainlog.Journal
+	} else if strings.HasPrefix(*logOutput, "fluent") || strings.HasPrefix(*logOutput, "ndjson+") {
+		format = chainlog.JSON
+	}
+	if term != nil {
+		chainlog.SetColor(chainlog.UseColor(term))
+	}
+	chainlog.SetFormat(format)
+	chainlog.SetDevice("Chain", "cored", config.Version)
+	reopenLogsOnHangup(ctx)
+
+	if *logMaxBytes > 0 || *logMaxEntries > 0 {
+		chainlog.SetVolumeAlert(float64(*logMaxBytes), float64(*logMaxEntries), func(v chainlog.Volume) {
+			chainlog.Printkv(ctx,
+				"warning", "log volume over limit",
+				"bytespersec", v.BytesPerSec,
+				"entriespersec", v.EntriesPerSec,
+			)
+		})
+	}
+	if *runtimeStats > 0 {
+		go logRuntimeStats(ctx, *runtimeStats)
+	}
+	if *heartbeatFreq > 0 {
+		go chainlog.Heartbeat(ctx, *heartbeatFreq)
+	}
+	if *statsdAddr != "" {
+		exporter, err := statsd.New(*statsdAddr, *statsdPrefix, *statsdTags)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+		go exporter.Run(ctx, 10*time.Second)
+	}
+	if *otlpMetrics != "" {
+		go otlp.New(*otlpMetrics, "cored").Run(ctx, 10*time.Second)
+	}
+
 	listener, err := net.Listen("tcp", *listenAddr)
 	if err != nil {
 		chainlog.Fatalkv(ctx, chainlog.KeyError, err)
@@ -184,7 +378,10 @@ func main() {
 
 	driver := pg.NewDriver()
 	if *logQueries {
-		driver = sqlutil.LogDriver(driver)
+		driver = sqlutil.LogDriver(driver, *logQueriesPct)
+	}
+	if *slowQuery > 0 {
+		driver = sqlutil.SlowQueryDriver(driver, *slowQuery, *slowExplain)
 	}
 	sql.Register("coredpg", driver)
 	db, err := sql.Open("coredpg", *dbURL)
@@ -193,6 +390,9 @@ func main() {
 	}
 	db.SetMaxOpenConns(*maxDBConns)
 	db.SetMaxIdleConns(*maxDBConns)
+	if *dbStatsFreq > 0 {
+		go sqlutil.LogPoolStats(ctx, db, *dbStatsFreq)
+	}
 
 	err = migrate.Run(db)
 	if err != nil {
@@ -238,7 +438,23 @@ func main() {
 		// https://github.com/golang/go/issues/16450
 		// https://github.com/golang/go/issues/17071
 		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){},
+		ErrorLog:     serverlog.ErrorLog(),
+	}
+	var connHooks []func(net.Conn, http.ConnState)
+	if tlsConfig != nil {
+		handshakes := serverlog.NewTLSHandshakes(tlsConfig)
+		server.ErrorLog = handshakes.ErrorLog()
+		connHooks = append(connHooks, handshakes.ConnState)
 	}
+	if *connStatsFreq > 0 || *logConnReuse {
+		connStats := serverlog.NewConnStats()
+		connHooks = append(connHooks, connStats.ConnState)
+		server.Handler = connStats.Handler(server.Handler, *logConnReuse)
+		if *connStatsFreq > 0 {
+			go connStats.Run(ctx, *connStatsFreq)
+		}
+	}
+	server.ConnState = serverlog.ConnState(connHooks...)
 
 	// The `Serve` call has to happen in its own goroutine because
 	// it's blocking and we need to proceed to the rest of the core setup after
@@ -278,17 +494,44 @@ func main() {
 	}
 	expvar.NewString("processID").Set(processID)
 
-	log.SetPrefix("cored-" + version + ": ")
-	log.SetFlags(log.Lshortfile)
-	chainlog.SetPrefix(append([]interface{}{"app", "cored", "version", version, "processID", processID}, race...)...)
-	chainlog.SetOutput(logWriter())
+	stderrLog = log.New(os.Stderr, "cored-"+version+": ", log.Lshortfile)
+	chainlog.CaptureStdlib("stdlib")
+	chainlog.SetPrefix(append([]interface{}{
+		"app", "cored",
+		"version", version,
+		"hostname", hostname,
+		"pid", os.Getpid(),
+		"processID", processID,
+	}, race...)...)
+	chainlog.SetOutput(logOut)
+	if *logConfigFile != "" {
+		w := newLogConfigWatcher(*logConfigFile, level, format, logOut, logTee...)
+		err = w.load(ctx)
+		if err != nil {
+			chainlog.Fatalkv(ctx, chainlog.KeyError, err)
+		}
+		go w.watch(ctx, 5*time.Second)
+	}
+	if auditLogFile != "" {
+		var audit io.Writer = newErrlog("audit", rotation.Create(auditLogFile, *logSize, *logCount, rotationOpts()...))
+		if logArchiver != nil {
+			audit = chainlog.FanOut(audit, logArchiver)
+		}
+		chainlog.SetAuditOutput(audit)
+	}
+	logBanner(ctx, listener.Addr())
+
+	var opts []core.RunOption
+	opts = append(opts, core.UseTLS(tlsConfig))
+	opts = append(opts, core.SinkFailureLimit(*sinkFailLimit))
+	if *accessLog {
+		opts = append(opts, core.AccessLog(*accessExclude, *accessAgents))
+	}
 
 	var h http.Handler
 	if conf != nil {
-		h = launchConfiguredCore(ctx, confOpts, sdb, db, conf, processID, httpClient, core.UseTLS(tlsConfig))
+		h = launchConfiguredCore(ctx, confOpts, sdb, db, conf, processID, httpClient, opts...)
 	} else {
-		var opts []core.RunOption
-		opts = append(opts, core.UseTLS(tlsConfig))
 		opts = append(opts, enableMockHSM(db)...)
 		chainlog.Printf(ctx, "Launching as unconfigured Core.")
 		h = core.RunUnconfigured(ctx, confOpts, db, sdb, *listenAddr, opts...)
@@ -343,6 +586,9 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 
 	opts = append(opts, core.IndexTransactions(*indexTxs))
 	opts = append(opts, enableMockHSM(db)...)
+	if *statusFreq > 0 {
+		opts = append(opts, core.StatusLog(*statusFreq))
+	}
 	// Add any configured API request rate limits.
 	if *rpsToken > 0 {
 		opts = append(opts, core.RateLimit(limit.AuthUserID, 2*(*rpsToken), *rpsToken))
@@ -374,6 +620,7 @@ func launchConfiguredCore(ctx context.Context, confOpts *config.Options, sdb *si
 		c.MaxIssuanceWindow = bc.MillisDuration(conf.MaxIssuanceWindowMs)
 
 		gen := generator.New(c, signers, db)
+		gen.AdmissionLogPercent = *logTxAdmits
 		opts = append(opts, core.GeneratorLocal(gen))
 	} else {
 		opts = append(opts, core.GeneratorRemote(&rpc.Client{
@@ -456,38 +703,252 @@ func (s *remoteSigner) String() string {
 	return s.Client.BaseURL
 }
 
-func logWriter() io.Writer {
+// withFailover wraps w, the writer for output,
+// to write to stderr while w fails, if LOG_FAILOVER is set.
+func withFailover(w io.Writer, output string) io.Writer {
+	if !*logFailover || output == "stdout" || output == "stderr" {
+		return w
+	}
+	return chainlog.Failover(w, chainlog.InstrumentSink("stderr", os.Stderr), 5, 30*time.Second)
+}
+
+// logWriter returns the log destination named by output,
+// as given in the -log-output flag.
+// If output is empty, it uses LOGFILE and SPLUNKADDR.
+func logWriter(output string) (io.Writer, error) {
+	if output != "" {
+		return openLogOutput(output, *logSize, *logCount)
+	}
+
 	dropmsg := []byte("\nlog data dropped\n")
-	rotation := &errlog{w: rotation.Create(logFile, *logSize, *logCount)}
-	splunk := &errlog{w: splunk.New(splunkAddr, dropmsg)}
+	rotation := newErrlog("file", rotation.Create(logFile, *logSize, *logCount, rotationOpts()...))
+	splunk := newErrlog("splunk", splunk.New(splunkAddr, dropmsg))
 
 	switch {
 	case logFile != "" && splunkAddr != "":
-		return io.MultiWriter(rotation, splunk)
+		return chainlog.FanOut(rotation, splunk), nil
 	case logFile != "" && splunkAddr == "":
-		return rotation
+		return rotation, nil
 	case logFile == "" && splunkAddr != "":
-		return splunk
+		return splunk, nil
+	}
+	return chainlog.InstrumentSink("stdout", os.Stdout), nil
+}
+
+// timeLayout returns the layout for chainlog.SetTimeFormat
+// named by name, as given in the -log-time-format flag.
+func timeLayout(name string) string {
+	switch name {
+	case "rfc3339":
+		return time.RFC3339
+	case "rfc3339milli":
+		return "2006-01-02T15:04:05.000Z07:00"
+	case "rfc3339nano":
+		return "2006-01-02T15:04:05.000000000Z07:00"
+	}
+	return name // epochmillis, epochnanos, or a layout
+}
+
+// archiveWriter returns a writer archiving log entries
+// to the bucket named by rawurl, as given in LOG_ARCHIVE_URL:
+// s3://bucket/prefix for S3, in the region set by AWS_REGION,
+// or gs://bucket/prefix for Google Cloud Storage, through
+// its S3-compatible API. Credentials come from the environment
+// as for other AWS clients; for GCS, they are HMAC keys.
+func archiveWriter(rawurl string) (io.Writer, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing log archive URL")
+	}
+	config := aws.NewConfig()
+	switch u.Scheme {
+	case "s3":
+	case "gs":
+		config = config.WithEndpoint("https://storage.googleapis.com").WithRegion("auto")
+	default:
+		return nil, fmt.Errorf("unsupported log archive scheme %q", u.Scheme)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "log archive")
+	}
+	opts := []archive.Option{archive.Every(*archiveFreq), archive.Prefix(strings.TrimPrefix(u.Path, "/"))}
+	if *archiveGzip {
+		opts = append(opts, archive.Compress())
+	}
+	return chainlog.InstrumentSink("archive", archive.New(archive.S3(s3.New(sess), u.Host), opts...)), nil
+}
+
+// rotationOpts returns the options for log files
+// set by LOG_ROTATE_INTERVAL, LOG_COMPRESS, LOG_MAX_AGE,
+// and LOG_BUFFER.
+func rotationOpts() []rotation.Option {
+	var opts []rotation.Option
+	if *logRotate > 0 {
+		opts = append(opts, rotation.Every(*logRotate))
+	}
+	if *logCompress {
+		opts = append(opts, rotation.Compress())
+	}
+	if *logMaxAge > 0 {
+		opts = append(opts, rotation.MaxAge(*logMaxAge))
+	}
+	if *logBuffer > 0 {
+		opts = append(opts, rotation.Buffer(*logBuffer, *logBufferTime))
 	}
-	return os.Stdout
+	return opts
 }
 
+// logTerminal returns the standard stream, if any,
+// that logWriter(output) writes to.
+func logTerminal(output string) *os.File {
+	switch {
+	case output == "stdout", output == "" && logFile == "" && splunkAddr == "":
+		return os.Stdout
+	case output == "stderr":
+		return os.Stderr
+	}
+	return nil
+}
+
+// openLogOutput returns a writer for the log destination output:
+// stdout, stderr, a file path, or a file://, tcp://,
+// gelf+udp://, gelf+tcp://, otlp+http(s)://, or hec+http(s):// URL.
+// A hec URL's query may set the index, source, and sourcetype;
+// its token is SPLUNK_HEC_TOKEN.
+// Files are rotated at size bytes, keeping count old files.
+func openLogOutput(output string, size, count int) (io.Writer, error) {
+	switch output {
+	case "":
+		return nil, errors.New("empty log output")
+	case "stdout":
+		return chainlog.InstrumentSink("stdout", os.Stdout), nil
+	case "stderr":
+		return chainlog.InstrumentSink("stderr", os.Stderr), nil
+	}
+
+	u, err := url.Parse(output)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing log output")
+	}
+	switch u.Scheme {
+	case "":
+		return newErrlog("file", rotation.Create(output, size, count, rotationOpts()...)), nil
+	case "file":
+		return newErrlog("file", rotation.Create(u.Path, size, count, rotationOpts()...)), nil
+	case "tcp":
+		return newErrlog("splunk", splunk.New(u.Host, []byte("\nlog data dropped\n"))), nil
+	case "gelf+udp", "gelf+tcp":
+		return newErrlog("gelf", gelf.New(strings.TrimPrefix(u.Scheme, "gelf+"), u.Host)), nil
+	case "hec+http", "hec+https":
+		q := u.Query()
+		u.Scheme = strings.TrimPrefix(u.Scheme, "hec+")
+		u.RawQuery = ""
+		return newErrlog("splunk-hec", splunk.NewHEC(splunk.HECConfig{
+			URL:        u.String(),
+			Token:      *hecToken,
+			Index:      q.Get("index"),
+			Source:     q.Get("source"),
+			SourceType: q.Get("sourcetype"),
+		})), nil
+	case "otlp+http", "otlp+https":
+		u.Scheme = strings.TrimPrefix(u.Scheme, "otlp+")
+		return newErrlog("otlp", logotlp.New(u.String(), "cored")), nil
+	case "syslog", "syslog+udp", "syslog+tcp", "syslog+tls":
+		q := u.Query()
+		facility := syslog.Daemon
+		if name := q.Get("facility"); name != "" {
+			facility, err = syslog.ParseFacility(name)
+			if err != nil {
+				return nil, err
+			}
+		}
+		network := strings.TrimPrefix(strings.TrimPrefix(u.Scheme, "syslog"), "+")
+		return newErrlog("syslog", syslog.New(network, u.Host, facility, q.Get("tag"))), nil
+	case "journald":
+		return newErrlog("journald", journald.New()), nil
+	case "fluent":
+		q := u.Query()
+		tag := q.Get("tag")
+		if tag == "" {
+			tag = "cored"
+		}
+		return newErrlog("fluent", fluent.New(fluent.Config{
+			Addr:       u.Host,
+			Tag:        tag,
+			RequireAck: q.Get("ack") != "",
+		})), nil
+	case "ndjson+http", "ndjson+https":
+		q := u.Query()
+		u.Scheme = strings.TrimPrefix(u.Scheme, "ndjson+")
+		u.RawQuery = ""
+		header := make(http.Header)
+		if kv := strings.SplitN(*logHTTPHeader, ":", 2); len(kv) == 2 {
+			header.Set(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+		return newErrlog("ndjson", ndjson.New(ndjson.Config{
+			URL:    u.String(),
+			Header: header,
+			Gzip:   q.Get("gzip") != "",
+		})), nil
+	case "kafka":
+		return newErrlog("kafka", kafka.New(kafka.Config{
+			Brokers:  strings.Split(u.Host, ","),
+			Topic:    strings.TrimPrefix(u.Path, "/"),
+			ClientID: "cored",
+		})), nil
+	}
+	return nil, fmt.Errorf("unsupported log output scheme %q", u.Scheme)
+}
+
+// stderrLog reports errors writing to log sinks.
+// The standard logger can't be used for this,
+// since its output is captured into chain/log.
+var stderrLog = log.New(os.Stderr, "", log.Lshortfile)
+
 type errlog struct {
 	w io.Writer
 	t time.Time // protected by chain/log mutex
 }
 
+func newErrlog(name string, w io.Writer) *errlog {
+	return &errlog{w: chainlog.InstrumentSink(name, w)}
+}
+
 func (w *errlog) Write(p []byte) (int, error) {
 	// We don't want to ruin our performance
 	// when there's a persistent error
 	// writing to a log sink.
 	// Print to stderr at most once per minute.
-	_, err := w.w.Write(p)
+	n, err := w.w.Write(p)
 	if err != nil && time.Since(w.t) > time.Minute {
-		log.Println("chain/log:", err)
+		stderrLog.Println("chain/log:", err)
 		w.t = time.Now()
 	}
-	return len(p), nil // report success for the MultiWriter
+	// The error is counted by chain/log and, if LOG_FAILOVER
+	// is set, sends the entry to stderr. FanOut isolates it
+	// from any other outputs.
+	return n, err
+}
+
+// Flush flushes the sink, so chainlog.Flush reaches
+// the log files it buffers.
+func (w *errlog) Flush() error {
+	if f, ok := w.w.(interface {
+		Flush() error
+	}); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Close closes the sink, if it has a Close method,
+// when the log config no longer names it.
+func (w *errlog) Close() error {
+	if c, ok := w.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 type waitHandler struct {
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"time"
+
+	chainlog "chain/log"
+)
+
+// logRuntimeStats writes a log entry describing the state
+// of the Go runtime once per interval until ctx is done,
+// so basic process health can be reconstructed from
+// the logs alone.
+func logRuntimeStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			chainlog.Printkv(ctx,
+				"message", "runtime stats",
+				"heapinuse", m.HeapInuse,
+				"heapobjects", m.HeapObjects,
+				"numgc", m.NumGC,
+				"gcpausetotal", time.Duration(m.PauseTotalNs),
+				"goroutines", runtime.NumGoroutine(),
+				"openfds", openFDs(),
+			)
+		}
+	}
+}
+
+// openFDs returns the number of file descriptors open
+// in this process, or -1 if it can't be determined
+// (for example, on systems without /dev/fd).
+func openFDs() int {
+	d, err := os.Open("/dev/fd")
+	if err != nil {
+		return -1
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return -1
+	}
+	return len(names) - 1 // don't count d itself
+}
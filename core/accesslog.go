@@ -0,0 +1,121 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"expvar"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"chain/log"
+)
+
+// accessLogExcluded counts requests left out of the access log,
+// keyed by path, so they remain visible in metrics.
+var accessLogExcluded = expvar.NewMap("accesslog_excluded")
+
+type accessLog struct {
+	excludePaths  map[string]bool
+	excludeAgents []string // matched as substrings of User-Agent
+}
+
+// AccessLog configures the Core to write a log entry for every
+// API request once it completes. Requests whose path is
+// in excludePaths, or whose User-Agent contains any of
+// excludeAgents, are left out of the log, so frequent
+// load-balancer health checks don't dominate it.
+func AccessLog(excludePaths, excludeAgents []string) RunOption {
+	return func(a *API) {
+		l := &accessLog{excludePaths: make(map[string]bool)}
+		for _, p := range excludePaths {
+			if p != "" {
+				l.excludePaths[p] = true
+			}
+		}
+		for _, ua := range excludeAgents {
+			if ua != "" {
+				l.excludeAgents = append(l.excludeAgents, ua)
+			}
+		}
+		a.accessLog = l
+	}
+}
+
+func (l *accessLog) excluded(req *http.Request) bool {
+	if l.excludePaths[req.URL.Path] {
+		return true
+	}
+	ua := req.UserAgent()
+	for _, s := range l.excludeAgents {
+		if strings.Contains(ua, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestHandler times each request served by next.
+// It records the latency in the request's per-route histogram
+// and, if the access log is enabled, writes an entry.
+// Requests excluded from the access log are still recorded
+// in the latency histograms. Requests rejected by the rate
+// limiter are not, nor are health checks, which aren't
+// served by the mux, so they don't skew the histograms.
+func (a *API) requestHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t0 := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, req)
+		d := time.Since(t0)
+
+		if sw.status != http.StatusTooManyRequests {
+			if l := latency(a.mux, req); l != nil {
+				l.Record(d)
+			}
+		}
+		if a.accessLog == nil {
+			return
+		}
+		if a.accessLog.excluded(req) {
+			accessLogExcluded.Add(req.URL.Path, 1)
+			return
+		}
+		log.Printkv(req.Context(),
+			"method", req.Method,
+			"status", sw.status,
+			"bytes", sw.n,
+			"duration", d,
+		)
+	})
+}
+
+// statusWriter records the status code and size
+// of the response written through it.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	n      int
+}
+
+var _ http.Hijacker = (*statusWriter)(nil)
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += n
+	return n, err
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("not a hijacker")
+	}
+	return h.Hijack()
+}
@@ -17,9 +17,9 @@ import (
 	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg"
 	"chain/errors"
-	"chain/log"
 	"chain/protocol"
 	"chain/protocol/vm/vmutil"
+	"chain/sync/job"
 )
 
 const maxAccountCache = 1000
@@ -68,19 +68,7 @@ func (m *Manager) IndexAccounts(indexer Saver) {
 // ExpireReservations removes reservations that have expired periodically.
 // It blocks until the context is canceled.
 func (m *Manager) ExpireReservations(ctx context.Context, period time.Duration) {
-	ticks := time.Tick(period)
-	for {
-		select {
-		case <-ctx.Done():
-			log.Printf(ctx, "Deposed, ExpireReservations exiting")
-			return
-		case <-ticks:
-			err := m.utxoDB.ExpireReservations(ctx)
-			if err != nil {
-				log.Error(ctx, err)
-			}
-		}
-	}
+	job.Every(ctx, "expirereservations", period, m.utxoDB.ExpireReservations)
 }
 
 type Account struct {
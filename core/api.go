@@ -53,6 +53,7 @@ var (
 	errNotFound         = errors.New("not found")
 	errRateLimited      = errors.New("request limit exceeded")
 	errNotAuthenticated = errors.New("not authenticated")
+	errMethodNotAllowed = errors.New("method not allowed")
 )
 
 // API serves the Chain HTTP API
@@ -83,12 +84,15 @@ type API struct {
 	indexTxs        bool
 	internalSubj    pkix.Name
 	httpClient      *http.Client
+	accessLog       *accessLog
+	statusInterval  time.Duration
 
 	downloadingSnapshotMu sync.Mutex
 	downloadingSnapshot   *fetch.SnapshotProgress
 
-	healthMu     sync.Mutex
-	healthErrors map[string]string
+	healthMu         sync.Mutex
+	healthErrors     map[string]string
+	sinkFailureLimit time.Duration // zero to ignore log sink failures
 }
 
 func (a *API) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
@@ -188,22 +192,19 @@ func (a *API) buildHandler() {
 	m.Handle("/config", jsonHandler(a.retrieveConfig))
 	m.Handle("/info", jsonHandler(a.info))
 
+	m.Handle("/metrics", metricsHandler)
 	m.Handle("/debug/vars", expvar.Handler())
+	m.Handle("/debug/logging", http.HandlerFunc(a.debugLogging))
+	m.Handle("/debug/loglevel", http.HandlerFunc(debugLogLevel))
+	m.Handle("/debug/logring", http.HandlerFunc(debugLogRing))
 	m.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
 	m.Handle("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
 	m.Handle("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
 	m.Handle("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
 
-	latencyHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if l := latency(m, req); l != nil {
-			defer l.RecordSince(time.Now())
-		}
-		m.ServeHTTP(w, req)
-	})
-
-	handler := maxBytes(latencyHandler) // TODO(tessr): consider moving this to non-core specific mux
+	handler := maxBytes(m) // TODO(tessr): consider moving this to non-core specific mux
 	handler = webAssetsHandler(handler)
-	handler = healthHandler(handler)
+	handler = a.healthHandler(handler)
 	for _, l := range a.requestLimits {
 		handler = limit.Handler(handler, alwaysError(errRateLimited), l.perSecond, l.burst, l.key)
 	}
@@ -213,6 +214,7 @@ func (a *API) buildHandler() {
 	if a.config != nil && a.config.BlockchainId != nil {
 		handler = blockchainIDHandler(handler, a.config.BlockchainId.String())
 	}
+	handler = a.requestHandler(handler)
 	handler = loggingHandler(handler)
 	a.handler = handler
 }
@@ -395,15 +397,6 @@ func (a *API) forwardToLeader(ctx context.Context, path string, body interface{}
 	return l.Call(ctx, path, body, resp)
 }
 
-func healthHandler(handler http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if req.URL.Path == "/health" {
-			return
-		}
-		handler.ServeHTTP(w, req)
-	})
-}
-
 func jsonHandler(f interface{}) http.Handler {
 	h, err := httpjson.Handler(f, errorFormatter.Write)
 	if err != nil {
@@ -61,7 +61,11 @@ var policyByRoute = map[string][]string{
 	"/config":                     {"client-readwrite", "client-readonly", "monitoring", "internal"},
 	"/info":                       {"client-readwrite", "client-readonly", "crosscore", "crosscore-signblock", "monitoring", "internal"},
 
-	"/debug/": {"client-readwrite", "client-readonly", "monitoring"},
+	"/debug/":         {"client-readwrite", "client-readonly", "monitoring"},
+	"/debug/logging":  {"client-readwrite"},
+	"/debug/loglevel": {"client-readwrite"},
+	"/debug/logring":  {"client-readwrite"},
+	"/metrics":        {"client-readwrite", "client-readonly", "monitoring"},
 
 	"/raft/": {"internal"},
 
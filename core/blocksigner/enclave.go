@@ -2,12 +2,15 @@ package blocksigner
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
+	"time"
 
 	"chain/core/rpc"
 	"chain/crypto/ed25519"
 	"chain/encoding/json"
 	"chain/log"
+	"chain/net/http/authn"
 	"chain/protocol/bc/legacy"
 )
 
@@ -25,7 +28,23 @@ type signRequestBody struct {
 	Pub   json.HexBytes       `json:"pubkey"`
 }
 
-func (ec EnclaveClient) Sign(ctx context.Context, pk ed25519.PublicKey, bh *legacy.BlockHeader) ([]byte, error) {
+func (ec EnclaveClient) Sign(ctx context.Context, pk ed25519.PublicKey, bh *legacy.BlockHeader) (sig []byte, err error) {
+	t0 := time.Now()
+	digest := bh.Hash()
+	defer func() {
+		outcome := "signed"
+		if err != nil {
+			outcome = "failed"
+		}
+		log.Audit(ctx, "sign",
+			"key", hex.EncodeToString(pk),
+			"digest", hex.EncodeToString(digest.Bytes()),
+			"principal", authn.Principal(ctx),
+			"duration", time.Since(t0),
+			"outcome", outcome,
+		)
+	}()
+
 	body := signRequestBody{Block: bh, Pub: json.HexBytes(pk[:])}
 
 	// grab the latest set of hsms from the configuration
@@ -49,7 +68,6 @@ func (ec EnclaveClient) Sign(ctx context.Context, pk ed25519.PublicKey, bh *lega
 		}()
 	}
 
-	var err error
 	for i := 0; i < len(hsmURLs); i++ {
 		res := <-ch
 		if res.err == nil {
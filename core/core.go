@@ -122,6 +122,7 @@ func (a *API) leaderInfo(ctx context.Context) (map[string]interface{}, error) {
 		"build_date":                        config.BuildDate,
 		"build_config":                      config.BuildConfig,
 		"health":                            a.health(),
+		"recent_errors":                     log.RecentErrors(),
 	}
 
 	// Add in snapshot information if we're downloading a snapshot.
@@ -186,6 +187,7 @@ func (a *API) configure(ctx context.Context, req configureRequest) error {
 	// If the old way of configuring a single HSM is used,
 	// transparently update the config options.
 	if req.Config.BlockHsmUrl != "" {
+		log.Deprecated(ctx, "configure.block_hsm_url")
 		tup := []string{req.Config.BlockHsmUrl, req.Config.BlockHsmAccessToken}
 		ops = append(ops, a.options.Add("enclave", tup))
 	}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+
+	"chain/errors"
+	"chain/log"
+	"chain/net/http/httpjson"
+)
+
+// loggingState is the response body of /debug/logging.
+type loggingState struct {
+	log.State
+	AccessLog *accessLogState `json:"access_log,omitempty"`
+}
+
+// accessLogState describes the filters
+// configured with the AccessLog option.
+type accessLogState struct {
+	ExcludePaths  []string `json:"exclude_paths"`
+	ExcludeAgents []string `json:"exclude_agents"`
+}
+
+// loggingUpdate is the request body of a PATCH to /debug/logging.
+// Absent fields are left unchanged.
+type loggingUpdate struct {
+	Level   *string `json:"level"`
+	Format  *string `json:"format"`
+	VModule *string `json:"vmodule"`
+}
+
+func (u loggingUpdate) String() string {
+	var a []string
+	if u.Level != nil {
+		a = append(a, "level="+*u.Level)
+	}
+	if u.Format != nil {
+		a = append(a, "format="+*u.Format)
+	}
+	if u.VModule != nil {
+		a = append(a, "vmodule="+*u.VModule)
+	}
+	return strings.Join(a, ",")
+}
+
+// debugLogging serves /debug/logging.
+// A GET reports the effective logging configuration
+// and the log package's counters.
+// A PATCH applies the fields present in the request body,
+// then reports the result as for GET.
+func (a *API) debugLogging(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	switch req.Method {
+	case "GET":
+	case "PATCH":
+		var u loggingUpdate
+		err := httpjson.Read(ctx, req.Body, &u)
+		if err != nil {
+			errorFormatter.Write(ctx, w, err)
+			return
+		}
+		err = applyLoggingUpdate(u)
+		if err != nil {
+			errorFormatter.Write(ctx, w, errors.WithDetail(httpjson.ErrBadRequest, err.Error()))
+			return
+		}
+		log.Printkv(ctx, log.KeyMessage, "logging updated", "update", u)
+	default:
+		errorFormatter.Write(ctx, w, errMethodNotAllowed)
+		return
+	}
+
+	st := loggingState{State: log.ReadState()}
+	if l := a.accessLog; l != nil {
+		st.AccessLog = &accessLogState{ExcludeAgents: l.excludeAgents}
+		for p := range l.excludePaths {
+			st.AccessLog.ExcludePaths = append(st.AccessLog.ExcludePaths, p)
+		}
+	}
+	httpjson.Write(ctx, w, http.StatusOK, st)
+}
+
+// logLevel is the request and response body of /debug/loglevel.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// debugLogLevel serves /debug/loglevel, a narrower form
+// of /debug/logging for scripts and operators that only
+// need to raise or lower the minimum log level.
+// A GET reports the level.
+// A PUT sets the level named in the request body,
+// then reports it as for GET.
+func debugLogLevel(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	switch req.Method {
+	case "GET":
+	case "PUT":
+		var l logLevel
+		err := httpjson.Read(ctx, req.Body, &l)
+		if err != nil {
+			errorFormatter.Write(ctx, w, err)
+			return
+		}
+		u := loggingUpdate{Level: &l.Level}
+		err = applyLoggingUpdate(u)
+		if err != nil {
+			errorFormatter.Write(ctx, w, errors.WithDetail(httpjson.ErrBadRequest, err.Error()))
+			return
+		}
+		log.Printkv(ctx, log.KeyMessage, "logging updated", "update", u)
+	default:
+		errorFormatter.Write(ctx, w, errMethodNotAllowed)
+		return
+	}
+	httpjson.Write(ctx, w, http.StatusOK, logLevel{Level: log.CurrentLevel().String()})
+}
+
+// applyLoggingUpdate validates every field of u
+// before applying any of them.
+func applyLoggingUpdate(u loggingUpdate) error {
+	var (
+		level  log.Level
+		format log.Format
+		err    error
+	)
+	if u.Level != nil {
+		level, err = log.ParseLevel(*u.Level)
+		if err != nil {
+			return err
+		}
+	}
+	if u.Format != nil {
+		format, err = log.ParseFormat(*u.Format)
+		if err != nil {
+			return err
+		}
+	}
+	if u.VModule != nil {
+		// SetVModule validates the whole spec
+		// before applying any of it.
+		err = log.SetVModule(*u.VModule)
+		if err != nil {
+			return err
+		}
+	}
+	if u.Level != nil {
+		log.SetLevel(level)
+	}
+	if u.Format != nil {
+		log.SetFormat(format)
+	}
+	return nil
+}
+
+// debugLogRing serves /debug/logring.
+// A GET writes the entries held by the log ring
+// registered with log.SetRing, oldest first,
+// or responds 404 if there is none.
+func debugLogRing(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	if req.Method != "GET" {
+		errorFormatter.Write(ctx, w, errMethodNotAllowed)
+		return
+	}
+	r := log.CurrentRing()
+	if r == nil {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	r.WriteTo(w)
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chain/log"
+)
+
+func TestApplyLoggingUpdate(t *testing.T) {
+	defer log.SetLevel(log.LevelInfo)
+
+	level, bad := "error", "loud"
+	err := applyLoggingUpdate(loggingUpdate{Level: &level, Format: &bad})
+	if err == nil {
+		t.Fatal("err = nil, want error for unknown format")
+	}
+	if got := log.ReadState().Level; got != "info" {
+		t.Errorf("level = %q after failed update, want info", got)
+	}
+
+	err = applyLoggingUpdate(loggingUpdate{Level: &level})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := log.ReadState().Level; got != "error" {
+		t.Errorf("level = %q want error", got)
+	}
+}
+
+func TestDebugLogLevel(t *testing.T) {
+	defer log.SetLevel(log.LevelInfo)
+
+	req := httptest.NewRequest("PUT", "/debug/loglevel", strings.NewReader(`{"level":"debug"}`))
+	rec := httptest.NewRecorder()
+	debugLogLevel(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d want 200: %s", rec.Code, rec.Body)
+	}
+	if got := log.CurrentLevel(); got != log.LevelDebug {
+		t.Errorf("level = %v want debug", got)
+	}
+
+	req = httptest.NewRequest("GET", "/debug/loglevel", nil)
+	rec = httptest.NewRecorder()
+	debugLogLevel(rec, req)
+	if w := `{"level":"debug"}`; strings.TrimSpace(rec.Body.String()) != w {
+		t.Errorf("body = %s want %s", rec.Body, w)
+	}
+
+	req = httptest.NewRequest("PUT", "/debug/loglevel", strings.NewReader(`{"level":"loud"}`))
+	rec = httptest.NewRecorder()
+	debugLogLevel(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d want 400", rec.Code)
+	}
+}
+
+func TestDebugLogRing(t *testing.T) {
+	req := httptest.NewRequest("GET", "/debug/logring", nil)
+	rec := httptest.NewRecorder()
+	debugLogRing(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d want 404 with no ring", rec.Code)
+	}
+
+	r := log.NewRing(10)
+	log.SetRing(r, "")
+	defer log.SetRing(nil, "")
+	r.Write([]byte("a=1\n"))
+	r.Write([]byte("b=2\n"))
+
+	rec = httptest.NewRecorder()
+	debugLogRing(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d want 200: %s", rec.Code, rec.Body)
+	}
+	if got, want := rec.Body.String(), "a=1\nb=2\n"; got != want {
+		t.Errorf("body = %q want %q", got, want)
+	}
+}
@@ -66,6 +66,7 @@ var errorFormatter = httperror.Formatter{
 		txbuilder.ErrMissingFields: {400, "CH010", "One or more fields are missing"},
 		authz.ErrNotAuthorized:     {403, "CH011", "Request is unauthorized"},
 		sinkdb.ErrConflict:         {409, "CH012", "Conflict processing request"},
+		errMethodNotAllowed:        {405, "CH013", "Method not allowed"},
 		asset.ErrDuplicateAlias:    {400, "CH050", "Alias already exists"},
 		account.ErrDuplicateAlias:  {400, "CH050", "Alias already exists"},
 		txfeed.ErrDuplicateAlias:   {400, "CH050", "Alias already exists"},
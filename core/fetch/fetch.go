@@ -167,10 +167,12 @@ func DownloadBlocks(ctx context.Context, peer *rpc.Client, height uint64) (chan
 }
 
 func applyBlock(ctx context.Context, c *protocol.Chain, prevSnap *state.Snapshot, prev *legacy.Block, block *legacy.Block) error {
+	t0 := time.Now()
 	err := c.ValidateBlock(block, prev)
 	if err != nil {
 		return errors.Wrap(err, "validating fetched block")
 	}
+	ctx = log.Since(ctx, t0, "validate")
 	err = c.CommitBlock(ctx, block)
 	return errors.Wrap(err, "committing block")
 }
@@ -12,6 +12,7 @@ import (
 	"chain/errors"
 	"chain/log"
 	"chain/metrics"
+	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/state"
@@ -65,15 +66,17 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 		}
 	} else {
 		g.mu.Lock()
-		txs := g.pool
+		txs, sources := g.pool, g.poolSources
 		g.pool = nil
-		g.poolHashes = make(map[bc.Hash]bool)
+		g.poolSources = make(map[bc.Hash]string)
 		g.mu.Unlock()
 
-		b, s, err = g.chain.GenerateBlock(ctx, latestBlock, latestSnapshot, time.Now(), txs)
+		tGen := time.Now()
+		b, s, err = g.chain.GenerateBlock(protocol.WithTxSources(ctx, sources), latestBlock, latestSnapshot, time.Now(), txs)
 		if err != nil {
 			return errors.Wrap(err, "generate")
 		}
+		ctx = log.Since(ctx, tGen, "generate")
 		if len(b.Transactions) == 0 {
 			return nil // don't bother making an empty block
 		}
@@ -86,10 +89,12 @@ func (g *Generator) makeBlock(ctx context.Context) (err error) {
 }
 
 func (g *Generator) commitBlock(ctx context.Context, b *legacy.Block, s *state.Snapshot, prevBlock *legacy.Block) error {
+	t0 := time.Now()
 	err := g.getAndAddBlockSignatures(ctx, b, prevBlock)
 	if err != nil {
 		return errors.Wrap(err, "sign")
 	}
+	ctx = log.Since(ctx, t0, "sign")
 
 	err = g.chain.CommitAppliedBlock(ctx, b, s)
 	if err != nil {
@@ -6,11 +6,14 @@ package generator
 
 import (
 	"context"
+	"encoding/hex"
+	"math/rand"
 	"sync"
 	"time"
 
 	"chain/database/pg"
 	"chain/log"
+	"chain/net/http/reqid"
 	"chain/protocol"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
@@ -32,9 +35,18 @@ type Generator struct {
 	chain   *protocol.Chain
 	signers []BlockSigner
 
-	mu         sync.Mutex
-	pool       []*legacy.Tx // in topological order
-	poolHashes map[bc.Hash]bool
+	// AdmissionLogPercent is the percentage of transactions
+	// admitted to the pool, chosen at random, that are logged.
+	// Entries are written with the submitting request's context,
+	// so they identify its source. Rejections when making a block
+	// are always logged, with the submitting request's ID as
+	// their source; see package protocol.
+	// It must not be changed after calling Generate.
+	AdmissionLogPercent int
+
+	mu          sync.Mutex
+	pool        []*legacy.Tx       // in topological order
+	poolSources map[bc.Hash]string // ID of the request that submitted each tx, or ""
 }
 
 // New creates and initializes a new Generator.
@@ -44,10 +56,10 @@ func New(
 	db pg.DB,
 ) *Generator {
 	return &Generator{
-		db:         db,
-		chain:      c,
-		signers:    s,
-		poolHashes: make(map[bc.Hash]bool),
+		db:          db,
+		chain:       c,
+		signers:     s,
+		poolSources: make(map[bc.Hash]string),
 	}
 }
 
@@ -67,12 +79,18 @@ func (g *Generator) Submit(ctx context.Context, tx *legacy.Tx) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if g.poolHashes[tx.ID] {
+	if _, ok := g.poolSources[tx.ID]; ok {
 		return nil
 	}
 
-	g.poolHashes[tx.ID] = true
+	g.poolSources[tx.ID] = reqid.FromContext(ctx)
 	g.pool = append(g.pool, tx)
+	if g.AdmissionLogPercent > 0 && rand.Intn(100) < g.AdmissionLogPercent {
+		log.Printkv(ctx,
+			"txadmitted", hex.EncodeToString(tx.ID.Bytes()),
+			"pool", len(g.pool),
+		)
+	}
 	return nil
 }
 
@@ -1,5 +1,23 @@
 package core
 
+import (
+	"net/http"
+	"time"
+
+	"chain/log"
+)
+
+// SinkFailureLimit configures the Core to report itself unhealthy
+// once any instrumented log sink (see log.InstrumentSink)
+// has been failing continuously for at least d.
+// The failing sinks are listed in the health errors
+// returned by /info, and /health responds with
+// 503 Service Unavailable, so orchestrators can
+// restart or drain the process.
+func SinkFailureLimit(d time.Duration) RunOption {
+	return func(a *API) { a.sinkFailureLimit = d }
+}
+
 // healthSetter returns a function that, when called,
 // sets the named health status in the map returned by "/health".
 // The returned function is safe to call concurrently with ServeHTTP.
@@ -31,6 +49,9 @@ func (a *API) health() (x struct {
 	if err := a.options.Err(); err != nil {
 		x.Errors["config"] = err.Error()
 	}
+	for name, err := range a.failingSinks() {
+		x.Errors["log/"+name] = err.Error()
+	}
 
 	a.healthMu.Lock()
 	defer a.healthMu.Unlock()
@@ -39,3 +60,25 @@ func (a *API) health() (x struct {
 	}
 	return
 }
+
+func (a *API) failingSinks() map[string]error {
+	if a.sinkFailureLimit <= 0 {
+		return nil
+	}
+	return log.FailingSinks(a.sinkFailureLimit)
+}
+
+// healthHandler serves /health, responding with
+// 503 Service Unavailable if a log sink has been failing
+// for longer than the configured limit.
+func (a *API) healthHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/health" {
+			if len(a.failingSinks()) > 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
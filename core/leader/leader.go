@@ -52,6 +52,11 @@ type Leader struct {
 	key     string
 	lead    func(context.Context)
 	address string
+
+	// Used only by the leadershipChanges goroutine
+	// to report the timeline of transitions.
+	since   time.Time // start of the current leadership or followership
+	renewed time.Time // last successful lease renewal
 }
 
 // Address retrieves a routable address of the current
@@ -98,6 +103,7 @@ func Run(ctx context.Context, db pg.DB, addr string, lead func(context.Context))
 		key:     addr,
 		lead:    lead,
 		address: addr,
+		since:   time.Now(),
 	}
 	log.Printf(ctx, "Using leaderKey: %q", l.key)
 
@@ -106,13 +112,11 @@ func Run(ctx context.Context, db pg.DB, addr string, lead func(context.Context))
 		var leadCtx context.Context
 		for leader := range leadershipChanges(ctx, l) {
 			if leader {
-				log.Printf(ctx, "I am the core leader")
 				l.state.Store(Recovering)
 				leadCtx, cancel = context.WithCancel(ctx)
 				l.lead(leadCtx)
 				l.state.Store(Leading)
 			} else {
-				log.Printf(ctx, "No longer core leader")
 				l.state.Store(Following)
 				cancel()
 			}
@@ -168,9 +172,11 @@ func leadershipChanges(ctx context.Context, l *Leader) chan bool {
 
 func tryForLeadership(ctx context.Context, l *Leader) bool {
 	const insertQ = `
+		WITH prev AS (SELECT address FROM leader)
 		INSERT INTO leader (leader_key, address, expiry) VALUES ($1, $2, CURRENT_TIMESTAMP + INTERVAL '1 second')
 		ON CONFLICT (singleton) DO UPDATE SET leader_key = $1, address = $2, expiry = CURRENT_TIMESTAMP + INTERVAL '1 second'
 			WHERE leader.expiry < CURRENT_TIMESTAMP
+		RETURNING (SELECT address FROM prev)
 	`
 
 	// Try to put this process's key into the leader table.  It
@@ -180,17 +186,25 @@ func tryForLeadership(ctx context.Context, l *Leader) bool {
 	// On success, this process's leadership expires in 1 second
 	// unless it's renewed in the UPDATE query in maintainLeadership.
 	// That extends it for another 1 second.
-	res, err := l.db.ExecContext(ctx, insertQ, l.key, l.address)
-	if err != nil {
-		log.Error(ctx, err)
+	var prev sql.NullString
+	err := l.db.QueryRowContext(ctx, insertQ, l.key, l.address).Scan(&prev)
+	if err == sql.ErrNoRows {
 		return false
-	}
-	rowsAffected, err := res.RowsAffected()
-	if err != nil {
+	} else if err != nil {
 		log.Error(ctx, err)
 		return false
 	}
-	return rowsAffected > 0
+
+	now := time.Now()
+	log.Printkv(ctx,
+		log.KeyMessage, "I am the core leader",
+		"leader", "acquired",
+		"address", l.address,
+		"previous", prev.String,
+		"followed", now.Sub(l.since),
+	)
+	l.since, l.renewed = now, now
+	return true
 }
 
 func maintainLeadership(ctx context.Context, l *Leader) bool {
@@ -200,14 +214,39 @@ func maintainLeadership(ctx context.Context, l *Leader) bool {
 	`
 
 	res, err := l.db.ExecContext(ctx, updateQ, l.key)
-	if err != nil {
-		log.Error(ctx, err)
+	if err == nil {
+		var rowsAffected int64
+		rowsAffected, err = res.RowsAffected()
+		if err == nil && rowsAffected > 0 {
+			l.renewed = time.Now()
+			return true
+		}
+	}
+	if ctx.Err() != nil {
+		// Shutting down; leadership will lapse on its own.
 		return false
 	}
-	rowsAffected, err := res.RowsAffected()
+
+	now := time.Now()
 	if err != nil {
-		log.Error(ctx, err)
-		return false
+		log.Printkv(ctx,
+			log.KeyError, err,
+			"leader", "renewalfailed",
+			"sincerenewal", now.Sub(l.renewed),
+		)
 	}
-	return rowsAffected > 0
+
+	// Another process's key is in the table, or we could not
+	// renew before our lease expired. Either way, we're deposed.
+	next, _ := l.Address(ctx)
+	log.Printkv(ctx,
+		log.KeyMessage, "No longer core leader",
+		"leader", "lost",
+		"address", l.address,
+		"next", next,
+		"held", now.Sub(l.since),
+		"sincerenewal", now.Sub(l.renewed),
+	)
+	l.since = now
+	return false
 }
@@ -3,9 +3,13 @@ package core
 import (
 	"expvar"
 	"net/http"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
 	"chain/metrics"
 )
 
@@ -13,6 +17,8 @@ var (
 	latencyMu sync.Mutex
 	latencies = map[string]*metrics.RotatingLatency{}
 
+	latencyQuantiles = []float64{50, 95, 99}
+
 	latencyRange = map[string]time.Duration{
 		crosscoreRPCPrefix + "get-block":         20 * time.Second,
 		crosscoreRPCPrefix + "signer/sign-block": 5 * time.Second,
@@ -42,6 +48,102 @@ func latency(tab *http.ServeMux, req *http.Request) *metrics.RotatingLatency {
 	return nil
 }
 
+func init() {
+	expvar.Publish("latency_quantiles", expvar.Func(func() interface{} {
+		latencyMu.Lock()
+		defer latencyMu.Unlock()
+		m := make(map[string]map[string]time.Duration)
+		for path, l := range latencies {
+			q := l.Quantiles(latencyQuantiles...)
+			m[path] = map[string]time.Duration{
+				"p50": q[0],
+				"p95": q[1],
+				"p99": q[2],
+			}
+		}
+		return m
+	}))
+}
+
+// metricsHandler serves the Core's operational metrics,
+// including those published by package chain/log,
+// in the Prometheus text format.
+var metricsHandler http.Handler
+
+func init() {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(prometheus.NewGoCollector())
+	reg.MustRegister(prometheus.NewProcessCollector(os.Getpid(), ""))
+	reg.MustRegister(prometheus.NewExpvarCollector(map[string]*prometheus.Desc{
+		"log_entries": prometheus.NewDesc(
+			"chain_log_entries_total",
+			"Log entries written, by severity.",
+			[]string{"severity"}, nil,
+		),
+		"log_dropped": prometheus.NewDesc(
+			"chain_log_dropped_total",
+			"Log entries discarded, by cause and severity.",
+			[]string{"cause", "severity"}, nil,
+		),
+		"log_sink_healthy": prometheus.NewDesc(
+			"chain_log_sink_healthy",
+			"Whether the last write to each log sink succeeded.",
+			[]string{"sink"}, nil,
+		),
+		"log_sink_dropped": prometheus.NewDesc(
+			"chain_log_sink_dropped_total",
+			"Writes lost by each log sink.",
+			[]string{"sink"}, nil,
+		),
+		"log_sink_bytes": prometheus.NewDesc(
+			"chain_log_sink_bytes_total",
+			"Bytes written to each log sink.",
+			[]string{"sink"}, nil,
+		),
+		"log_sink_failures": prometheus.NewDesc(
+			"chain_log_sink_consecutive_failures",
+			"Consecutive failed writes to each log sink.",
+			[]string{"sink"}, nil,
+		),
+		"log_sink_latency": prometheus.NewDesc(
+			"chain_log_sink_latency_nanoseconds",
+			"Log sink write latency quantiles over the last few minutes.",
+			[]string{"sink", "quantile"}, nil,
+		),
+		"log_volume": prometheus.NewDesc(
+			"chain_log_volume",
+			"Log output rate over the last complete measurement window.",
+			[]string{"rate"}, nil,
+		),
+		"log_entry_size": prometheus.NewDesc(
+			"chain_log_entry_size_bytes",
+			"Encoded log entry size distribution since startup.",
+			[]string{"stat"}, nil,
+		),
+		"log_deprecated": prometheus.NewDesc(
+			"chain_deprecated_uses_total",
+			"Uses of deprecated features, by feature.",
+			[]string{"feature"}, nil,
+		),
+		"latency_quantiles": prometheus.NewDesc(
+			"chain_request_latency_nanoseconds",
+			"API request latency quantiles over the last few minutes, by route.",
+			[]string{"path", "quantile"}, nil,
+		),
+		"accesslog_excluded": prometheus.NewDesc(
+			"chain_accesslog_excluded_total",
+			"Requests left out of the access log, by path.",
+			[]string{"path"}, nil,
+		),
+		"ncore": prometheus.NewDesc(
+			"chain_cores_seen",
+			"Distinct cores that called this one in the last minute.",
+			nil, nil,
+		),
+	}))
+	metricsHandler = promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
+
 var (
 	ncoreMu   sync.Mutex
 	ncore     = expvar.NewInt("ncore")
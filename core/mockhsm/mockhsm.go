@@ -5,9 +5,11 @@ package mockhsm
 import (
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/lib/pq"
 
@@ -15,6 +17,8 @@ import (
 	"chain/crypto/ed25519/chainkd"
 	"chain/database/pg"
 	"chain/errors"
+	"chain/log"
+	"chain/net/http/authn"
 	"chain/protocol/bc/legacy"
 )
 
@@ -35,6 +39,7 @@ type HSM struct {
 	cacheMu sync.Mutex
 	kdCache map[chainkd.XPub]chainkd.XPrv
 	edCache map[string]ed25519.PrivateKey // ed25519.PublicKeys must be turned into strings before being used as map keys
+	aliases map[string]string             // by public key bytes, for audit entries
 }
 
 type XPub struct {
@@ -52,6 +57,7 @@ func New(db pg.DB) *HSM {
 		db:      db,
 		kdCache: make(map[chainkd.XPub]chainkd.XPrv),
 		edCache: make(map[string]ed25519.PrivateKey),
+		aliases: make(map[string]string),
 	}
 }
 
@@ -195,32 +201,41 @@ func (h *HSM) ListKeys(ctx context.Context, aliases []string, after string, limi
 	return xpubs, strconv.FormatInt(zafter, 10), nil
 }
 
-func (h *HSM) loadChainKDKey(ctx context.Context, xpub chainkd.XPub) (xprv chainkd.XPrv, err error) {
+func (h *HSM) loadChainKDKey(ctx context.Context, xpub chainkd.XPub) (xprv chainkd.XPrv, alias string, err error) {
 	h.cacheMu.Lock()
 	defer h.cacheMu.Unlock()
 
 	if xprv, ok := h.kdCache[xpub]; ok {
-		return xprv, nil
+		return xprv, h.aliases[string(xpub.Bytes())], nil
 	}
 
-	var b []byte
-	err = h.db.QueryRowContext(ctx, "SELECT prv FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes()).Scan(&b)
+	var (
+		b        []byte
+		sqlAlias sql.NullString
+	)
+	err = h.db.QueryRowContext(ctx, "SELECT prv, alias FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes()).Scan(&b, &sqlAlias)
 	if err == sql.ErrNoRows {
-		return xprv, ErrNoKey
+		return xprv, "", ErrNoKey
 	}
 	if err != nil {
-		return xprv, err
+		return xprv, "", err
 	}
 	copy(xprv[:], b)
 	h.kdCache[xpub] = xprv
-	return xprv, nil
+	h.aliases[string(xpub.Bytes())] = sqlAlias.String
+	return xprv, sqlAlias.String, nil
 }
 
 // XSign looks up the xprv given the xpub, optionally derives a new
 // xprv with the given path (but does not store the new xprv), and
 // signs the given msg.
-func (h *HSM) XSign(ctx context.Context, xpub chainkd.XPub, path [][]byte, msg []byte) ([]byte, error) {
-	xprv, err := h.loadChainKDKey(ctx, xpub)
+func (h *HSM) XSign(ctx context.Context, xpub chainkd.XPub, path [][]byte, msg []byte) (sig []byte, err error) {
+	t0 := time.Now()
+	var alias string
+	defer func() { auditSign(ctx, t0, xpub.Bytes(), alias, msg, err) }()
+
+	var xprv chainkd.XPrv
+	xprv, alias, err = h.loadChainKDKey(ctx, xpub)
 	if err != nil {
 		return nil, err
 	}
@@ -233,43 +248,76 @@ func (h *HSM) XSign(ctx context.Context, xpub chainkd.XPub, path [][]byte, msg [
 func (h *HSM) DeleteChainKDKey(ctx context.Context, xpub chainkd.XPub) error {
 	h.cacheMu.Lock()
 	delete(h.kdCache, xpub)
+	delete(h.aliases, string(xpub.Bytes()))
 	h.cacheMu.Unlock()
 	_, err := h.db.ExecContext(ctx, "DELETE FROM mockhsm WHERE pub = $1 AND key_type='chain_kd'", xpub.Bytes())
 	return err
 }
 
-func (h *HSM) loadEd25519Key(ctx context.Context, pub ed25519.PublicKey) (prv ed25519.PrivateKey, err error) {
+func (h *HSM) loadEd25519Key(ctx context.Context, pub ed25519.PublicKey) (prv ed25519.PrivateKey, alias string, err error) {
 	h.cacheMu.Lock()
 	defer h.cacheMu.Unlock()
 
 	pubStr := string(pub)
 
 	if prv, ok := h.edCache[pubStr]; ok {
-		return prv, nil
+		return prv, h.aliases[pubStr], nil
 	}
 
-	err = h.db.QueryRowContext(ctx, "SELECT prv FROM mockhsm WHERE pub = $1 AND key_type='ed25519'", []byte(pub)).Scan(&prv)
+	var sqlAlias sql.NullString
+	err = h.db.QueryRowContext(ctx, "SELECT prv, alias FROM mockhsm WHERE pub = $1 AND key_type='ed25519'", []byte(pub)).Scan(&prv, &sqlAlias)
 	if err == sql.ErrNoRows {
-		return prv, ErrNoKey
+		return prv, "", ErrNoKey
 	}
 	if err != nil {
-		return prv, err
+		return prv, "", err
 	}
 	h.edCache[pubStr] = prv
-	return prv, nil
+	h.aliases[pubStr] = sqlAlias.String
+	return prv, sqlAlias.String, nil
 }
 
 // Sign looks up the prv given the pub and signs the given msg.
-func (h *HSM) Sign(ctx context.Context, pub ed25519.PublicKey, bh *legacy.BlockHeader) ([]byte, error) {
-	prv, err := h.loadEd25519Key(ctx, pub)
+func (h *HSM) Sign(ctx context.Context, pub ed25519.PublicKey, bh *legacy.BlockHeader) (sig []byte, err error) {
+	t0 := time.Now()
+	msg := bh.Hash()
+	var alias string
+	defer func() { auditSign(ctx, t0, pub, alias, msg.Bytes(), err) }()
+
+	var prv ed25519.PrivateKey
+	prv, alias, err = h.loadEd25519Key(ctx, pub)
+
+	// ed25519.Sign will panic if prv is the wrong size. Protect against that.
+	if err == nil && len(prv) != ed25519.PrivateKeySize {
+		err = ErrInvalidKeySize
+	}
 	if err != nil {
 		return nil, err
 	}
+	return ed25519.Sign(prv, msg.Bytes()), nil
+}
 
-	// ed25519.Sign will panic if prv is the wrong size. Protect against that.
-	if len(prv) != ed25519.PrivateKeySize {
-		return nil, ErrInvalidKeySize
+// auditSign writes an audit entry for a signing request
+// for the key pub, which began at t0.
+// Callers defer it, so the duration includes signing.
+// A nil err means the signature was produced.
+func auditSign(ctx context.Context, t0 time.Time, pub []byte, alias string, digest []byte, err error) {
+	outcome := "signed"
+	if err == ErrNoKey {
+		outcome = "nokey"
+	} else if err != nil {
+		outcome = "failed"
 	}
-	msg := bh.Hash()
-	return ed25519.Sign(prv, msg.Bytes()), nil
+	keyvals := []interface{}{
+		"key", hex.EncodeToString(pub),
+		"alias", alias,
+		"digest", hex.EncodeToString(digest),
+		"principal", authn.Principal(ctx),
+		"duration", time.Since(t0),
+		"outcome", outcome,
+	}
+	if outcome == "failed" {
+		keyvals = append(keyvals, "failure", err)
+	}
+	log.Audit(ctx, "sign", keyvals...)
 }
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"chain/errors"
+	"chain/log"
 	"chain/net/http/httperror"
 	"chain/net/http/reqid"
 )
@@ -77,7 +78,21 @@ func (c *Client) Call(ctx context.Context, path string, request, response interf
 
 // CallRaw calls a remote procedure on another node, specified by the path. It
 // returns a io.ReadCloser of the raw response body.
+//
+// Every call is logged with its path, peer, latency, and outcome.
+// If ctx doesn't already carry a request ID, CallRaw generates one,
+// so the entry can be matched with the peer's log of the same request.
 func (c *Client) CallRaw(ctx context.Context, path string, request interface{}) (io.ReadCloser, error) {
+	if reqid.FromContext(ctx) == "" {
+		ctx = reqid.NewContext(ctx, reqid.New())
+	}
+	t0 := time.Now()
+	r, err := c.callRaw(ctx, path, request)
+	logCall(ctx, c.BaseURL, path, time.Since(t0), err)
+	return r, err
+}
+
+func (c *Client) callRaw(ctx context.Context, path string, request interface{}) (io.ReadCloser, error) {
 	u, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return nil, errors.Wrap(err)
@@ -158,6 +173,34 @@ func (c *Client) CallRaw(ctx context.Context, path string, request interface{})
 	return resp.Body, nil
 }
 
+// logCall writes a log entry describing the outcome
+// of a single RPC to the peer at baseURL.
+func logCall(ctx context.Context, baseURL, path string, d time.Duration, err error) {
+	peer := baseURL
+	if u, uerr := url.Parse(baseURL); uerr == nil {
+		peer = u.Host
+	}
+	keyvals := []interface{}{
+		"rpc", path,
+		"peer", peer,
+		"duration", d,
+	}
+	switch e := errors.Root(err).(type) {
+	case nil:
+		keyvals = append(keyvals, "outcome", "ok")
+	case ErrStatusCode:
+		keyvals = append(keyvals, "outcome", "error", "status", e.StatusCode)
+		if e.ErrorData != nil {
+			keyvals = append(keyvals, "chaincode", e.ErrorData.ChainCode)
+		}
+	default:
+		// Log only the message; callers are
+		// responsible for reporting the stack.
+		keyvals = append(keyvals, "outcome", "error", log.KeyError, err.Error())
+	}
+	log.Printkv(ctx, keyvals...)
+}
+
 func cleanedURLString(u *url.URL) string {
 	var dup url.URL = *u
 	dup.User = nil
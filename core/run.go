@@ -223,6 +223,10 @@ func Run(
 	// leader-only Core duties.
 	a.leader = leader.Run(ctx, db, routableAddress, a.lead)
 
+	if a.statusInterval > 0 {
+		go a.logStatus(ctx)
+	}
+
 	// Construct the complete http.Handler once.
 	a.buildHandler()
 
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"chain/log"
+	"chain/sync/job"
+)
+
+// StatusLog configures the Core to write a log entry once per
+// interval describing the progress of the blockchain, so
+// environments with only log access can chart it without
+// the query API.
+func StatusLog(interval time.Duration) RunOption {
+	return func(a *API) { a.statusInterval = interval }
+}
+
+func (a *API) logStatus(ctx context.Context) {
+	job.Every(ctx, "logstatus", a.statusInterval, func(ctx context.Context) error {
+		log.Printkv(ctx, a.statusKeyvals()...)
+		return nil
+	})
+}
+
+func (a *API) statusKeyvals() []interface{} {
+	keyvals := []interface{}{
+		"message", "core status",
+		"state", a.leader.State().String(),
+		"height", a.chain.Height(),
+	}
+	if ms := a.chain.TimestampMS(); ms > 0 {
+		t := time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC()
+		keyvals = append(keyvals, "blocktime", t.Format(time.RFC3339Nano))
+	}
+	// Only the generator holds a pool of pending transactions.
+	if a.generator != nil && a.config.IsGenerator {
+		keyvals = append(keyvals, "pendingtxs", len(a.generator.PendingTxs()))
+	}
+	return keyvals
+}
@@ -0,0 +1,60 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"chain/core/config"
+	"chain/core/generator"
+	"chain/protocol/prottest"
+)
+
+func TestStatusKeyvals(t *testing.T) {
+	c := prottest.NewChain(t)
+	prottest.MakeBlock(t, c, nil)
+
+	api := &API{
+		chain:     c,
+		leader:    alwaysLeader{},
+		generator: generator.New(c, nil, nil),
+		config:    &config.Config{IsGenerator: true},
+	}
+	got := statusMap(t, api.statusKeyvals())
+
+	blocktime := time.Unix(0, int64(c.TimestampMS())*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)
+	want := map[string]string{
+		"message":    "core status",
+		"state":      "leading",
+		"height":     "2",
+		"blocktime":  blocktime,
+		"pendingtxs": "0",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q want %q", k, got[k], v)
+		}
+	}
+
+	// A core that isn't the generator has no tx pool to report.
+	api.config.IsGenerator = false
+	api.leader = alwaysFollower{}
+	got = statusMap(t, api.statusKeyvals())
+	if _, ok := got["pendingtxs"]; ok {
+		t.Errorf("pendingtxs = %q on non-generator, want none", got["pendingtxs"])
+	}
+	if got["state"] != "following" {
+		t.Errorf("state = %q want following", got["state"])
+	}
+}
+
+func statusMap(t *testing.T, keyvals []interface{}) map[string]string {
+	if len(keyvals)%2 != 0 {
+		t.Fatalf("odd number of keyvals: %v", keyvals)
+	}
+	m := make(map[string]string)
+	for i := 0; i < len(keyvals); i += 2 {
+		m[fmt.Sprint(keyvals[i])] = fmt.Sprint(keyvals[i+1])
+	}
+	return m
+}
@@ -11,11 +11,11 @@ import (
 	"chain/database/pg"
 	chainjson "chain/encoding/json"
 	"chain/errors"
-	"chain/log"
 	"chain/net/http/httperror"
 	"chain/net/http/reqid"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
+	"chain/sync/job"
 )
 
 const defaultTxTTL = 5 * time.Minute
@@ -185,24 +185,15 @@ func recordSubmittedTx(ctx context.Context, db pg.DB, txHash bc.Hash, currentHei
 // older than a day. This function blocks and only exits when its context
 // is cancelled.
 func cleanUpSubmittedTxs(ctx context.Context, db pg.DB) {
-	ticker := time.NewTicker(15 * time.Minute)
-	for {
-		select {
-		case <-ticker.C:
-			// TODO(jackson): We could avoid expensive bulk deletes by partitioning
-			// the table and DROP-ing tables of expired rows. Partitioning doesn't
-			// play well with ON CONFLICT clauses though, so we would need to rework
-			// how we guarantee uniqueness.
-			const q = `DELETE FROM submitted_txs WHERE submitted_at < now() - interval '1 day'`
-			_, err := db.ExecContext(ctx, q)
-			if err != nil {
-				log.Error(ctx, err)
-			}
-		case <-ctx.Done():
-			ticker.Stop()
-			return
-		}
-	}
+	job.Every(ctx, "cleanupsubmittedtxs", 15*time.Minute, func(ctx context.Context) error {
+		// TODO(jackson): We could avoid expensive bulk deletes by partitioning
+		// the table and DROP-ing tables of expired rows. Partitioning doesn't
+		// play well with ON CONFLICT clauses though, so we would need to rework
+		// how we guarantee uniqueness.
+		const q = `DELETE FROM submitted_txs WHERE submitted_at < now() - interval '1 day'`
+		_, err := db.ExecContext(ctx, q)
+		return err
+	})
 }
 
 // finalizeTxWait calls FinalizeTx and then waits for confirmation of
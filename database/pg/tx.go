@@ -0,0 +1,81 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"chain/errors"
+	"chain/log"
+)
+
+// maxTxRetries is the number of times RunInTx retries
+// a transaction that can't be serialized.
+const maxTxRetries = 3
+
+// IsSerializationFailure returns true if the given error is a
+// Postgres serialization failure or deadlock, which can be
+// resolved by retrying the transaction.
+func IsSerializationFailure(err error) bool {
+	pqErr, ok := errors.Root(err).(*pq.Error)
+	if !ok {
+		return false
+	}
+	switch pqErr.Code.Name() {
+	case "serialization_failure", "deadlock_detected":
+		return true
+	}
+	return false
+}
+
+// RunInTx calls fn in a new database transaction,
+// committing it if fn returns nil and rolling it back otherwise.
+// If the transaction fails with a serialization failure,
+// it is retried up to maxTxRetries times.
+//
+// Each attempt's outcome ("commit", "rollback", or "beginfailed"),
+// duration, and retry count are logged with ctx, so entries for
+// API requests include the calling endpoint's path.
+func RunInTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(*sql.Tx) error) error {
+	for retry := 0; ; retry++ {
+		err := runInTx(ctx, db, opts, retry, fn)
+		if err == nil || retry == maxTxRetries || !IsSerializationFailure(err) {
+			return err
+		}
+	}
+}
+
+func runInTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, retry int, fn func(*sql.Tx) error) (err error) {
+	t0 := time.Now()
+	outcome := "rollback"
+	defer func() {
+		keyvals := []interface{}{
+			"dbtx", outcome,
+			"duration", time.Since(t0),
+			"retries", retry,
+		}
+		if err != nil {
+			keyvals = append(keyvals, "txerror", err.Error())
+		}
+		log.Printkv(ctx, keyvals...)
+	}()
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		outcome = "beginfailed"
+		return errors.Wrap(err, "begin transaction")
+	}
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "commit transaction")
+	}
+	outcome = "commit"
+	return nil
+}
@@ -0,0 +1,88 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"chain/log/logtest"
+)
+
+// txDriver opens connections whose transactions
+// fail to commit with a serialization failure
+// until commitFailures reaches zero.
+type txDriver struct {
+	commitFailures *int
+}
+
+func (d txDriver) Open(string) (driver.Conn, error) { return txConn(d), nil }
+
+type txConn txDriver
+
+func (c txConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c txConn) Close() error                        { return nil }
+func (c txConn) Begin() (driver.Tx, error)           { return fakeTx(c), nil }
+
+type fakeTx txConn
+
+func (tx fakeTx) Rollback() error { return nil }
+
+func (tx fakeTx) Commit() error {
+	if *tx.commitFailures > 0 {
+		*tx.commitFailures--
+		return &pq.Error{Code: "40001"}
+	}
+	return nil
+}
+
+func TestRunInTxRetry(t *testing.T) {
+	out := logtest.Capture(t)
+
+	failures := 2
+	sql.Register("pg-tx-test", txDriver{&failures})
+	db, err := sql.Open("pg-tx-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	err = RunInTx(context.Background(), db, nil, func(*sql.Tx) error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d want 3", calls)
+	}
+
+	entries := out.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v, want 3", entries)
+	}
+	if w := (logtest.Entry{"dbtx": "rollback", "retries": "0"}); !entries[0].Matches(w) {
+		t.Errorf("first entry = %v, want %v", entries[0], w)
+	}
+	if w := (logtest.Entry{"dbtx": "commit", "retries": "2"}); !entries[2].Matches(w) {
+		t.Errorf("last entry = %v, want %v", entries[2], w)
+	}
+}
+
+func TestRunInTxGiveUp(t *testing.T) {
+	logtest.Capture(t)
+
+	failures := maxTxRetries + 1
+	sql.Register("pg-tx-test-give-up", txDriver{&failures})
+	db, err := sql.Open("pg-tx-test-give-up", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = RunInTx(context.Background(), db, nil, func(*sql.Tx) error { return nil })
+	if !IsSerializationFailure(err) {
+		t.Errorf("err = %v, want serialization failure", err)
+	}
+}
@@ -0,0 +1,80 @@
+package sqlutil
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+)
+
+// normalize returns query with string and numeric literals
+// replaced by the placeholder "?" and runs of white space
+// collapsed to a single space, so statements that differ only
+// in their parameters or formatting compare equal.
+// Bind parameters such as $1 are left alone.
+func normalize(query string) string {
+	var b bytes.Buffer
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+			continue
+		case c == '\'':
+			i = skipString(query, i)
+			c = '?'
+		case isDigit(c) && (i == 0 || !isIdent(query[i-1])):
+			for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+				i++
+			}
+			c = '?'
+		default:
+			i++
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		if c == '?' {
+			b.WriteByte('?')
+		} else {
+			b.WriteByte(query[i-1])
+		}
+	}
+	return b.String()
+}
+
+// skipString returns the index just past the
+// single-quoted string literal starting at query[i].
+func skipString(query string, i int) int {
+	for i++; i < len(query); i++ {
+		if query[i] == '\'' {
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++ // escaped quote
+				continue
+			}
+			return i + 1
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isIdent reports whether c can appear in an identifier
+// or bind parameter, so that digits following it are
+// not a numeric literal.
+func isIdent(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// fingerprint returns a stable hash of the
+// normalized form of query.
+func fingerprint(query string) string {
+	h := fnv.New64a()
+	h.Write([]byte(normalize(query)))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
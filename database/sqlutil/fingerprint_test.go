@@ -0,0 +1,30 @@
+package sqlutil
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := []struct{ q, want string }{
+		{"SELECT x\n\tFROM t WHERE x = $1", "SELECT x FROM t WHERE x = $1"},
+		{"SELECT * FROM t WHERE id = 42 AND name = 'bob'", "SELECT * FROM t WHERE id = ? AND name = ?"},
+		{"SELECT 'it''s', 3.14", "SELECT ?, ?"},
+		{"SELECT col2 FROM t2 LIMIT 10", "SELECT col2 FROM t2 LIMIT ?"},
+		{"  INSERT INTO t VALUES ($1, $2)  ", "INSERT INTO t VALUES ($1, $2)"},
+	}
+	for _, c := range cases {
+		if got := normalize(c.q); got != c.want {
+			t.Errorf("normalize(%q) = %q want %q", c.q, got, c.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := fingerprint("SELECT * FROM t WHERE id = 1")
+	b := fingerprint("SELECT *  FROM t\nWHERE id = 2")
+	c := fingerprint("SELECT * FROM u WHERE id = 1")
+	if a != b {
+		t.Errorf("fingerprints differ for equivalent queries: %s %s", a, b)
+	}
+	if a == c {
+		t.Errorf("fingerprints equal for different queries: %s", a)
+	}
+}
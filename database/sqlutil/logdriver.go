@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
+	"math/rand"
 
 	"chain/log"
 )
@@ -15,73 +16,38 @@ import (
 
 const maxArgsLogLen = 20 // bytes
 
-func logQuery(ctx context.Context, query string, args interface{}) {
-	s := fmt.Sprint(args)
-	if len(s) > maxArgsLogLen {
-		s = s[:maxArgsLogLen-3] + "..."
+func logQuery(ctx context.Context, s *statement) {
+	args := fmt.Sprint(values(s.args))
+	if len(args) > maxArgsLogLen {
+		args = args[:maxArgsLogLen-3] + "..."
 	}
-	log.Printkv(ctx, "query", query, "args", s)
-}
-
-type logDriver struct {
-	driver driver.Driver
-}
-
-// LogDriver returns a Driver that logs each query
-// before forwarding it to d.
-func LogDriver(d driver.Driver) driver.Driver {
-	return &logDriver{d}
-}
-
-func (ld *logDriver) Open(name string) (driver.Conn, error) {
-	c, err := ld.driver.Open(name)
-	return &logConn{c}, err
-}
-
-type logConn struct {
-	driver.Conn
-}
-
-func (lc *logConn) Prepare(query string) (driver.Stmt, error) {
-	stmt, err := lc.Conn.Prepare(query)
-	return &logStmt{query, stmt}, err
-}
-
-func (lc *logConn) Exec(query string, args []driver.Value) (driver.Result, error) {
-	execer, ok := lc.Conn.(driver.Execer)
-	if !ok {
-		return nil, driver.ErrSkip
+	keyvals := []interface{}{
+		"query", normalize(s.query),
+		"fingerprint", fingerprint(s.query),
+		"args", args,
+		"duration", s.duration,
+		"rows", s.rows,
 	}
-	logQuery(context.Background(), query, args)
-	return execer.Exec(query, args)
-}
-
-func (lc *logConn) Query(query string, args []driver.Value) (driver.Rows, error) {
-	queryer, ok := lc.Conn.(driver.Queryer)
-	if !ok {
-		return nil, driver.ErrSkip
+	if s.err != nil {
+		keyvals = append(keyvals, "failed", true)
 	}
-	logQuery(context.Background(), query, args)
-	return queryer.Query(query, args)
-}
-
-// TODO(kr): implement context variants
-// (but don't bother until lib/pq does first).
-//func (lc *logConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error)
-//func (lc *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error)
-//func (lc *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error)
-
-type logStmt struct {
-	query string
-	driver.Stmt
-}
-
-func (ls *logStmt) Exec(args []driver.Value) (driver.Result, error) {
-	logQuery(context.Background(), ls.query, args)
-	return ls.Stmt.Exec(args)
-}
-
-func (ls *logStmt) Query(args []driver.Value) (driver.Rows, error) {
-	logQuery(context.Background(), ls.query, args)
-	return ls.Stmt.Query(args)
+	log.Printkv(ctx, keyvals...)
+}
+
+// LogDriver returns a Driver that forwards to d and logs
+// each statement once it completes, with its duration and
+// the number of rows returned or affected.
+// Entries are written with the context passed to the
+// statement, so they carry the request ID of the
+// request that made it.
+//
+// If percent is less than 100, only that percentage
+// of statements, chosen at random, is logged.
+func LogDriver(d driver.Driver, percent int) driver.Driver {
+	return &observedDriver{d, func(ctx context.Context, _ driver.Conn, s *statement) {
+		if percent < 100 && rand.Intn(100) >= percent {
+			return
+		}
+		logQuery(ctx, s)
+	}}
 }
@@ -0,0 +1,55 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"chain/log"
+	"chain/log/logtest"
+)
+
+func init() {
+	sql.Register("sqlutil-log", LogDriver(fakeDriver{}, 100))
+	sql.Register("sqlutil-log-none", LogDriver(fakeDriver{}, 0))
+}
+
+func TestLogDriver(t *testing.T) {
+	out := logtest.Capture(t)
+
+	db, err := sql.Open("sqlutil-log", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := log.AddPrefixkv(context.Background(), "reqid", "abc")
+	var x string
+	err = db.QueryRowContext(ctx, "SELECT x FROM t WHERE y = $1", 7).Scan(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := out.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want 1", entries)
+	}
+	e := entries[0]
+	want := logtest.Entry{"reqid": "abc", "query": "SELECT x FROM t WHERE y = $1", "args": "[7]", "rows": "1"}
+	if !e.Matches(want) || e["duration"] == "" {
+		t.Errorf("entry = %v, want %v and a duration", e, want)
+	}
+}
+
+func TestLogDriverSampled(t *testing.T) {
+	out := logtest.Capture(t)
+
+	db, err := sql.Open("sqlutil-log-none", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(context.Background(), "UPDATE t SET x = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); s != "" {
+		t.Errorf("log = %q, want nothing", s)
+	}
+}
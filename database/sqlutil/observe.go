@@ -0,0 +1,204 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"time"
+)
+
+// A statement describes a completed query or exec
+// made through an observed driver.
+type statement struct {
+	query    string
+	args     []driver.NamedValue
+	duration time.Duration // for queries, until the rows are closed
+	rows     int64         // rows returned or affected
+	err      error
+}
+
+// An observer is called after each statement completes.
+// Conn is the connection the statement ran on;
+// it can be used to run further statements
+// on the same connection.
+type observer func(ctx context.Context, conn driver.Conn, s *statement)
+
+type observedDriver struct {
+	driver  driver.Driver
+	observe observer
+}
+
+func (od *observedDriver) Open(name string) (driver.Conn, error) {
+	c, err := od.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &observedConn{c, od.observe}, nil
+}
+
+type observedConn struct {
+	driver.Conn
+	observe observer
+}
+
+func (oc *observedConn) Prepare(query string) (driver.Stmt, error) {
+	return oc.PrepareContext(context.Background(), query)
+}
+
+func (oc *observedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if p, ok := oc.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = oc.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &observedStmt{stmt, oc, query}, nil
+}
+
+func (oc *observedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := oc.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return oc.Conn.Begin()
+}
+
+func (oc *observedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := oc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	t0 := time.Now()
+	res, err := execer.ExecContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return res, err
+	}
+	oc.observeExec(ctx, query, args, t0, res, err)
+	return res, err
+}
+
+func (oc *observedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := oc.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	t0 := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return rows, err
+	}
+	return oc.observeRows(ctx, query, args, t0, rows, err)
+}
+
+func (oc *observedConn) observeExec(ctx context.Context, query string, args []driver.NamedValue, t0 time.Time, res driver.Result, err error) {
+	s := &statement{query: query, args: args, duration: time.Since(t0), err: err}
+	if err == nil {
+		s.rows, _ = res.RowsAffected()
+	}
+	oc.observe(ctx, oc.Conn, s)
+}
+
+func (oc *observedConn) observeRows(ctx context.Context, query string, args []driver.NamedValue, t0 time.Time, rows driver.Rows, err error) (driver.Rows, error) {
+	s := &statement{query: query, args: args, err: err}
+	if err != nil {
+		s.duration = time.Since(t0)
+		oc.observe(ctx, oc.Conn, s)
+		return nil, err
+	}
+	return &observedRows{Rows: rows, ctx: ctx, conn: oc, t0: t0, s: s}, nil
+}
+
+type observedStmt struct {
+	driver.Stmt
+	conn  *observedConn
+	query string
+}
+
+func (st *observedStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return st.ExecContext(context.Background(), named(args))
+}
+
+func (st *observedStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return st.QueryContext(context.Background(), named(args))
+}
+
+func (st *observedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	t0 := time.Now()
+	var (
+		res driver.Result
+		err error
+	)
+	if e, ok := st.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = st.Stmt.Exec(values(args))
+	}
+	st.conn.observeExec(ctx, st.query, args, t0, res, err)
+	return res, err
+}
+
+func (st *observedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	t0 := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if q, ok := st.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = st.Stmt.Query(values(args))
+	}
+	return st.conn.observeRows(ctx, st.query, args, t0, rows, err)
+}
+
+// observedRows counts the rows read and reports
+// the statement when it is closed.
+type observedRows struct {
+	driver.Rows
+	ctx    context.Context
+	conn   *observedConn
+	t0     time.Time
+	s      *statement
+	closed bool
+}
+
+func (r *observedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.s.rows++
+	} else if err != io.EOF && r.s.err == nil {
+		r.s.err = err
+	}
+	return err
+}
+
+func (r *observedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.s.duration = time.Since(r.t0)
+		r.conn.observe(r.ctx, r.conn.Conn, r.s)
+	}
+	return err
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	a := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		a[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return a
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	a := make([]driver.Value, len(args))
+	for i, nv := range args {
+		a[i] = nv.Value
+	}
+	return a
+}
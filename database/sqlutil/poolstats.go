@@ -0,0 +1,44 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"chain/log"
+)
+
+// LogPoolStats writes a log entry describing the state of
+// db's connection pool once per interval until ctx is done.
+// Wait counts and durations are reported for the interval,
+// so pool exhaustion shows up as a nonzero "waits" field
+// rather than only as unexplained request latency.
+func LogPoolStats(ctx context.Context, db *sql.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	prev := db.Stats()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cur := db.Stats()
+			log.Printkv(ctx, poolKeyvals(prev, cur)...)
+			prev = cur
+		}
+	}
+}
+
+func poolKeyvals(prev, cur sql.DBStats) []interface{} {
+	return []interface{}{
+		"message", "db pool stats",
+		"maxopen", cur.MaxOpenConnections,
+		"open", cur.OpenConnections,
+		"inuse", cur.InUse,
+		"idle", cur.Idle,
+		"waits", cur.WaitCount - prev.WaitCount,
+		"waitduration", cur.WaitDuration - prev.WaitDuration,
+		"idleclosed", cur.MaxIdleClosed - prev.MaxIdleClosed,
+		"lifetimeclosed", cur.MaxLifetimeClosed - prev.MaxLifetimeClosed,
+	}
+}
@@ -0,0 +1,36 @@
+package sqlutil
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"chain/testutil"
+)
+
+func TestPoolKeyvals(t *testing.T) {
+	prev := sql.DBStats{WaitCount: 3, WaitDuration: time.Second}
+	cur := sql.DBStats{
+		MaxOpenConnections: 10,
+		OpenConnections:    10,
+		InUse:              10,
+		WaitCount:          5,
+		WaitDuration:       3 * time.Second,
+		MaxIdleClosed:      1,
+	}
+	got := poolKeyvals(prev, cur)
+	want := []interface{}{
+		"message", "db pool stats",
+		"maxopen", 10,
+		"open", 10,
+		"inuse", 10,
+		"idle", 0,
+		"waits", int64(2),
+		"waitduration", 2 * time.Second,
+		"idleclosed", int64(1),
+		"lifetimeclosed", int64(0),
+	}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("poolKeyvals = %v want %v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	"chain/log"
+)
+
+// SlowQueryDriver returns a Driver that forwards to d and logs
+// each statement that takes at least threshold to complete,
+// with its normalized text and fingerprint (see normalize),
+// duration, and the number of rows returned or affected.
+// For queries, the duration includes reading the rows.
+//
+// For explainPercent percent of slow statements
+// (chosen at random), it also runs EXPLAIN on the same
+// connection and includes the plan in the entry.
+func SlowQueryDriver(d driver.Driver, threshold time.Duration, explainPercent int) driver.Driver {
+	return &observedDriver{d, func(ctx context.Context, conn driver.Conn, s *statement) {
+		if s.duration < threshold {
+			return
+		}
+		keyvals := []interface{}{
+			"slowquery", normalize(s.query),
+			"fingerprint", fingerprint(s.query),
+			"duration", s.duration,
+			"rows", s.rows,
+		}
+		if s.err != nil {
+			keyvals = append(keyvals, "failed", true)
+		}
+		if explainPercent > 0 && rand.Intn(100) < explainPercent {
+			if plan, ok := explain(ctx, conn, s); ok {
+				keyvals = append(keyvals, "plan", plan)
+			}
+		}
+		log.Printkv(ctx, keyvals...)
+	}}
+}
+
+// explain returns the query plan for s, run on conn.
+// It reports false if the statement can't be explained.
+func explain(ctx context.Context, conn driver.Conn, s *statement) (string, bool) {
+	if !explainable(s.query) {
+		return "", false
+	}
+	queryer, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return "", false
+	}
+	rows, err := queryer.QueryContext(ctx, "EXPLAIN "+s.query, s.args)
+	if err != nil {
+		return "", false
+	}
+	defer rows.Close()
+	var lines []string
+	dest := make([]driver.Value, len(rows.Columns()))
+	for {
+		err := rows.Next(dest)
+		if err == io.EOF {
+			break
+		} else if err != nil || len(dest) == 0 {
+			return "", false
+		}
+		switch v := dest[0].(type) {
+		case string:
+			lines = append(lines, v)
+		case []byte:
+			lines = append(lines, string(v))
+		}
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// explainable reports whether EXPLAIN can be applied to query
+// without executing it.
+func explainable(query string) bool {
+	f := strings.Fields(query)
+	if len(f) == 0 {
+		return false
+	}
+	switch strings.ToUpper(f[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"chain/log/logtest"
+)
+
+// fakeDriver returns two rows for every query and
+// reports one row affected for every exec.
+// Queries starting with EXPLAIN return a fixed plan.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.HasPrefix(query, "EXPLAIN ") {
+		return &fakeRows{vals: []string{"Seq Scan on t", "  Filter: (x = $1)"}}, nil
+	}
+	return &fakeRows{vals: []string{"a", "b"}}, nil
+}
+
+type fakeRows struct {
+	vals []string
+}
+
+func (r *fakeRows) Columns() []string { return []string{"x"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	dest[0], r.vals = r.vals[0], r.vals[1:]
+	return nil
+}
+
+func init() {
+	sql.Register("sqlutil-slow", SlowQueryDriver(fakeDriver{}, 0, 100))
+	sql.Register("sqlutil-fast", SlowQueryDriver(fakeDriver{}, time.Hour, 100))
+}
+
+func TestSlowQuery(t *testing.T) {
+	out := logtest.Capture(t)
+
+	db, err := sql.Open("sqlutil-slow", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	rows, err := db.QueryContext(ctx, "SELECT x\n\tFROM t WHERE x = $1", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	if _, err := db.ExecContext(ctx, "UPDATE t SET x = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want 2", entries)
+	}
+	want := logtest.Entry{"slowquery": "SELECT x FROM t WHERE x = $1", "rows": "2", "plan": "Seq Scan on t\n  Filter: (x = $1)"}
+	if !entries[0].Matches(want) {
+		t.Errorf("entry = %v, want %v", entries[0], want)
+	}
+	if want := (logtest.Entry{"rows": "1"}); !entries[1].Matches(want) {
+		t.Errorf("entry = %v, want %v", entries[1], want)
+	}
+}
+
+func TestFastQuery(t *testing.T) {
+	out := logtest.Capture(t)
+
+	db, err := sql.Open("sqlutil-fast", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(context.Background(), "UPDATE t SET x = 1"); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); s != "" {
+		t.Errorf("log = %q, want nothing", s)
+	}
+}
@@ -7,12 +7,16 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var funcs []func() bool
+var (
+	funcs []func() bool
+	names []string
+)
 
 // Int returns a new int pointer.
 // When Parse is called,
@@ -31,6 +35,7 @@ func Int(name string, value int) *int {
 // value of the environment var.
 func IntVar(p *int, name string, value int) {
 	*p = value
+	names = append(names, name)
 	funcs = append(funcs, func() bool {
 		if s := os.Getenv(name); s != "" {
 			v, err := strconv.Atoi(s)
@@ -62,6 +67,7 @@ func Bool(name string, value bool) *bool {
 // of the environment variable.
 func BoolVar(p *bool, name string, value bool) {
 	*p = value
+	names = append(names, name)
 	funcs = append(funcs, func() bool {
 		if s := os.Getenv(name); s != "" {
 			v, err := strconv.ParseBool(s)
@@ -93,6 +99,7 @@ func Duration(name string, value time.Duration) *time.Duration {
 // variable.
 func DurationVar(p *time.Duration, name string, value time.Duration) {
 	*p = value
+	names = append(names, name)
 	funcs = append(funcs, func() bool {
 		if s := os.Getenv(name); s != "" {
 			v, err := time.ParseDuration(s)
@@ -130,6 +137,7 @@ func URLVar(p *url.URL, name string, value string) {
 		panic(err)
 	}
 	*p = *v
+	names = append(names, name)
 	funcs = append(funcs, func() bool {
 		if s := os.Getenv(name); s != "" {
 			v, err := url.Parse(s)
@@ -160,6 +168,7 @@ func String(name string, value string) *string {
 // var.
 func StringVar(p *string, name string, value string) {
 	*p = value
+	names = append(names, name)
 	funcs = append(funcs, func() bool {
 		if s := os.Getenv(name); s != "" {
 			*p = s
@@ -186,6 +195,7 @@ func StringSlice(name string, value ...string) *[]string {
 // to store the value of the environment var.
 func StringSliceVar(p *[]string, name string, value ...string) {
 	*p = value
+	names = append(names, name)
 	funcs = append(funcs, func() bool {
 		if s := os.Getenv(name); s != "" {
 			a := strings.Split(s, ",")
@@ -195,6 +205,14 @@ func StringSliceVar(p *[]string, name string, value ...string) {
 	})
 }
 
+// Names returns the names of all env vars
+// that have been registered, in sorted order.
+func Names() []string {
+	a := append([]string(nil), names...)
+	sort.Strings(a)
+	return a
+}
+
 // Parse parses known env vars
 // and assigns the values to the variables
 // that were previously registered.
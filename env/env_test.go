@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -273,3 +274,19 @@ func TestStringSliceVar(t *testing.T) {
 		t.Fatalf("expected %v, got %v", exp, result)
 	}
 }
+
+func TestNames(t *testing.T) {
+	String("names-b", "")
+	Bool("names-a", false)
+
+	var got []string
+	for _, name := range Names() {
+		if strings.HasPrefix(name, "names-") {
+			got = append(got, name)
+		}
+	}
+	want := []string{"names-a", "names-b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v want %v", got, want)
+	}
+}
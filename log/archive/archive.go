@@ -0,0 +1,215 @@
+// Package archive buffers log entries and uploads them
+// periodically, as one object per period, to S3 or
+// GCS, for long-term retention independent of the
+// sinks that feed search and alerting.
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"chain/log/internal/batch"
+	"chain/sync/retry"
+)
+
+// ErrClosed is returned by Write after Close.
+var ErrClosed = errors.New("log archive writer closed")
+
+// An Uploader stores objects.
+type Uploader interface {
+	Upload(ctx context.Context, key string, body []byte, gzipped bool) error
+}
+
+// A Writer is an io.Writer that buffers entries in memory
+// and uploads them at the end of each period.
+// Its methods are safe for concurrent use.
+type Writer struct {
+	up       Uploader
+	every    time.Duration
+	prefix   string
+	compress bool
+	now      func() time.Time
+
+	closeOnce sync.Once
+	closing   chan struct{}  // closed by Close
+	done      chan struct{}  // closed when run returns
+	uploads   sync.WaitGroup // started by flush
+
+	mu     sync.Mutex // protects the following
+	closed bool
+	start  time.Time // of the current period; zero if buf is empty
+	buf    bytes.Buffer
+	zw     *gzip.Writer
+}
+
+// An Option configures a Writer.
+type Option func(*Writer)
+
+// Every sets the period covered by each object.
+// The default is one hour.
+// Periods are aligned to multiples of d since
+// the zero time, so hourly objects start on the hour.
+func Every(d time.Duration) Option {
+	return func(w *Writer) { w.every = d }
+}
+
+// Prefix sets a prefix for object keys,
+// such as "logs/cored/".
+func Prefix(p string) Option {
+	return func(w *Writer) { w.prefix = p }
+}
+
+// Compress makes the Writer gzip objects,
+// which are buffered compressed.
+func Compress() Option {
+	return func(w *Writer) { w.compress = true }
+}
+
+var hostname, _ = os.Hostname()
+
+// New returns a Writer that uploads with up.
+// Each object's key is the prefix followed by the start of
+// its period, in UTC, the host name, and the suffix
+// ".log" or, if compressed, ".log.gz", as in
+// logs/cored/2017/03/01/13-00-00-host.log.gz.
+//
+// Objects are uploaded in the background, retrying
+// with backoff. An object that still fails to upload
+// is lost; entries about the failure go to stderr.
+func New(up Uploader, opts ...Option) *Writer {
+	w := &Writer{
+		up:      up,
+		every:   time.Hour,
+		now:     time.Now,
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	go w.run()
+	return w
+}
+
+// Write buffers p, first uploading the previous
+// period's entries if a new period has begun.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+	now := w.now()
+	if !w.start.IsZero() && now.Truncate(w.every) != w.start {
+		w.flush()
+	}
+	if w.start.IsZero() {
+		w.start = now.Truncate(w.every)
+		if w.compress {
+			w.zw = gzip.NewWriter(&w.buf)
+		}
+	}
+	if w.zw != nil {
+		return w.zw.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush uploads the buffered entries now, and waits
+// for the upload to finish. Programs call it before
+// exiting, so the last partial period isn't lost.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	key, body := w.take()
+	w.mu.Unlock()
+	if body == nil {
+		return nil
+	}
+	return w.upload(key, body)
+}
+
+// Close uploads the buffered entries, waits for
+// the uploads in progress, and stops the background
+// goroutine. Writes after Close fail with ErrClosed.
+func (w *Writer) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.closing)
+		<-w.done
+		w.mu.Lock()
+		w.closed = true
+		key, body := w.take()
+		w.mu.Unlock()
+		if body != nil {
+			err = w.upload(key, body)
+		}
+		w.uploads.Wait()
+	})
+	return err
+}
+
+// run uploads each period's entries once it ends,
+// even if no entry is written in the next period.
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.closing:
+			return
+		}
+		w.mu.Lock()
+		if !w.start.IsZero() && w.now().Truncate(w.every) != w.start {
+			w.flush()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// flush starts uploading the buffered entries.
+// The caller must hold w.mu.
+func (w *Writer) flush() {
+	key, body := w.take()
+	if body != nil {
+		w.uploads.Add(1)
+		go func() {
+			defer w.uploads.Done()
+			w.upload(key, body)
+		}()
+	}
+}
+
+// take returns the object for the buffered entries,
+// if any, and empties the buffer.
+// The caller must hold w.mu.
+func (w *Writer) take() (key string, body []byte) {
+	if w.start.IsZero() {
+		return "", nil
+	}
+	if w.zw != nil {
+		w.zw.Close()
+		w.zw = nil
+	}
+	key = w.prefix + w.start.UTC().Format("2006/01/02/15-04-05") + "-" + hostname + ".log"
+	if w.compress {
+		key += ".gz"
+	}
+	body = append([]byte(nil), w.buf.Bytes()...)
+	w.buf.Reset()
+	w.start = time.Time{}
+	return key, body
+}
+
+func (w *Writer) upload(key string, body []byte) error {
+	p := retry.Policy{Attempts: 5, Initial: time.Second, Logger: batch.Stderr}
+	return retry.Do(context.Background(), "archive-upload", p, func(ctx context.Context) error {
+		return w.up.Upload(ctx, key, body, w.compress)
+	})
+}
@@ -0,0 +1,101 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeUploader struct {
+	mu   sync.Mutex
+	objs map[string][]byte
+	done chan string
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, key string, body []byte, gzipped bool) error {
+	u.mu.Lock()
+	u.objs[key] = body
+	u.mu.Unlock()
+	u.done <- key
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	up := &fakeUploader{objs: make(map[string][]byte), done: make(chan string, 2)}
+	now := time.Date(2017, 3, 1, 13, 59, 0, 0, time.UTC)
+	w := New(up, Prefix("logs/"), Compress())
+	w.now = func() time.Time { return now }
+
+	w.Write([]byte("a\n"))
+	w.Write([]byte("b\n"))
+	now = now.Add(2 * time.Minute) // next hour
+	w.Write([]byte("c\n"))
+
+	key0 := "logs/2017/03/01/13-00-00-" + hostname + ".log.gz"
+	select {
+	case key := <-up.done:
+		if key != key0 {
+			t.Errorf("key = %q want %q", key, key0)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for upload")
+	}
+	err := w.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-up.done
+
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	for key, want := range map[string]string{
+		key0: "a\nb\n",
+		"logs/2017/03/01/14-00-00-" + hostname + ".log.gz": "c\n",
+	} {
+		zr, err := gzip.NewReader(bytes.NewReader(up.objs[key]))
+		if err != nil {
+			t.Errorf("%s: %v", key, err)
+			continue
+		}
+		got, err := ioutil.ReadAll(zr)
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v want %q", key, got, err, want)
+		}
+	}
+	if err := w.Flush(); err != nil || len(up.objs) != 2 {
+		t.Errorf("empty Flush = %v, uploaded %d objects", err, len(up.objs))
+	}
+}
+
+func TestClose(t *testing.T) {
+	up := &fakeUploader{objs: make(map[string][]byte), done: make(chan string, 1)}
+	w := New(up, Prefix("logs/"))
+	w.now = func() time.Time { return time.Date(2017, 3, 1, 13, 0, 0, 0, time.UTC) }
+
+	w.Write([]byte("a\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-w.done:
+	default:
+		t.Error("background goroutine still running after Close")
+	}
+	key := "logs/2017/03/01/13-00-00-" + hostname + ".log"
+	up.mu.Lock()
+	got := string(up.objs[key])
+	up.mu.Unlock()
+	if got != "a\n" {
+		t.Errorf("%s = %q want %q", key, got, "a\n")
+	}
+	if _, err := w.Write([]byte("b\n")); err != ErrClosed {
+		t.Errorf("Write after Close = %v want %v", err, ErrClosed)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type s3Uploader struct {
+	client *s3.S3
+	bucket string
+}
+
+// S3 returns an Uploader that puts objects in the given bucket.
+// A client configured with the endpoint
+// https://storage.googleapis.com and HMAC keys
+// uploads to Google Cloud Storage through its
+// S3-compatible API.
+func S3(client *s3.S3, bucket string) Uploader {
+	return &s3Uploader{client: client, bucket: bucket}
+}
+
+func (u *s3Uploader) Upload(ctx context.Context, key string, body []byte, gzipped bool) error {
+	in := &s3.PutObjectInput{
+		Bucket:      aws.String(u.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("text/plain; charset=utf-8"),
+	}
+	if gzipped {
+		in.ContentEncoding = aws.String("gzip")
+	}
+	_, err := u.client.PutObjectWithContext(ctx, in)
+	return err
+}
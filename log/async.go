@@ -0,0 +1,133 @@
+package log
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by an AsyncWriter's Write
+// when its queue is full.
+var ErrQueueFull = errors.New("log queue full")
+
+// asyncFlushTimeout is the longest an AsyncWriter's Flush
+// waits for the queued entries to be written.
+var asyncFlushTimeout = 5 * time.Second
+
+// An AsyncWriter queues entries and writes them
+// to another writer from a background goroutine.
+type AsyncWriter struct {
+	w     io.Writer
+	queue chan []byte
+
+	mu       sync.Mutex    // protects the following, and orders sends on queue
+	queued   int64         // entries accepted by Write
+	written  int64         // entries written to w
+	progress chan struct{} // closed, and replaced, as each entry is written
+}
+
+// Async returns a writer that queues a copy of each entry,
+// up to size entries, and writes them, in order, to w
+// from a background goroutine, so that logging never
+// blocks on a slow disk or network sink.
+// When the queue is full, Write discards the entry
+// and returns ErrQueueFull; the log package counts it
+// in expvar "log_dropped" under "queue".
+// Errors writing to w are not reported;
+// wrap w with InstrumentSink to observe them.
+//
+// Programs call Flush before exiting,
+// so that queued entries aren't lost.
+func Async(w io.Writer, size int) *AsyncWriter {
+	if size < 1 {
+		size = 1
+	}
+	a := &AsyncWriter{w: w, queue: make(chan []byte, size), progress: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p without blocking.
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case a.queue <- append([]byte(nil), p...):
+		a.queued++
+		return len(p), nil
+	default:
+		return 0, ErrQueueFull
+	}
+}
+
+// Flush waits until the entries queued before it
+// are written, then flushes the underlying writer,
+// if it has a Flush method.
+// It doesn't take a place in the queue, so it doesn't
+// block when the queue is full, and it gives up after
+// five seconds if the underlying writer is stuck.
+func (a *AsyncWriter) Flush() error {
+	timeout := time.NewTimer(asyncFlushTimeout)
+	defer timeout.Stop()
+	a.mu.Lock()
+	target := a.queued
+	for a.written < target {
+		progress := a.progress
+		a.mu.Unlock()
+		select {
+		case <-progress:
+		case <-timeout.C:
+			return errFlushTimeout
+		}
+		a.mu.Lock()
+	}
+	a.mu.Unlock()
+	return flushWriter(a.w)
+}
+
+var errFlushTimeout = errors.New("log flush timed out")
+
+func (a *AsyncWriter) run() {
+	for entry := range a.queue {
+		writeIsolated(a.w, entry)
+		a.mu.Lock()
+		a.written++
+		close(a.progress)
+		a.progress = make(chan struct{})
+		a.mu.Unlock()
+	}
+}
+
+// Flush writes the entries held in shards (see SetWriteShards),
+// then flushes the log output, if it has a Flush method,
+// such as an AsyncWriter, so that entries buffered
+// or queued by the output are written.
+// Fatalkv calls it before exiting.
+func Flush() error {
+	flushShards()
+	return flushWriter(Output())
+}
+
+// flushWriter calls w's Flush method, if any.
+func flushWriter(w io.Writer) error {
+	switch f := w.(type) {
+	case interface {
+		Flush() error
+	}:
+		return f.Flush()
+	case interface {
+		Flush()
+	}:
+		f.Flush()
+	}
+	return nil
+}
+
+// closeWriter calls w's Close method, if any.
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateWriter signals on started as each write begins,
+// and blocks it until a value arrives on gate.
+type gateWriter struct {
+	started chan struct{}
+	gate    chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{started: make(chan struct{}, 10), gate: make(chan struct{}, 10)}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsync(t *testing.T) {
+	w := newGateWriter()
+	a := Async(w, 2)
+
+	p := []byte("a\n")
+	a.Write(p)
+	p[0] = 'x'  // Write must have copied p
+	<-w.started // the writer holds "a"; the queue is empty
+	a.Write([]byte("b\n"))
+	a.Write([]byte("c\n"))
+	if _, err := a.Write([]byte("d\n")); err != ErrQueueFull {
+		t.Errorf("Write to full queue: err = %v, want ErrQueueFull", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		w.gate <- struct{}{}
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := w.String(), "a\nb\nc\n"; got != want {
+		t.Errorf("written %q, want %q", got, want)
+	}
+}
+
+func TestAsyncFlushTimeout(t *testing.T) {
+	defer func(d time.Duration) { asyncFlushTimeout = d }(asyncFlushTimeout)
+	asyncFlushTimeout = 10 * time.Millisecond
+
+	w := newGateWriter()
+	a := Async(w, 1)
+	a.Write([]byte("a\n"))
+	<-w.started
+	a.Write([]byte("b\n")) // the queue is full
+	if err := a.Flush(); err != errFlushTimeout {
+		t.Errorf("Flush with a stuck writer: err = %v, want %v", err, errFlushTimeout)
+	}
+	close(w.gate)
+}
+
+func TestAsyncQueueDrop(t *testing.T) {
+	w := newGateWriter()
+	a := Async(w, 1)
+	defer SetOutput(Output())
+	SetOutput(a)
+
+	ctx := context.Background()
+	drops0 := droppedCount(dropQueue, severityInfo)
+	Printkv(ctx, "i", 0)
+	<-w.started // the writer holds entry 0; the queue is empty
+	Printkv(ctx, "i", 1)
+	Printkv(ctx, "i", 2)
+	if n := droppedCount(dropQueue, severityInfo) - drops0; n != 1 {
+		t.Errorf("dropped %d entries, want 1", n)
+	}
+	w.gate <- struct{}{}
+	w.gate <- struct{}{}
+	if err := Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// KeyAudit identifies entries written with Audit.
+const KeyAudit = "audit"
+
+// dropAudit is the cause recorded in expvar "log_dropped"
+// for audit entries the audit output failed to write.
+const dropAudit = "audit"
+
+var (
+	auditMu     sync.Mutex // protects the following
+	auditWriter io.Writer
+)
+
+// SetAuditOutput sets the audit stream's output to w.
+// If w is nil (the default), audit entries are written
+// to the regular log output, regardless of the level
+// set by SetLevel.
+func SetAuditOutput(w io.Writer) {
+	auditMu.Lock()
+	auditWriter = w
+	auditMu.Unlock()
+}
+
+// Audit writes an entry to the audit stream recording
+// a security-relevant operation, such as the use of a key.
+// The entry's first field is audit=[event], followed by keyvals,
+// formatted as in Printkv.
+func Audit(ctx context.Context, event string, keyvals ...interface{}) {
+	keyvals = append([]interface{}{KeyAudit, event}, keyvals...)
+
+	logError := len(keyvals)%2 != 0
+	if logError {
+		keyvals = append(keyvals, "", keyLogError, "odd number of log params")
+	}
+	t := time.Now().UTC()
+	_, loc := caller()
+	sev := severity(keyvals)
+
+	entry := formatEntry(ctx, currentFormat(), processPrefix(), sev, t, loc, keyvals)
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditWriter == nil {
+		countEntry(sev, logError)
+		writeEntry(t, sev, entry)
+		return
+	}
+	_, err := auditWriter.Write(entry)
+	if err != nil {
+		countDrop(dropAudit, sev)
+	}
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestAudit(t *testing.T) {
+	out := captureOutput(t)
+
+	ctx := context.Background()
+	Audit(ctx, "sign", "key", "abc")
+	entries := out.Entries()
+	if len(entries) != 1 || !entries[0].Matches(capture.Entry{KeyAudit: "sign", "key": "abc"}) {
+		t.Fatalf("entries = %v, want audit=sign key=abc", entries)
+	}
+	if at := entries[0][KeyCaller]; !strings.HasPrefix(at, "audit_test.go:") {
+		t.Errorf("at = %q, want audit_test.go:*", at)
+	}
+
+	out.Reset()
+	auditOut := new(capture.Recorder)
+	SetAuditOutput(auditOut)
+	defer SetAuditOutput(nil)
+	SetPrefix("app", "cored")
+	defer SetPrefix()
+
+	Audit(AddPrefixkv(ctx, "reqid", "r1"), "sign", "key", "def")
+	if got := out.String(); got != "" {
+		t.Errorf("log = %q, want empty", got)
+	}
+	entries = auditOut.Entries()
+	if len(entries) != 1 || !entries[0].Matches(capture.Entry{"app": "cored", "reqid": "r1", KeyAudit: "sign", "key": "def"}) {
+		t.Fatalf("audit entries = %v, want app=cored reqid=r1 audit=sign key=def", entries)
+	}
+	if at := entries[0][KeyCaller]; !strings.HasPrefix(at, "audit_test.go:") {
+		t.Errorf("audit at = %q, want audit_test.go:*", at)
+	}
+}
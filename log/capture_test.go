@@ -0,0 +1,17 @@
+package log
+
+import (
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+// captureOutput is logtest.Capture, for this package's
+// own tests, which can't import logtest.
+func captureOutput(t testing.TB) *capture.Recorder {
+	r := new(capture.Recorder)
+	old := Output()
+	SetOutput(r)
+	t.Cleanup(func() { SetOutput(old) })
+	return r
+}
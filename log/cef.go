@@ -0,0 +1,149 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	deviceMu      sync.Mutex // protects the following
+	deviceVendor  = "Chain"
+	deviceProduct = "cored"
+	deviceVersion = ""
+)
+
+// SetDevice sets the vendor, product, and version
+// written in the header of entries in the CEF and LEEF formats.
+// The defaults are "Chain", "cored", and "".
+func SetDevice(vendor, product, version string) {
+	deviceMu.Lock()
+	deviceVendor, deviceProduct, deviceVersion = vendor, product, version
+	deviceMu.Unlock()
+}
+
+// CEF and LEEF severities, from 0 (or 1) to 10.
+var siemSeverities = map[string]int{
+	severityDebug:   1,
+	severityInfo:    3,
+	severityWarning: 6,
+	severityError:   8,
+}
+
+// siemKeys maps conventional keys to their names
+// in CEF and LEEF extensions.
+// Other keys are written unchanged.
+var siemKeys = map[Format]map[string]string{
+	CEF: {
+		KeyMessage:  "msg",
+		KeyError:    "reason",
+		"reqid":     "externalId",
+		"principal": "suser",
+	},
+	LEEF: {
+		KeyMessage:  "msg",
+		KeyError:    "reason",
+		"reqid":     "externalId",
+		"principal": "usrName",
+	},
+}
+
+// formatSIEM writes an entry in the CEF or LEEF format.
+// The event ID in the header is the entry's KeyAudit
+// field, if it has one, or else its severity.
+// The event name (CEF only) is the entry's summary.
+// The time goes in extension rt (CEF) or devTime (LEEF),
+// the caller's location in cs1 (CEF, labeled "at") or at (LEEF),
+// and the other fields are renamed as in siemKeys.
+// The stack trace is omitted.
+func formatSIEM(buf *bytes.Buffer, f Format, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}) {
+	deviceMu.Lock()
+	vendor, product, version := deviceVendor, deviceProduct, deviceVersion
+	deviceMu.Unlock()
+
+	eventID := sev
+	for i := 0; i < len(fields); i += 2 {
+		if fields[i] == KeyAudit {
+			eventID = fmt.Sprint(fields[i+1])
+			break
+		}
+	}
+
+	keys := siemKeys[f]
+	var ext []string
+	add := func(k, v string) {
+		if f == CEF {
+			ext = append(ext, k+"="+cefExtValue(v))
+		} else {
+			ext = append(ext, k+"="+leefValue(v))
+		}
+	}
+	if f == CEF {
+		buf.WriteString("CEF:0")
+		for _, s := range []string{vendor, product, version, eventID, summary(fields)} {
+			buf.WriteString("|" + cefHeaderValue(s))
+		}
+		fmt.Fprintf(buf, "|%d|", siemSeverities[sev])
+		add("rt", strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+		add("cs1Label", KeyCaller)
+		add("cs1", loc)
+	} else {
+		buf.WriteString("LEEF:1.0")
+		for _, s := range []string{vendor, product, version, eventID} {
+			buf.WriteString("|" + leefHeaderValue(s))
+		}
+		buf.WriteString("|")
+		add("devTime", strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+		add("devTimeFormat", "epoch")
+		add("sev", strconv.Itoa(siemSeverities[sev]))
+		add(KeyCaller, loc)
+	}
+	all := append(pre[:len(pre):len(pre)], fields...)
+	for i := 0; i < len(all); i += 2 {
+		k := formatKey(all[i])
+		if name, ok := keys[k]; ok {
+			k = name
+		} else {
+			k = siemKey(k)
+		}
+		add(k, valueString(all[i+1]))
+	}
+	sep := " "
+	if f == LEEF {
+		sep = "\t"
+	}
+	buf.WriteString(strings.Join(ext, sep))
+	buf.WriteByte('\n')
+}
+
+// siemKey returns k with every character that is
+// not a letter, digit, or underscore removed,
+// as CEF and LEEF extension keys require.
+func siemKey(k string) string {
+	k = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return -1
+	}, k)
+	if k == "" {
+		return "unknown"
+	}
+	return k
+}
+
+var (
+	cefHeaderEscaper  = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtEscaper     = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+	leefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ", "\t", " ")
+	leefEscaper       = strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+)
+
+func cefHeaderValue(s string) string  { return cefHeaderEscaper.Replace(s) }
+func cefExtValue(s string) string     { return cefExtEscaper.Replace(s) }
+func leefHeaderValue(s string) string { return leefHeaderEscaper.Replace(s) }
+func leefValue(s string) string       { return leefEscaper.Replace(s) }
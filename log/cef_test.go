@@ -0,0 +1,39 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatSIEM(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	SetDevice("Chain", "cored", "1.2")
+	defer SetDevice("Chain", "cored", "")
+
+	cases := []struct {
+		f      Format
+		sev    string
+		fields []interface{}
+		want   string
+	}{{
+		CEF, severityError,
+		[]interface{}{KeyError, "bad=1|2\nx", "height", 7},
+		`CEF:0|Chain|cored|1.2|error|bad=1\|2 x|8|rt=1488373445006 cs1Label=at cs1=a.go:1 externalId=r1 reason=bad\=1|2\nx height=7` + "\n",
+	}, {
+		CEF, severityInfo,
+		[]interface{}{KeyAudit, "sign", "principal", "token:t1", "key-id", "k"},
+		`CEF:0|Chain|cored|1.2|sign|audit=sign principal=token:t1 key-id=k|3|rt=1488373445006 cs1Label=at cs1=a.go:1 externalId=r1 audit=sign suser=token:t1 keyid=k` + "\n",
+	}, {
+		LEEF, severityWarning,
+		[]interface{}{"warning", "slow\tdown", KeyMessage, "hi"},
+		"LEEF:1.0|Chain|cored|1.2|warning|devTime=1488373445006\tdevTimeFormat=epoch\tsev=6\tat=a.go:1\texternalId=r1\twarning=slow down\tmsg=hi\n",
+	}}
+	for i, c := range cases {
+		buf := new(bytes.Buffer)
+		formatSIEM(buf, c.f, []interface{}{"reqid", "r1"}, c.sev, ts, "a.go:1", c.fields)
+		if got := buf.String(); got != c.want {
+			t.Errorf("%d: formatSIEM =\n%q want\n%q", i, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// color is nonzero if the Console format uses color.
+var color int32
+
+// ANSI escape sequences for Console severity labels.
+var severityColors = map[string]string{
+	severityDebug:   "\x1b[90m", // gray
+	severityInfo:    "\x1b[36m", // cyan
+	severityWarning: "\x1b[33m", // yellow
+	severityError:   "\x1b[31m", // red
+}
+
+var severityLabels = map[string]string{
+	severityDebug:   "DEBUG",
+	severityInfo:    "INFO ",
+	severityWarning: "WARN ",
+	severityError:   "ERROR",
+}
+
+// SetColor sets whether entries in the Console format
+// use color to show their severity. The default is false.
+// See also UseColor.
+func SetColor(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&color, v)
+}
+
+// IsTerminal reports whether w is a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// DefaultFormat returns the format best suited to entries
+// written to w: Console if w is a terminal, KV otherwise.
+func DefaultFormat(w io.Writer) Format {
+	if IsTerminal(w) {
+		return Console
+	}
+	return KV
+}
+
+// UseColor reports whether entries written to w
+// in the Console format should use color:
+// that is, whether w is a terminal and
+// the NO_COLOR environment variable is unset or empty.
+// See https://no-color.org.
+func UseColor(w io.Writer) bool {
+	return IsTerminal(w) && os.Getenv("NO_COLOR") == ""
+}
+
+// formatConsole writes an entry for a person to read.
+// It begins with the local time, severity, and message,
+// followed by the remaining fields and the caller's location.
+func formatConsole(buf *bytes.Buffer, prefix []byte, sev string, t time.Time, loc string, fields []interface{}) {
+	buf.WriteString(t.Local().Format("15:04:05.000 "))
+	if atomic.LoadInt32(&color) != 0 {
+		buf.WriteString(severityColors[sev] + severityLabels[sev] + "\x1b[0m")
+	} else {
+		buf.WriteString(severityLabels[sev])
+	}
+
+	rest := fields[:0:0]
+	for i := 0; i < len(fields); i += 2 {
+		if fields[i] == KeyMessage {
+			fmt.Fprintf(buf, " %v", fields[i+1])
+			continue
+		}
+		rest = append(rest, fields[i], fields[i+1])
+	}
+	buf.WriteByte(' ')
+	buf.Write(prefix)
+	for i := 0; i < len(rest); i += 2 {
+		writeKV(buf, rest[i], rest[i+1])
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(KeyCaller + "=" + loc + "\n")
+}
+
+// processStart is the origin of the relative times
+// in the Dev format.
+var processStart = time.Now()
+
+// devMessageWidth is the width of the message column
+// in the Dev format, so most entries' fields line up.
+const devMessageWidth = 40
+
+// formatDev writes an entry for a developer to read,
+// as formatConsole, but with the time elapsed since
+// the process started, the message padded to a fixed
+// width, and, if color is on, keys dimmed.
+func formatDev(buf *bytes.Buffer, prefix []byte, sev string, t time.Time, loc string, fields []interface{}) {
+	fmt.Fprintf(buf, "+%10.3fs ", t.Sub(processStart).Seconds())
+	on := atomic.LoadInt32(&color) != 0
+	if on {
+		buf.WriteString(severityColors[sev] + severityLabels[sev] + "\x1b[0m")
+	} else {
+		buf.WriteString(severityLabels[sev])
+	}
+
+	var msg string
+	rest := fields[:0:0]
+	for i := 0; i < len(fields); i += 2 {
+		if fields[i] == KeyMessage {
+			msg += fmt.Sprint(fields[i+1])
+			continue
+		}
+		rest = append(rest, fields[i], fields[i+1])
+	}
+	fmt.Fprintf(buf, " %-*s ", devMessageWidth, msg)
+	buf.Write(prefix)
+	rest = append(rest, KeyCaller, loc)
+	for i := 0; i < len(rest); i += 2 {
+		k := formatKey(rest[i]) + "="
+		if on {
+			k = "\x1b[2m" + k + "\x1b[0m"
+		}
+		buf.WriteString(k + formatValue(rest[i+1]) + " ")
+	}
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteByte('\n')
+}
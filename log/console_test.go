@@ -0,0 +1,66 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatConsole(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.Local)
+	cases := []struct {
+		color  bool
+		sev    string
+		fields []interface{}
+		want   string
+	}{
+		{false, severityInfo, []interface{}{"height", 7, KeyMessage, "block committed"}, "13:04:05.006 INFO  block committed reqid=r1 height=7 at=a.go:1\n"},
+		{false, severityWarning, []interface{}{"warning", "slow"}, "13:04:05.006 WARN  reqid=r1 warning=slow at=a.go:1\n"},
+		{true, severityError, []interface{}{KeyError, "boom"}, "13:04:05.006 \x1b[31mERROR\x1b[0m reqid=r1 error=boom at=a.go:1\n"},
+	}
+	defer SetColor(false)
+	for _, c := range cases {
+		SetColor(c.color)
+		buf := new(bytes.Buffer)
+		formatConsole(buf, []byte("reqid=r1 "), c.sev, ts, "a.go:1", c.fields)
+		if got := buf.String(); got != c.want {
+			t.Errorf("formatConsole(%v) = %q want %q", c.fields, got, c.want)
+		}
+	}
+}
+
+func TestSetFormatConsole(t *testing.T) {
+	out := captureOutput(t)
+	SetFormat(Console)
+	defer SetFormat(KV)
+
+	Printkv(context.Background(), "warning", "careful")
+	if got, w := out.String(), "WARN  warning=careful at=console_test.go:"; !strings.Contains(got, w) {
+		t.Errorf("log = %q, want substring %q", got, w)
+	}
+	if DefaultFormat(out) != KV || UseColor(out) {
+		t.Error("buffer treated as a terminal")
+	}
+}
+
+func TestFormatDev(t *testing.T) {
+	ts := processStart.Add(12345 * time.Millisecond)
+	defer SetColor(false)
+
+	buf := new(bytes.Buffer)
+	formatDev(buf, nil, severityInfo, ts, "a.go:1", []interface{}{KeyMessage, "block committed", "height", 7})
+	want := "+    12.345s INFO  block committed                          height=7 at=a.go:1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("formatDev = %q want %q", got, want)
+	}
+
+	SetColor(true)
+	buf.Reset()
+	formatDev(buf, []byte("reqid=r1 "), severityWarning, ts, "a.go:1", []interface{}{"warning", "slow"})
+	want = "+    12.345s \x1b[33mWARN \x1b[0m " + strings.Repeat(" ", devMessageWidth) + " reqid=r1 \x1b[2mwarning=\x1b[0mslow \x1b[2mat=\x1b[0ma.go:1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("formatDev = %q want %q", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyRepeated is the key of a field added to an entry
+// written for a run of identical entries (see SetDedup).
+// Its value is the number of entries the run
+// contained after the first.
+const KeyRepeated = "repeated"
+
+var (
+	dedupWindow int64 // a time.Duration, set by SetDedup
+
+	dedupMu sync.Mutex // protects dup
+	dup     dupRun
+)
+
+// A dupRun is a run of identical consecutive entries.
+type dupRun struct {
+	key     string
+	n       int // suppressed repeats
+	timer   *time.Timer
+	ctx     context.Context
+	sev     string
+	t       time.Time // of the latest repeat
+	loc     string
+	keyvals []interface{}
+}
+
+// SetDedup enables coalescing of identical consecutive entries,
+// such as the same error logged on every attempt during an outage.
+// Entries are identical if they have the same severity,
+// call site, context prefix, and fields.
+// The first entry of a run is written as usual.
+// The rest are suppressed, and written as one entry,
+// with a KeyRepeated field giving their number,
+// when a different entry is logged or, at the latest,
+// window after the first suppressed entry.
+// Repeats continue to be coalesced after that,
+// so a long run becomes one entry per window.
+// It has no effect on a Logger with an output of its own.
+// A window of zero disables coalescing, the default.
+func SetDedup(window time.Duration) {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+	flushDupLocked()
+	dup.key = ""
+	atomic.StoreInt64(&dedupWindow, int64(window))
+}
+
+// dedupEnabled reports whether SetDedup
+// has enabled coalescing.
+func dedupEnabled() bool {
+	return atomic.LoadInt64(&dedupWindow) > 0
+}
+
+// dedup reports whether to suppress an entry
+// as a repeat of the previous one.
+// If it is not a repeat, dedup first writes
+// the entry for any suppressed repeats.
+func dedup(ctx context.Context, sev string, t time.Time, loc string, keyvals []interface{}) bool {
+	key := dupKey(ctx, sev, loc, keyvals)
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+	window := time.Duration(atomic.LoadInt64(&dedupWindow))
+	if window <= 0 {
+		return false
+	}
+	if key != "" && key == dup.key {
+		if dup.n == 0 {
+			dup.timer = time.AfterFunc(window, flushDup)
+		}
+		dup.n++
+		dup.t = t
+		return true
+	}
+	flushDupLocked()
+	dup = dupRun{key: key, ctx: ctx, sev: sev, loc: loc, keyvals: keyvals}
+	return false
+}
+
+func flushDup() {
+	dedupMu.Lock()
+	defer dedupMu.Unlock()
+	flushDupLocked()
+}
+
+// flushDupLocked writes the entry for the suppressed
+// repeats in the current run, if any.
+// The caller must hold dedupMu.
+func flushDupLocked() {
+	if dup.n == 0 {
+		return
+	}
+	dup.timer.Stop()
+	keyvals := make([]interface{}, 0, len(dup.keyvals)+2)
+	for i := 0; i < len(dup.keyvals); i += 2 {
+		k, v := dup.keyvals[i], dup.keyvals[i+1]
+		if e, ok := v.(error); ok && k == KeyError {
+			// The first entry of the run had the stack trace;
+			// don't capture one here, in the wrong place.
+			v = e.Error()
+		}
+		keyvals = append(keyvals, k, v)
+	}
+	keyvals = append(keyvals, KeyRepeated, dup.n)
+	dup.n = 0
+	countEntry(dup.sev, false)
+	writeEntry(dup.t, dup.sev, formatEntry(dup.ctx, currentFormat(), processPrefix(), dup.sev, dup.t, dup.loc, keyvals))
+}
+
+// dupKey returns a string that is the same
+// for identical entries, or "" if encoding
+// a value panics.
+func dupKey(ctx context.Context, sev, loc string, keyvals []interface{}) (key string) {
+	defer func() {
+		if recover() != nil {
+			key = ""
+		}
+	}()
+	var buf bytes.Buffer
+	buf.WriteString(sev)
+	buf.WriteByte(' ')
+	buf.WriteString(loc)
+	buf.WriteByte(' ')
+	buf.Write(prefix(ctx))
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		buf.WriteByte(' ')
+		writeKV(&buf, keyvals[i], keyvals[i+1])
+	}
+	return buf.String()
+}
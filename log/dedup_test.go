@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chain/log/internal/capture"
+)
+
+func TestDedup(t *testing.T) {
+	out := captureOutput(t)
+	SetDedup(time.Hour)
+	defer SetDedup(0)
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		Error(ctx, errors.New("connection refused"))
+	}
+	Printkv(ctx, "message", "recovered")
+
+	entries := out.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v, want 3", entries)
+	}
+	if _, ok := entries[0][KeyRepeated]; ok {
+		t.Errorf("first entry = %v, want no %s field", entries[0], KeyRepeated)
+	}
+	if w := (capture.Entry{KeyError: "connection refused", KeyRepeated: "4"}); !entries[1].Matches(w) {
+		t.Errorf("second entry = %v, want %v", entries[1], w)
+	}
+	if entries[2][KeyMessage] != "recovered" {
+		t.Errorf("third entry = %v, want the different entry", entries[2])
+	}
+}
+
+func TestDedupWindow(t *testing.T) {
+	out := captureOutput(t)
+	SetDedup(10 * time.Millisecond)
+	defer SetDedup(0)
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		Printkv(ctx, "warning", "slow")
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	dedupMu.Lock()
+	entries := out.Entries()
+	dedupMu.Unlock()
+	if len(entries) != 2 || entries[1][KeyRepeated] != "2" {
+		t.Errorf("entries = %v, want the entry and its repeats after the window", entries)
+	}
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"context"
+	"expvar"
+	"sync"
+)
+
+var (
+	// deprecatedCounts counts uses of each deprecated feature.
+	deprecatedCounts = expvar.NewMap("log_deprecated")
+
+	deprecatedMu   sync.Mutex
+	deprecatedSeen = map[string]bool{}
+)
+
+// Deprecated records a use of a deprecated feature.
+// Every use is counted in expvar "log_deprecated",
+// keyed by feature, so remaining usage can be measured
+// before the feature is removed.
+// The first use of each feature in the process also
+// writes a warning entry with keyvals.
+func Deprecated(ctx context.Context, feature string, keyvals ...interface{}) {
+	deprecatedCounts.Add(feature, 1)
+	deprecatedMu.Lock()
+	seen := deprecatedSeen[feature]
+	deprecatedSeen[feature] = true
+	deprecatedMu.Unlock()
+	if seen {
+		return
+	}
+	Printkv(ctx, append([]interface{}{"warning", "deprecated feature used", "feature", feature}, keyvals...)...)
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"context"
+	"expvar"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestDeprecated(t *testing.T) {
+	out := captureOutput(t)
+
+	deprecatedMu.Lock()
+	delete(deprecatedSeen, "test-feature")
+	deprecatedMu.Unlock()
+	deprecatedCounts.Set("test-feature", new(expvar.Int))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		Deprecated(ctx, "test-feature", "param", "old_name")
+	}
+
+	entries := out.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want 1", entries)
+	}
+	if w := (capture.Entry{"feature": "test-feature", "param": "old_name"}); !entries[0].Matches(w) {
+		t.Errorf("entry = %v, want %v", entries[0], w)
+	}
+	if at := entries[0][KeyCaller]; !strings.HasPrefix(at, "deprecated_test.go:") {
+		t.Errorf("at = %q, want deprecated_test.go:*", at)
+	}
+	if got := deprecatedCounts.Get("test-feature").(*expvar.Int).Value(); got != 3 {
+		t.Errorf("count = %d want 3", got)
+	}
+}
@@ -0,0 +1,53 @@
+package log
+
+import "sync/atomic"
+
+// A DuplicateKeys is a policy for fields with the same key
+// in one entry.
+type DuplicateKeys int32
+
+// Policies for SetDuplicateKeys.
+const (
+	KeepDuplicates DuplicateKeys = iota // write every field, the default
+	LastWins                            // write only the last field with each key
+	FirstWins                           // write only the first field with each key
+)
+
+var duplicateKeys int32 // a DuplicateKeys, set by SetDuplicateKeys
+
+// SetDuplicateKeys sets the policy for fields with the same key
+// in one entry, such as a field given both in a Context by With
+// and in the entry itself, which some log indexers reject
+// or resolve arbitrarily.
+// Under LastWins or FirstWins, only one field with each key
+// is written, in the place of the first.
+// Keys are compared as they are written,
+// and the Context prefix and auto-generated fields
+// are not affected.
+// It applies to every Logger, too.
+func SetDuplicateKeys(p DuplicateKeys) {
+	atomic.StoreInt32(&duplicateKeys, int32(p))
+}
+
+// uniqueKeys returns fields with duplicate keys removed
+// as set by SetDuplicateKeys.
+func uniqueKeys(fields []interface{}) []interface{} {
+	p := DuplicateKeys(atomic.LoadInt32(&duplicateKeys))
+	if p == KeepDuplicates || len(fields) <= 2 {
+		return fields
+	}
+	index := make(map[string]int, len(fields)/2) // key to position in out
+	out := make([]interface{}, 0, len(fields))
+	for i := 0; i+1 < len(fields); i += 2 {
+		k := formatKey(fields[i])
+		if j, ok := index[k]; ok {
+			if p == LastWins {
+				out[j+1] = fields[i+1]
+			}
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, fields[i], fields[i+1])
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSetDuplicateKeys(t *testing.T) {
+	out := captureOutput(t)
+	defer SetDuplicateKeys(KeepDuplicates)
+
+	ctx := With(context.Background(), "a", "ctx")
+	cases := []struct {
+		p    DuplicateKeys
+		want string
+	}{
+		{KeepDuplicates, " a=1 b=2 a=3 a=ctx\n"},
+		{LastWins, " a=ctx b=2\n"},
+		{FirstWins, " a=1 b=2\n"},
+	}
+	for _, c := range cases {
+		out.Reset()
+		SetDuplicateKeys(c.p)
+		Printkv(ctx, "a", 1, "b", 2, "a", 3)
+		if got := out.String(); !strings.HasSuffix(got, c.want) {
+			t.Errorf("policy %d: entry %q, want suffix %q", c.p, got, c.want)
+		}
+	}
+}
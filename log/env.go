@@ -0,0 +1,61 @@
+package log
+
+import (
+	"fmt"
+	"os"
+)
+
+// Environment variables read when the package is initialized,
+// so that the logging of any program using this package
+// can be reconfigured without changing its code.
+// A program's own configuration, such as a call to SetLevel,
+// takes precedence.
+const (
+	EnvLevel  = "CHAIN_LOG_LEVEL"  // as for ParseLevel
+	EnvFormat = "CHAIN_LOG_FORMAT" // as for ParseFormat
+	EnvOutput = "CHAIN_LOG_OUTPUT" // "stdout", "stderr", or a file path to append to
+)
+
+func init() {
+	for _, err := range configureFromEnv(os.Getenv) {
+		// The log output may not be usable yet,
+		// so report problems on stderr.
+		fmt.Fprintf(os.Stderr, "chain/log: %s\n", err)
+	}
+}
+
+// configureFromEnv applies the variables that getenv reports
+// as set and returns an error for each one it can't apply.
+func configureFromEnv(getenv func(string) string) (errs []error) {
+	if s := getenv(EnvLevel); s != "" {
+		l, err := ParseLevel(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", EnvLevel, err))
+		} else {
+			SetLevel(l)
+		}
+	}
+	if s := getenv(EnvFormat); s != "" {
+		f, err := ParseFormat(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", EnvFormat, err))
+		} else {
+			SetFormat(f)
+		}
+	}
+	switch s := getenv(EnvOutput); s {
+	case "":
+	case "stdout":
+		SetOutput(os.Stdout)
+	case "stderr":
+		SetOutput(os.Stderr)
+	default:
+		f, err := os.OpenFile(s, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", EnvOutput, err))
+		} else {
+			SetOutput(f)
+		}
+	}
+	return errs
+}
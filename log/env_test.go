@@ -0,0 +1,60 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestConfigureFromEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetOutput(Output())
+	defer SetLevel(LevelInfo)
+	defer SetFormat(KV)
+
+	path := filepath.Join(dir, "out.log")
+	env := map[string]string{
+		EnvLevel:  "warning",
+		EnvFormat: "json",
+		EnvOutput: path,
+	}
+	errs := configureFromEnv(func(k string) string { return env[k] })
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	Printf(context.Background(), "hidden")
+	Printkv(context.Background(), "warning", "shown")
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(capture.Recorder)
+	out.Write(b)
+	if got := out.Entries(); !strings.HasPrefix(string(b), "{") || len(got) != 1 || got[0]["warning"] != "shown" {
+		t.Errorf("log = %q, want only the warning, as JSON", b)
+	}
+}
+
+func TestConfigureFromEnvErrors(t *testing.T) {
+	env := map[string]string{
+		EnvLevel:  "loud",
+		EnvFormat: "xml",
+		EnvOutput: "/nonexistent/dir/out.log",
+	}
+	errs := configureFromEnv(func(k string) string { return env[k] })
+	if len(errs) != 3 {
+		t.Errorf("errs = %v, want 3", errs)
+	}
+	if CurrentLevel() != LevelInfo || currentFormat() != KV {
+		t.Errorf("level, format = %v, %v want unchanged", CurrentLevel(), currentFormat())
+	}
+}
@@ -0,0 +1,96 @@
+package log
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type failover struct {
+	primary, secondary io.Writer
+	limit              int
+	probe              time.Duration
+
+	mu       sync.Mutex // protects the following
+	failures int        // consecutive failed writes to primary
+	since    time.Time  // when primary was given up on; zero if in use
+	lastTry  time.Time  // of primary, while given up on
+}
+
+// Failover returns a writer that writes each entry to primary,
+// or, if that fails, to secondary, such as stderr.
+// After limit consecutive writes to primary fail,
+// it gives up on primary and writes only to secondary,
+// trying primary again with one entry every probe interval.
+// When primary accepts an entry, writes go
+// to it once more.
+//
+// It writes a warning entry, to the package-level output,
+// when giving up on primary, and an entry
+// recording the outage when primary recovers.
+func Failover(primary, secondary io.Writer, limit int, probe time.Duration) io.Writer {
+	if limit < 1 {
+		limit = 1
+	}
+	return &failover{primary: primary, secondary: secondary, limit: limit, probe: probe}
+}
+
+func (f *failover) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	if f.since.IsZero() || now.Sub(f.lastTry) >= f.probe {
+		err := writeIsolated(f.primary, p)
+		if err == nil {
+			if !f.since.IsZero() {
+				// Entries can't be written from inside Write,
+				// which runs with the output's lock held.
+				failures, outage := f.failures, now.Sub(f.since)
+				go func() {
+					Printkv(context.Background(),
+						KeyMessage, "log output recovered",
+						"failures", failures,
+						"outage", outage,
+					)
+				}()
+			}
+			f.failures, f.since = 0, time.Time{}
+			return len(p), nil
+		}
+		f.failures++
+		f.lastTry = now
+		if f.since.IsZero() && f.failures >= f.limit {
+			f.since = now
+			failures := f.failures
+			go func() {
+				Printkv(context.Background(),
+					"warning", "log output failing; writing to secondary output",
+					"failures", failures,
+					"lasterror", err.Error(),
+				)
+			}()
+		}
+	}
+	err := writeIsolated(f.secondary, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush flushes primary and secondary,
+// if they have Flush methods.
+func (f *failover) Flush() error {
+	err := flushWriter(f.primary)
+	if err2 := flushWriter(f.secondary); err == nil {
+		err = err2
+	}
+	return err
+}
+
+// Close closes primary, if it has a Close method.
+// Secondary, typically shared with other writers, is left open.
+func (f *failover) Close() error {
+	return closeWriter(f.primary)
+}
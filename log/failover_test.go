@@ -0,0 +1,69 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chain/log/internal/capture"
+)
+
+type toggleWriter struct {
+	fail   bool
+	writes int
+	buf    bytes.Buffer
+}
+
+func (w *toggleWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.fail {
+		return 0, errors.New("down")
+	}
+	return w.buf.Write(p)
+}
+
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestFailover(t *testing.T) {
+	entries := make(chanWriter, 10)
+	defer SetOutput(Output())
+	SetOutput(entries)
+
+	primary := &toggleWriter{fail: true}
+	var secondary bytes.Buffer
+	w := Failover(primary, &secondary, 2, time.Hour).(*failover)
+
+	w.Write([]byte("a\n")) // fails over for this entry only
+	w.Write([]byte("b\n")) // second failure; gives up on primary
+	w.Write([]byte("c\n")) // not tried on primary
+	if primary.writes != 2 {
+		t.Errorf("primary writes = %d want 2", primary.writes)
+	}
+	if got := secondary.String(); got != "a\nb\nc\n" {
+		t.Errorf("secondary = %q want %q", got, "a\nb\nc\n")
+	}
+	want := capture.Entry{"warning": "log output failing; writing to secondary output", "failures": "2"}
+	if e := capture.Parse(<-entries); !e.Matches(want) || !strings.HasPrefix(e[KeyCaller], "failover.go:") {
+		t.Errorf("entry = %v, want %v at failover.go", e, want)
+	}
+
+	primary.fail = false
+	w.mu.Lock()
+	w.lastTry = w.lastTry.Add(-2 * time.Hour) // time to probe
+	w.mu.Unlock()
+	w.Write([]byte("d\n"))
+	w.Write([]byte("e\n"))
+	if got := primary.buf.String(); got != "d\ne\n" {
+		t.Errorf("primary = %q want %q", got, "d\ne\n")
+	}
+	if e := capture.Parse(<-entries); e[KeyMessage] != "log output recovered" || e["outage"] == "" {
+		t.Errorf("entry = %v, want recovery message", e)
+	}
+}
@@ -0,0 +1,79 @@
+package log
+
+import (
+	"fmt"
+	"io"
+)
+
+type fanOut []io.Writer
+
+// FanOut returns a writer that writes each entry
+// to every one of ws, in order.
+// Unlike io.MultiWriter, a failing writer does not
+// stop the entry from reaching the others:
+// every writer is tried, even if one returns
+// an error or panics.
+// A write fails only if it fails for every writer,
+// in which case it returns the first error.
+//
+// To see which sinks are failing, wrap each of ws
+// with InstrumentSink.
+func FanOut(ws ...io.Writer) io.Writer {
+	return fanOut(append([]io.Writer(nil), ws...))
+}
+
+func (f fanOut) Write(p []byte) (int, error) {
+	var firstErr error
+	ok := false
+	for _, w := range f {
+		err := writeIsolated(w, p)
+		if err == nil {
+			ok = true
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if !ok && firstErr != nil {
+		return 0, firstErr
+	}
+	return len(p), nil
+}
+
+// Flush flushes each writer that has a Flush method,
+// and returns the first error.
+func (f fanOut) Flush() error {
+	var firstErr error
+	for _, w := range f {
+		if err := flushWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes each writer that has a Close method,
+// and returns the first error.
+func (f fanOut) Close() error {
+	var firstErr error
+	for _, w := range f {
+		if err := closeWriter(w); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// writeIsolated writes p to w,
+// converting a short write or a panic into an error.
+func writeIsolated(w io.Writer, p []byte) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic writing log entry: %v", v)
+		}
+	}()
+	n, err := w.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type failWriter struct{ err error }
+
+func (w failWriter) Write([]byte) (int, error) { return 0, w.err }
+
+type panicWriter struct{}
+
+func (panicWriter) Write([]byte) (int, error) { panic("boom") }
+
+func TestFanOut(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	var buf1, buf2 bytes.Buffer
+	w := FanOut(failWriter{errA}, &buf1, panicWriter{}, &buf2)
+	n, err := w.Write([]byte("x\n"))
+	if n != 2 || err != nil {
+		t.Errorf("Write = %d, %v want 2, nil", n, err)
+	}
+	if buf1.String() != "x\n" || buf2.String() != "x\n" {
+		t.Errorf("outputs = %q, %q want both %q", buf1.String(), buf2.String(), "x\n")
+	}
+
+	w = FanOut(failWriter{errA}, failWriter{errB})
+	if _, err := w.Write([]byte("x\n")); err != errA {
+		t.Errorf("Write error = %v want %v", err, errA)
+	}
+	if _, err := FanOut(panicWriter{}).Write([]byte("x\n")); err == nil {
+		t.Error("Write to panicking writer succeeded")
+	}
+}
+
+type closeWriterFunc func() error
+
+func (closeWriterFunc) Write(p []byte) (int, error) { return len(p), nil }
+func (f closeWriterFunc) Close() error              { return f() }
+
+func TestFanOutClose(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	var closed []string
+	closer := func(name string, err error) io.Writer {
+		return closeWriterFunc(func() error {
+			closed = append(closed, name)
+			return err
+		})
+	}
+	w := FanOut(closer("w1", nil), new(bytes.Buffer), closer("w2", errA), closer("w3", errB))
+	if err := w.(io.Closer).Close(); err != errA {
+		t.Errorf("Close = %v want %v", err, errA)
+	}
+	if got := strings.Join(closed, ","); got != "w1,w2,w3" {
+		t.Errorf("closed %s, want w1,w2,w3", got)
+	}
+}
@@ -0,0 +1,202 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A Field is a typed key-value pair for WriteFields.
+// Unlike a value passed to Printkv, it holds common types
+// without converting them to interface{}, so an entry
+// discarded by the minimum level costs next to nothing,
+// and one that is written is usually encoded straight
+// from its fields; see WriteFields.
+type Field struct {
+	Key  string
+	kind typedKind
+	n    int64
+	s    string
+	err  error
+}
+
+type typedKind int8
+
+const (
+	stringField typedKind = iota
+	intField
+	boolField
+	durationField
+	errorField
+)
+
+// String returns a Field with a string value.
+func String(key, v string) Field {
+	return Field{Key: key, kind: stringField, s: v}
+}
+
+// Int returns a Field with an integer value.
+func Int(key string, v int64) Field {
+	return Field{Key: key, kind: intField, n: v}
+}
+
+// Bool returns a Field with a boolean value.
+func Bool(key string, v bool) Field {
+	f := Field{Key: key, kind: boolField}
+	if v {
+		f.n = 1
+	}
+	return f
+}
+
+// Duration returns a Field with a duration value.
+func Duration(key string, d time.Duration) Field {
+	return Field{Key: key, kind: durationField, n: int64(d)}
+}
+
+// Err returns a Field with key KeyError and value err,
+// which makes the entry an error, as in Printkv.
+func Err(err error) Field {
+	return Field{Key: KeyError, kind: errorField, err: err}
+}
+
+// value returns f's value as it would be passed to Printkv.
+func (f Field) value() interface{} {
+	switch f.kind {
+	case intField:
+		return f.n
+	case boolField:
+		return f.n != 0
+	case durationField:
+		return time.Duration(f.n)
+	case errorField:
+		return f.err
+	}
+	return f.s
+}
+
+// WriteFields writes an entry with the given fields,
+// as Printkv, so that hot paths can log cheaply:
+//
+//	log.WriteFields(ctx, log.String(log.KeyLevel, "debug"), log.Int("height", h))
+//
+// Fields are converted to interface{} values only if
+// the entry could be written and something needs them:
+// hooks, filters, a vmodule spec, the debug buffer,
+// rate limiting, sampling, dedup, SetDuplicateKeys,
+// fields added by WithFields, an Err field, or a format
+// other than KV, Logfmt, or JSON. Otherwise the values
+// are encoded directly into the entry.
+func WriteFields(ctx context.Context, fields ...Field) {
+	sev := fieldSeverity(fields)
+	if !enabled(sev) && len(currentVModule().rules) == 0 && !debugBufferEnabled() {
+		countDrop(dropLevel, sev)
+		return
+	}
+	if f := currentFormat(); directFields(ctx, f, sev, fields) {
+		t := time.Now().UTC()
+		_, loc := caller()
+		countEntry(sev, false)
+		buf := getBuffer()
+		encodeFields(buf, ctx, f, processPrefix(), sev, t, loc, fields)
+		writeEntry(t, sev, buf.Bytes())
+		putBuffer(buf)
+		return
+	}
+	keyvals := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		keyvals = append(keyvals, f.Key, f.value())
+	}
+	printkv(ctx, caller, "", keyvals)
+}
+
+// directFields reports whether an entry with the given
+// fields, in format f, can be encoded by encodeFields,
+// with the same result as printkv.
+func directFields(ctx context.Context, f Format, sev string, fields []Field) bool {
+	if f != KV && f != Logfmt && f != JSON {
+		return false
+	}
+	if sev == severityError || !enabled(sev) {
+		return false
+	}
+	if len(hooks.Load().([]*Hook)) > 0 || len(filters.Load().([]*Filter)) > 0 {
+		return false
+	}
+	if len(currentVModule().rules) > 0 || debugBufferEnabled() || rateLimitEnabled() || samplingEnabled() || dedupEnabled() {
+		return false
+	}
+	if DuplicateKeys(atomic.LoadInt32(&duplicateKeys)) != KeepDuplicates || len(withFields(ctx)) > 0 {
+		return false
+	}
+	for _, fl := range fields {
+		if fl.kind == errorField {
+			return false
+		}
+	}
+	return true
+}
+
+// writeValue writes f's value to buf as format ff
+// encodes the value f.value(): integers and booleans
+// as in every format, strings and durations
+// quoted as the format quotes them.
+func (f Field) writeValue(buf *bytes.Buffer, ff Format) {
+	var a [24]byte
+	switch f.kind {
+	case intField:
+		buf.Write(strconv.AppendInt(a[:0], f.n, 10))
+		return
+	case boolField:
+		buf.Write(strconv.AppendBool(a[:0], f.n != 0))
+		return
+	case durationField:
+		d := time.Duration(f.n)
+		if ff == JSON && atomic.LoadInt64(&durationUnit) > 0 {
+			buf.WriteString(durationString(d))
+			return
+		}
+		f.writeString(buf, ff, durationString(d))
+		return
+	}
+	f.writeString(buf, ff, f.s)
+}
+
+// writeString writes the string value s to buf, in format ff.
+func (f Field) writeString(buf *bytes.Buffer, ff Format, s string) {
+	switch ff {
+	case JSON:
+		writeJSONString(buf, s)
+	case Logfmt:
+		buf.WriteString(logfmtString(s))
+	default:
+		if f.kind == stringField && s == nullValue {
+			buf.WriteString(strconv.Quote(s))
+			return
+		}
+		buf.WriteString(quoteValue(s))
+	}
+}
+
+// fieldSeverity returns the severity of an entry
+// with the given fields, as severity does for keyvals.
+func fieldSeverity(fields []Field) string {
+	sev := severityInfo
+	for _, f := range fields {
+		switch f.Key {
+		case KeyLevel:
+			if l, err := ParseLevel(f.s); err == nil && f.kind == stringField {
+				return l.String()
+			}
+		case KeyError:
+			sev = severityError
+		case "warning":
+			if sev == severityInfo {
+				sev = severityWarning
+			}
+		}
+	}
+	return sev
+}
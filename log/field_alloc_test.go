@@ -0,0 +1,35 @@
+//go:build !race
+// +build !race
+
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+// The race detector allocates, so these tests
+// are left out of race-enabled builds.
+
+func TestWriteFieldsAllocs(t *testing.T) {
+	defer SetOutput(Output())
+	SetOutput(ioutil.Discard)
+
+	ctx := context.Background()
+	write := func() {
+		WriteFields(ctx, String("s", "x"), Int("n", 1000), Bool("ok", true))
+	}
+	direct := testing.AllocsPerRun(100, write)
+	// The one allocation is the text of the entry's time,
+	// which every entry pays for.
+	if direct > 1 {
+		t.Errorf("WriteFields allocs = %v, want at most 1", direct)
+	}
+
+	remove := AddFilter(func([]interface{}) bool { return false })
+	defer remove()
+	if converted := testing.AllocsPerRun(100, write); direct >= converted {
+		t.Errorf("WriteFields allocs = %v, want fewer than %v through printkv", direct, converted)
+	}
+}
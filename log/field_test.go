@@ -0,0 +1,86 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chain/log/internal/capture"
+)
+
+func TestWriteFields(t *testing.T) {
+	out := captureOutput(t)
+
+	ctx := context.Background()
+	WriteFields(ctx,
+		String("s", "a b"),
+		Int("n", -7),
+		Bool("ok", true),
+		Duration("d", 1500*time.Millisecond),
+	)
+	entries := out.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want 1", entries)
+	}
+	if w := (capture.Entry{"s": "a b", "n": "-7", "ok": "true", "d": "1.5s"}); !entries[0].Matches(w) {
+		t.Errorf("entry = %v, want %v", entries[0], w)
+	}
+	if at := entries[0][KeyCaller]; !strings.HasPrefix(at, "field_test.go:") {
+		t.Errorf("at = %q, want caller in this file", at)
+	}
+
+	out.Reset()
+	WriteFields(ctx, String(KeyLevel, "debug"), Int("n", 1))
+	if got := out.Entries(); len(got) != 0 {
+		t.Errorf("entries = %v, want debug entry discarded", got)
+	}
+
+	errors0 := ReadState().Entries[severityError]
+	WriteFields(ctx, Err(errors.New("boom")))
+	if n := ReadState().Entries[severityError] - errors0; n != 1 {
+		t.Errorf("counted %d errors, want 1", n)
+	}
+}
+
+func TestWriteFieldsDirect(t *testing.T) {
+	out := captureOutput(t)
+	SetTimeFormat("static", time.UTC) // no time fields, so entries compare equal
+	defer SetTimeFormat(rfc3339NanoFixed, time.UTC)
+	defer SetFormat(KV)
+
+	ctx := context.Background()
+	write := func() {
+		WriteFields(ctx,
+			String("s", "a b"),
+			String("null", "null"),
+			String(KeyLevel, "warning"),
+			Int("n", -7000),
+			Bool("ok", false),
+			Duration("d", 1500*time.Microsecond),
+		)
+	}
+	for _, f := range []Format{KV, Logfmt, JSON} {
+		SetFormat(f)
+		out.Reset()
+		write() // direct
+		direct := out.String()
+
+		remove := AddFilter(func([]interface{}) bool { return false })
+		out.Reset()
+		write() // through printkv
+		remove()
+		if out.String() != direct {
+			t.Errorf("%v: direct entry = %q, want %q", f, direct, out.String())
+		}
+	}
+}
+
+func BenchmarkWriteFieldsDiscarded(b *testing.B) {
+	ctx := context.Background()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		WriteFields(ctx, String(KeyLevel, "debug"), Int("i", int64(i)), String("s", "x"))
+	}
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// A Filter reports whether to discard an entry
+// with the given fields. It must not log,
+// and must not retain or modify keyvals.
+type Filter func(keyvals []interface{}) bool
+
+// filters holds the filters registered with AddFilter,
+// as a []*Filter. Writers hold filtersMu.
+var (
+	filters   atomic.Value
+	filtersMu sync.Mutex
+)
+
+func init() {
+	filters.Store([]*Filter(nil))
+}
+
+// AddFilter registers f to be applied to subsequent entries,
+// from the package-level functions and from every Logger.
+// An entry is discarded if any registered filter returns true
+// for its fields, excluding prefix fields; such entries
+// are counted in expvar "log_dropped".
+// Calling the returned function removes f.
+func AddFilter(f Filter) (remove func()) {
+	p := &f
+	filtersMu.Lock()
+	old := filters.Load().([]*Filter)
+	filters.Store(append(old[:len(old):len(old)], p))
+	filtersMu.Unlock()
+	return func() {
+		filtersMu.Lock()
+		defer filtersMu.Unlock()
+		var fs []*Filter
+		for _, q := range filters.Load().([]*Filter) {
+			if q != p {
+				fs = append(fs, q)
+			}
+		}
+		filters.Store(fs)
+	}
+}
+
+// Match returns a Filter that matches entries having,
+// for each key in keyval, a field with that key and
+// the following value. Values are compared as strings,
+// formatted with fmt.Sprint, so Match("path", "/health")
+// matches an entry with field "path" set to "/health".
+// Odd-length keyval is an error and causes a panic.
+func Match(keyval ...interface{}) Filter {
+	if len(keyval)%2 != 0 {
+		panic("log: odd number of Match params")
+	}
+	want := make([]string, len(keyval))
+	for i, v := range keyval {
+		want[i] = fmt.Sprint(v)
+	}
+	return func(keyvals []interface{}) bool {
+	Next:
+		for i := 0; i < len(want); i += 2 {
+			for j := 0; j+1 < len(keyvals); j += 2 {
+				if fmt.Sprint(keyvals[j]) == want[i] && fmt.Sprint(keyvals[j+1]) == want[i+1] {
+					continue Next
+				}
+			}
+			return false
+		}
+		return true
+	}
+}
+
+// filtered reports whether a registered filter
+// discards an entry with the given fields.
+func filtered(keyvals []interface{}) bool {
+	for _, f := range filters.Load().([]*Filter) {
+		if (*f)(keyvals) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	f := Match("path", "/health", "status", 200)
+	cases := []struct {
+		keyvals []interface{}
+		want    bool
+	}{
+		{[]interface{}{"path", "/health", "status", 200}, true},
+		{[]interface{}{"status", "200", "x", 1, "path", "/health"}, true},
+		{[]interface{}{"path", "/health", "status", 500}, false},
+		{[]interface{}{"path", "/health"}, false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := f(c.keyvals); got != c.want {
+			t.Errorf("Match(%v) = %t want %t", c.keyvals, got, c.want)
+		}
+	}
+}
+
+func TestAddFilter(t *testing.T) {
+	out := captureOutput(t)
+
+	ctx := context.Background()
+	dropped0 := droppedCount(dropFilter, severityInfo)
+	remove := AddFilter(Match("path", "/health"))
+	Printkv(ctx, "path", "/health")
+	Printkv(ctx, "path", "/info")
+	New(ioutil.Discard).Printkv(ctx, "path", "/health") // not counted
+	remove()
+	Printkv(ctx, "path", "/health", "n", 2)
+
+	entries := out.Entries()
+	if len(entries) != 2 || entries[0]["path"] != "/info" || entries[1]["n"] != "2" {
+		t.Errorf("entries = %v, want the /info entry and the one after remove", entries)
+	}
+	if got := droppedCount(dropFilter, severityInfo) - dropped0; got != 1 {
+		t.Errorf("dropped = %d want 1", got)
+	}
+}
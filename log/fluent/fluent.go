@@ -0,0 +1,169 @@
+// Package fluent sends log entries to Fluentd or Fluent Bit
+// using the forward protocol, so they can enter
+// a Fluent pipeline without being parsed from a file.
+// See https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1.
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"chain/log/internal/batch"
+	"chain/sync/retry"
+)
+
+const (
+	// DialTimeout limits how long the writer
+	// waits to connect to the server.
+	DialTimeout = time.Second
+
+	// WriteTimeout limits how long the writer waits
+	// to send a batch and, if acknowledgements are
+	// required, to receive its acknowledgement.
+	WriteTimeout = 10 * time.Second
+)
+
+// Config configures a writer that sends to a forward input.
+type Config struct {
+	Addr string // host:port of the server
+	Tag  string // Fluent tag of every entry
+
+	// RequireAck makes the writer wait for the server
+	// to acknowledge each batch, and resend it if the
+	// acknowledgement doesn't arrive, so that batches
+	// are delivered at least once.
+	RequireAck bool
+}
+
+type writer struct {
+	w *batch.Writer
+	f *forwarder
+}
+
+type forwarder struct {
+	c    Config
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// New creates a new writer that sends each written entry
+// as one event. An entry formatted as a JSON object
+// (see chain/log.JSON) becomes the event's record;
+// any other entry becomes the "log" field
+// of the record, as from Fluent's own tail input.
+//
+// Writes never block: events are queued and sent
+// in batches, as forward mode messages,
+// from a background goroutine, and dropped
+// if too many are waiting.
+func New(c Config) io.Writer {
+	f := &forwarder{c: c}
+	return &writer{w: batch.New(f.send), f: f}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	err := w.w.Add(event(time.Now(), bytes.TrimRight(p, "\n")))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends the queued events, waiting at most batch.FlushTimeout.
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close sends the queued events, then closes the connection.
+func (w *writer) Close() error {
+	err := w.w.Close()
+	if w.f.conn != nil {
+		w.f.conn.Close()
+		w.f.conn = nil
+	}
+	return err
+}
+
+// event returns the encoding of entry as an event at time t.
+func event(t time.Time, entry []byte) []byte {
+	record := map[string]interface{}{"log": string(entry)}
+	if len(entry) > 0 && entry[0] == '{' {
+		d := json.NewDecoder(bytes.NewReader(entry))
+		d.UseNumber()
+		var v map[string]interface{}
+		if d.Decode(&v) == nil {
+			record = v
+		}
+	}
+	ev := appendArrayHeader(nil, 2)
+	ev = appendEventTime(ev, t)
+	return appendMap(ev, record)
+}
+
+// send sends one forward mode message
+// holding the events, retrying on error.
+func (f *forwarder) send(events [][]byte) {
+	var b []byte
+	b = appendArrayHeader(b, 3)
+	b = appendString(b, f.c.Tag)
+	b = appendArrayHeader(b, len(events))
+	for _, ev := range events {
+		b = append(b, ev...)
+	}
+	var chunk string
+	if f.c.RequireAck {
+		id := make([]byte, 16)
+		rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+		b = appendMapHeader(b, 2)
+		b = appendString(b, "chunk")
+		b = appendString(b, chunk)
+	} else {
+		b = appendMapHeader(b, 1)
+	}
+	b = appendString(b, "size")
+	b = appendInt(b, int64(len(events)))
+
+	p := retry.Policy{Attempts: 3, Initial: 100 * time.Millisecond, Logger: batch.Stderr}
+	retry.Do(context.Background(), "fluent-forward", p, func(context.Context) error {
+		err := f.write(b, chunk)
+		if err != nil && f.conn != nil {
+			f.conn.Close()
+			f.conn = nil
+		}
+		return err
+	})
+}
+
+// write writes msg and, if chunk is not empty,
+// waits for its acknowledgement.
+func (f *forwarder) write(msg []byte, chunk string) error {
+	if f.conn == nil {
+		conn, err := net.DialTimeout("tcp", f.c.Addr, DialTimeout)
+		if err != nil {
+			return err
+		}
+		f.conn, f.r = conn, bufio.NewReader(conn)
+	}
+	f.conn.SetDeadline(time.Now().Add(WriteTimeout))
+	_, err := f.conn.Write(msg)
+	if err != nil || chunk == "" {
+		return err
+	}
+	ack, err := readAck(f.r)
+	if err != nil {
+		return err
+	}
+	if ack != chunk {
+		return fmt.Errorf("fluent: got ack %q want %q", ack, chunk)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package fluent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendValue(t *testing.T) {
+	cases := []struct {
+		v    interface{}
+		want []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{"ab", []byte{0xa2, 'a', 'b'}},
+		{[]interface{}{false}, []byte{0x91, 0xc2}},
+		{map[string]interface{}{"b": nil, "a": "x"}, []byte{0x82, 0xa1, 'a', 0xa1, 'x', 0xa1, 'b', 0xc0}},
+	}
+	for _, c := range cases {
+		if got := appendValue(nil, c.v); !bytes.Equal(got, c.want) {
+			t.Errorf("appendValue(%#v) = %x want %x", c.v, got, c.want)
+		}
+	}
+}
+
+// decode decodes the MessagePack value at the start of r,
+// for the types the writer produces.
+func decode(r *bufio.Reader) (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	next := func(n int) []byte {
+		b := make([]byte, n)
+		io.ReadFull(r, b)
+		return b
+	}
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x90:
+		return decodeArray(r, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return decodeMap(r, int(c&0x0f))
+	case c&0xe0 == 0xa0:
+		return string(next(int(c & 0x1f))), nil
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, nil
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(next(8))), nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(next(8))), nil
+	case 0xd7:
+		b := next(9)
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:5])), int64(binary.BigEndian.Uint32(b[5:]))), nil
+	case 0xd9:
+		return string(next(int(next(1)[0]))), nil
+	case 0xda:
+		return string(next(int(binary.BigEndian.Uint16(next(2))))), nil
+	case 0xdc:
+		return decodeArray(r, int(binary.BigEndian.Uint16(next(2))))
+	}
+	return nil, errUnexpected
+}
+
+func decodeArray(r *bufio.Reader, n int) (interface{}, error) {
+	a := make([]interface{}, n)
+	for i := range a {
+		var err error
+		a[i], err = decode(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func decodeMap(r *bufio.Reader, n int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for i := 0; i < n; i++ {
+		k, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		m[k.(string)], err = decode(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func TestSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan interface{}, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			msg, err := decode(bufio.NewReader(conn))
+			if err != nil {
+				conn.Close()
+				return
+			}
+			got <- msg
+			if i == 1 {
+				// Acknowledge only the second attempt.
+				chunk := msg.([]interface{})[2].(map[string]interface{})["chunk"].(string)
+				b := appendMapHeader(nil, 1)
+				b = appendString(b, "ack")
+				b = appendString(b, chunk)
+				conn.Write(b)
+			}
+			conn.Close()
+		}
+	}()
+
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6, time.UTC)
+	f := &forwarder{c: Config{Addr: ln.Addr().String(), Tag: "chain.cored", RequireAck: true}}
+	f.send([][]byte{
+		event(ts, []byte(`{"message":"hi","n":7}`)),
+		event(ts, []byte("message=bye")),
+	})
+	if f.conn == nil {
+		t.Error("send failed, want success after retry")
+	}
+
+	for i := 0; i < 2; i++ {
+		msg := (<-got).([]interface{})
+		if msg[0] != "chain.cored" {
+			t.Errorf("tag = %v want chain.cored", msg[0])
+		}
+		evs := msg[1].([]interface{})
+		if len(evs) != 2 {
+			t.Fatalf("got %d events want 2", len(evs))
+		}
+		ev0 := evs[0].([]interface{})
+		if tm := ev0[0].(time.Time); !tm.Equal(ts) {
+			t.Errorf("time = %v want %v", tm, ts)
+		}
+		if want := map[string]interface{}{"message": "hi", "n": int64(7)}; !reflect.DeepEqual(ev0[1], want) {
+			t.Errorf("record 0 = %v want %v", ev0[1], want)
+		}
+		rec1 := evs[1].([]interface{})[1]
+		if want := map[string]interface{}{"log": "message=bye"}; !reflect.DeepEqual(rec1, want) {
+			t.Errorf("record 1 = %v want %v", rec1, want)
+		}
+		if opt := msg[2].(map[string]interface{}); opt["size"] != int64(2) {
+			t.Errorf("size = %v want 2", opt["size"])
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package fluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// The subset of MessagePack used by the forward protocol:
+// see https://github.com/msgpack/msgpack/blob/master/spec.md.
+
+func appendUint(b []byte, prefix byte, v uint64, n int) []byte {
+	b = append(b, prefix)
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint(b, 0xdc, uint64(n), 2)
+	}
+	return appendUint(b, 0xdd, uint64(n), 4)
+}
+
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint(b, 0xde, uint64(n), 2)
+	}
+	return appendUint(b, 0xdf, uint64(n), 4)
+}
+
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = appendUint(b, 0xd9, uint64(n), 1)
+	case n <= math.MaxUint16:
+		b = appendUint(b, 0xda, uint64(n), 2)
+	default:
+		b = appendUint(b, 0xdb, uint64(n), 4)
+	}
+	return append(b, s...)
+}
+
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v < 128:
+		return append(b, byte(v))
+	case v < 0 && v >= -32:
+		return append(b, byte(v))
+	}
+	return appendUint(b, 0xd3, uint64(v), 8)
+}
+
+func appendFloat(b []byte, v float64) []byte {
+	return appendUint(b, 0xcb, math.Float64bits(v), 8)
+}
+
+// appendEventTime appends t as the forward protocol's
+// EventTime extension type, with nanoseconds.
+func appendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	var v [8]byte
+	binary.BigEndian.PutUint32(v[:4], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(v[4:], uint32(t.Nanosecond()))
+	return append(b, v[:]...)
+}
+
+// appendValue appends v, a value decoded by
+// encoding/json with UseNumber.
+func appendValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendInt(b, i)
+		}
+		f, _ := v.Float64()
+		return appendFloat(b, f)
+	case string:
+		return appendString(b, v)
+	case []interface{}:
+		b = appendArrayHeader(b, len(v))
+		for _, e := range v {
+			b = appendValue(b, e)
+		}
+		return b
+	case map[string]interface{}:
+		return appendMap(b, v)
+	}
+	return appendString(b, fmt.Sprint(v))
+}
+
+// appendMap appends m with its keys in sorted order.
+func appendMap(b []byte, m map[string]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	b = appendMapHeader(b, len(m))
+	for _, k := range keys {
+		b = appendString(b, k)
+		b = appendValue(b, m[k])
+	}
+	return b
+}
+
+var errUnexpected = errors.New("fluent: unexpected response")
+
+// readAck reads a server's response,
+// a map with string keys and values,
+// and returns its "ack" value.
+func readAck(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case c&0xf0 == 0x80:
+		n = int(c & 0x0f)
+	case c == 0xde:
+		n, err = readLen(r, 2)
+	default:
+		return "", errUnexpected
+	}
+	var ack string
+	for i := 0; i < n && err == nil; i++ {
+		var k, v string
+		k, err = readString(r)
+		if err == nil {
+			v, err = readString(r)
+		}
+		if k == "ack" {
+			ack = v
+		}
+	}
+	return ack, err
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == 0xd9:
+		n, err = readLen(r, 1)
+	case c == 0xda:
+		n, err = readLen(r, 2)
+	default:
+		return "", errUnexpected
+	}
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return string(b), err
+}
+
+func readLen(r io.Reader, size int) (int, error) {
+	b := make([]byte, size)
+	_, err := io.ReadFull(r, b)
+	n := 0
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+	return n, err
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// A Format is an encoding of log entries.
+type Format int32
+
+// Formats.
+const (
+	KV      Format = iota // Splunk-style K=V pairs, the default
+	Console               // for people reading a terminal
+	JSON                  // one JSON object per line
+	Logfmt                // K=V pairs, quoted for strict logfmt parsers
+	GELF                  // Graylog Extended Log Format; see package chain/log/gelf
+	CEF                   // ArcSight Common Event Format
+	LEEF                  // IBM QRadar Log Event Extended Format 1.0
+	OTLP                  // OpenTelemetry LogRecords in JSON; see package chain/log/otlp
+	Dev                   // Console, with relative times and aligned fields, for development
+	Proto                 // length-delimited protocol buffers; see entry.proto
+	RFC5424               // syslog messages with structured data
+	Journal               // systemd journal native protocol; see package chain/log/journald
+)
+
+// format holds the Format set by SetFormat.
+var format int32
+
+var formatNames = map[Format]string{
+	KV:      "kv",
+	Console: "console",
+	JSON:    "json",
+	Logfmt:  "logfmt",
+	GELF:    "gelf",
+	CEF:     "cef",
+	LEEF:    "leef",
+	OTLP:    "otlp",
+	Dev:     "dev",
+	Proto:   "proto",
+	RFC5424: "rfc5424",
+	Journal: "journal",
+}
+
+func (f Format) String() string {
+	if s, ok := formatNames[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("Format(%d)", int32(f))
+}
+
+// ParseFormat returns the Format with the given name.
+func ParseFormat(s string) (Format, error) {
+	for f, name := range formatNames {
+		if s == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log format %q", s)
+}
+
+// SetFormat sets the encoding of subsequent entries.
+// The default is KV.
+func SetFormat(f Format) {
+	atomic.StoreInt32(&format, int32(f))
+}
+
+func currentFormat() Format {
+	return Format(atomic.LoadInt32(&format))
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var hostname = func() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return h
+}()
+
+// syslog severities, as used by GELF's level field.
+var gelfLevels = map[string]int{
+	severityDebug:   7,
+	severityError:   3,
+	severityWarning: 4,
+	severityInfo:    6,
+}
+
+// formatGELF writes an entry as a GELF 1.1 message
+// on one line. The message's short_message is the entry's
+// summary; full_message is the stack trace, if any.
+// Every field, including the prefix fields pre
+// and KeyCaller, becomes an additional field,
+// with its key prefixed by "_".
+func formatGELF(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	buf.WriteString(`{"version":"1.1",`)
+	writeJSONMember(buf, "host", hostname)
+	writeJSONMember(buf, "short_message", summary(fields))
+	if stack != nil {
+		var b bytes.Buffer
+		writeRawStack(&b, stack)
+		if b.Len() > 0 {
+			writeJSONMember(buf, "full_message", b.String())
+		}
+	}
+	fmt.Fprintf(buf, `"timestamp":%.3f,"level":%d,`, float64(t.UnixNano())/1e9, gelfLevels[sev])
+	for i := 0; i < len(pre); i += 2 {
+		writeGELFField(buf, pre[i], pre[i+1])
+	}
+	writeGELFField(buf, KeyCaller, loc)
+	for i := 0; i < len(fields); i += 2 {
+		writeGELFField(buf, fields[i], fields[i+1])
+	}
+	buf.Truncate(buf.Len() - 1) // trailing comma
+	buf.WriteString("}\n")
+}
+
+// writeGELFField writes an additional field.
+// GELF allows only strings and numbers as values,
+// and names matching ^[\w.-]+$, other than "id".
+func writeGELFField(buf *bytes.Buffer, k, v interface{}) {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, formatKey(k))
+	if name == "id" {
+		name = "id_"
+	}
+	writeJSONString(buf, "_"+name)
+	buf.WriteByte(':')
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		fmt.Fprint(buf, v)
+	case float32:
+		writeGELFFloat(buf, float64(v), 32)
+	case float64:
+		writeGELFFloat(buf, v, 64)
+	default:
+		writeJSONString(buf, valueString(v))
+	}
+	buf.WriteByte(',')
+}
+
+func writeGELFFloat(buf *bytes.Buffer, f float64, bits int) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		writeJSONString(buf, fmt.Sprint(f)) // not valid JSON numbers
+		return
+	}
+	buf.WriteString(strconv.FormatFloat(f, 'g', -1, bits))
+}
@@ -0,0 +1,132 @@
+// Package gelf sends log entries in the GELF format
+// (see chain/log.GELF) to a Graylog server.
+package gelf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// DialTimeout limits how long a write will block
+	// while dialing the Graylog server.
+	DialTimeout = 50 * time.Millisecond
+
+	// WriteTimeout limits how long a write will block
+	// sending data on a TCP connection.
+	// As in package chain/log/splunk, it is deliberately
+	// small, so a slow server drops entries rather
+	// than blocking the process.
+	WriteTimeout = 100 * time.Microsecond
+
+	// ChunkSize is the largest UDP datagram sent.
+	// Larger messages are split into GELF chunks.
+	// It fits in a typical Internet MTU.
+	ChunkSize = 1420
+
+	maxChunks   = 128 // the most GELF allows per message
+	chunkHeader = 12  // magic, message ID, sequence number, count
+)
+
+// ErrTooLarge is returned by a UDP writer
+// for a message that needs more than 128 chunks.
+var ErrTooLarge = errors.New("gelf message too large")
+
+type writer struct {
+	network string
+	addr    string
+	conn    net.Conn
+}
+
+// New creates a new writer that sends each written
+// GELF message, as one entry formatted by chain/log,
+// to the given address. The network must be "udp" or "tcp".
+//
+// It connects on the first call to Write,
+// and reconnects after a TCP write error.
+// Over TCP, messages are delimited by a null byte,
+// and writes have a timeout of WriteTimeout.
+// Over UDP, messages longer than ChunkSize are chunked.
+func New(network, addr string) io.Writer {
+	return &writer{network: network, addr: addr}
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	if w.conn == nil {
+		w.conn, err = net.DialTimeout(w.network, w.addr, DialTimeout)
+		if err != nil {
+			return 0, err
+		}
+	}
+	msg := bytes.TrimRight(p, "\n")
+	if w.network == "udp" {
+		err = w.writeUDP(msg)
+	} else {
+		w.conn.SetDeadline(time.Now().Add(WriteTimeout))
+		_, err = w.conn.Write(append(msg[:len(msg):len(msg)], 0))
+		if err != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection, if any.
+// A later Write reconnects.
+func (w *writer) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *writer) writeUDP(msg []byte) error {
+	if len(msg) <= ChunkSize {
+		_, err := w.conn.Write(msg)
+		return err
+	}
+	chunks := chunk(msg)
+	if chunks == nil {
+		return ErrTooLarge
+	}
+	for _, c := range chunks {
+		_, err := w.conn.Write(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunk splits msg into GELF chunks of at most ChunkSize bytes,
+// or returns nil if it would take more than maxChunks.
+func chunk(msg []byte) [][]byte {
+	const size = ChunkSize - chunkHeader
+	n := (len(msg) + size - 1) / size
+	if n > maxChunks {
+		return nil
+	}
+	id := make([]byte, 8)
+	rand.Read(id)
+	var chunks [][]byte
+	for i := 0; i < n; i++ {
+		end := (i + 1) * size
+		if end > len(msg) {
+			end = len(msg)
+		}
+		c := append([]byte{0x1e, 0x0f}, id...)
+		c = append(c, byte(i), byte(n))
+		chunks = append(chunks, append(c, msg[i*size:end]...))
+	}
+	return chunks
+}
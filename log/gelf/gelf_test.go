@@ -0,0 +1,71 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	msg := bytes.Repeat([]byte("x"), 3*(ChunkSize-chunkHeader)+1)
+	chunks := chunk(msg)
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks want 4", len(chunks))
+	}
+	var got []byte
+	for i, c := range chunks {
+		if len(c) > ChunkSize {
+			t.Errorf("chunk %d has %d bytes, more than %d", i, len(c), ChunkSize)
+		}
+		if c[0] != 0x1e || c[1] != 0x0f || c[10] != byte(i) || c[11] != 4 {
+			t.Errorf("chunk %d header = %x", i, c[:chunkHeader])
+		}
+		if !bytes.Equal(c[2:10], chunks[0][2:10]) {
+			t.Errorf("chunk %d message ID = %x want %x", i, c[2:10], chunks[0][2:10])
+		}
+		got = append(got, c[chunkHeader:]...)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Error("reassembled message differs")
+	}
+
+	if chunk(make([]byte, maxChunks*ChunkSize)) != nil {
+		t.Error("chunk of oversize message is non-nil")
+	}
+}
+
+func TestWriteTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			s, _ := r.ReadString(0)
+			got <- s
+		}
+	}()
+
+	w := New("tcp", ln.Addr().String())
+	for _, msg := range []string{"{\"a\":1}\n", "{\"b\":2}\n"} {
+		// The short WriteTimeout is for the network buffers;
+		// loopback writes of a few bytes always fit.
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, want := range []string{"{\"a\":1}\x00", "{\"b\":2}\x00"} {
+		if s := <-got; s != want {
+			t.Errorf("got %q want %q", s, want)
+		}
+	}
+}
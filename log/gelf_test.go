@@ -0,0 +1,56 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatGELF(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	cases := []struct {
+		sev    string
+		fields []interface{}
+		want   map[string]interface{}
+	}{{
+		severityInfo,
+		[]interface{}{KeyMessage, "hi", "n", 7, "id", "x", "a b", true, "f", math.NaN()},
+		map[string]interface{}{
+			"short_message": "hi", "level": 6.0,
+			"_message": "hi", "_n": 7.0, "_id_": "x", "_a-b": "true", "_f": "NaN",
+		},
+	}, {
+		severityError,
+		[]interface{}{"height", 2, KeyError, "boom"},
+		map[string]interface{}{"short_message": "boom", "level": 3.0, "_height": 2.0, "_error": "boom"},
+	}, {
+		severityInfo,
+		[]interface{}{"height", 2},
+		map[string]interface{}{"short_message": "height=2", "level": 6.0, "_height": 2.0},
+	}}
+	for i, c := range cases {
+		buf := new(bytes.Buffer)
+		formatGELF(buf, []interface{}{"reqid", "r1"}, c.sev, ts, "a.go:1", c.fields, []byte("trace"))
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("%d: entry %q: %v", i, buf, err)
+		}
+		want := map[string]interface{}{
+			"version":      "1.1",
+			"host":         hostname,
+			"timestamp":    1488373445.006,
+			"full_message": "trace\n",
+			"_reqid":       "r1",
+			"_at":          "a.go:1",
+		}
+		for k, v := range c.want {
+			want[k] = v
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%d: formatGELF = %v want %v", i, got, want)
+		}
+	}
+}
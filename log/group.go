@@ -0,0 +1,29 @@
+package log
+
+import "fmt"
+
+// Group returns the alternating keys and values keyval
+// with each key prefixed by name and a dot, for Printkv,
+// With, and the like, so that the fields of one subsystem
+// stay together and don't collide with another's:
+//
+//	log.Printkv(ctx, log.Group("db", "query", q, "ms", ms)...)
+//
+// writes db.query=... db.ms=... fields. Groups can be nested.
+// A grouped KeyError, KeyMessage, or KeyLevel field is an
+// ordinary field, so it doesn't make the entry an error,
+// or set its message or level.
+func Group(name string, keyval ...interface{}) []interface{} {
+	if len(keyval)%2 != 0 {
+		keyval = append(keyval, "", keyLogError, "odd number of log params")
+	}
+	a := make([]interface{}, 0, len(keyval))
+	for i := 0; i < len(keyval); i += 2 {
+		k := keyval[i]
+		if k != keyLogError {
+			k = name + "." + fmt.Sprint(k)
+		}
+		a = append(a, k, keyval[i+1])
+	}
+	return a
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestGroup(t *testing.T) {
+	got := Group("db", "query", "q", "ms", 3)
+	want := []interface{}{"db.query", "q", "db.ms", 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Group = %v want %v", got, want)
+	}
+
+	got = Group("a", Group("b", "k", 1)...)
+	want = []interface{}{"a.b.k", 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("nested Group = %v want %v", got, want)
+	}
+
+	got = Group("a", "k")
+	want = []interface{}{"a.k", "", keyLogError, "odd number of log params"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("odd Group = %v want %v", got, want)
+	}
+}
+
+func TestPrintkvGroup(t *testing.T) {
+	out := captureOutput(t)
+
+	Printkv(context.Background(), append(Group("db", "query", "select", KeyError, "x"), "n", 1)...)
+	entries := out.Entries()
+	want := capture.Entry{KeyLevel: "info", "db.query": "select", "db.error": "x", "n": "1"}
+	if len(entries) != 1 || !entries[0].Matches(want) {
+		t.Errorf("entries = %v, want %v", entries, want)
+	}
+}
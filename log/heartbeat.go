@@ -0,0 +1,24 @@
+package log
+
+import (
+	"context"
+	"time"
+)
+
+// Heartbeat writes an entry once per interval until ctx is done.
+// Each entry has a "heartbeat" field holding a sequence number,
+// starting at 1, so that log monitoring can detect both
+// a dead process and a broken shipping path by the absence
+// of heartbeats, and lost entries by a gap in the sequence.
+func Heartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for seq := 1; ; seq++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			Printkv(ctx, "heartbeat", seq)
+		}
+	}
+}
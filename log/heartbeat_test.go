@@ -0,0 +1,31 @@
+package log
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	out := captureOutput(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Heartbeat(ctx, time.Millisecond)
+		close(done)
+	}()
+	for len(out.Entries()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	entries := out.Entries()
+	for i := 0; i < 2; i++ {
+		if got, want := entries[i]["heartbeat"], strconv.Itoa(i+1); got != want {
+			t.Errorf("entry %d = %v, want heartbeat=%s", i, entries[i], want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// A Hook is applied to an entry's fields before the entry
+// is encoded, to add fields, rewrite values, or discard
+// the entry according to site-specific policy.
+// It returns the fields to log, and false to discard the entry.
+// To change the fields, it must return a new slice
+// rather than modify keyvals in place.
+// It must not log.
+type Hook func(ctx context.Context, keyvals []interface{}) ([]interface{}, bool)
+
+// hooks holds the hooks registered with AddHook,
+// as a []*Hook. Writers hold hooksMu.
+var (
+	hooks   atomic.Value
+	hooksMu sync.Mutex
+)
+
+func init() {
+	hooks.Store([]*Hook(nil))
+}
+
+// AddHook registers h to be applied to subsequent entries,
+// from the package-level functions and from every Logger,
+// after any registered earlier. Hooks see an entry before
+// its severity is inferred, so a hook can change it,
+// and before any Filter. Entries that a hook discards
+// are counted in expvar "log_dropped".
+// Calling the returned function removes h.
+func AddHook(h Hook) (remove func()) {
+	p := &h
+	hooksMu.Lock()
+	old := hooks.Load().([]*Hook)
+	hooks.Store(append(old[:len(old):len(old)], p))
+	hooksMu.Unlock()
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		var hs []*Hook
+		for _, q := range hooks.Load().([]*Hook) {
+			if q != p {
+				hs = append(hs, q)
+			}
+		}
+		hooks.Store(hs)
+	}
+}
+
+// runHooks applies the registered hooks to keyvals
+// and reports whether to keep the entry.
+func runHooks(ctx context.Context, keyvals []interface{}) ([]interface{}, bool) {
+	for _, h := range hooks.Load().([]*Hook) {
+		var ok bool
+		keyvals, ok = (*h)(ctx, keyvals)
+		if !ok {
+			return nil, false
+		}
+		if len(keyvals)%2 != 0 {
+			keyvals = append(keyvals, "", keyLogError, "odd number of log params from hook")
+		}
+	}
+	return keyvals, true
+}
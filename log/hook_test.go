@@ -0,0 +1,65 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestAddHook(t *testing.T) {
+	out := captureOutput(t)
+
+	remove1 := AddHook(func(ctx context.Context, keyvals []interface{}) ([]interface{}, bool) {
+		for i := 0; i < len(keyvals); i += 2 {
+			if keyvals[i] == "secret" {
+				return nil, false
+			}
+		}
+		return append(keyvals[:len(keyvals):len(keyvals)], "site", "east"), true
+	})
+	remove2 := AddHook(func(ctx context.Context, keyvals []interface{}) ([]interface{}, bool) {
+		out := make([]interface{}, len(keyvals))
+		copy(out, keyvals)
+		for i := 0; i < len(out); i += 2 {
+			if out[i] == "password" {
+				out[i+1] = "REDACTED"
+			}
+		}
+		return out, true
+	})
+
+	ctx := context.Background()
+	dropped0 := droppedCount(dropHook, severityInfo)
+	Printkv(ctx, "password", "hunter2")
+	Printkv(ctx, "secret", "x")
+	New(out).Printkv(ctx, "n", 1)
+	remove1()
+	remove2()
+	Printkv(ctx, "n", 2)
+
+	entries := out.Entries()
+	want := []capture.Entry{
+		{"password": "REDACTED", "site": "east"},
+		{"n": "1", "site": "east"},
+		{"n": "2"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %v, want %d", entries, len(want))
+	}
+	for i, w := range want {
+		if !entries[i].Matches(w) {
+			t.Errorf("entry %d = %v, want %v", i, entries[i], w)
+		}
+	}
+	if _, ok := entries[2]["site"]; ok {
+		t.Errorf("entry after remove = %v, want no site", entries[2])
+	}
+	if got := out.String(); strings.Contains(got, "hunter2") || strings.Contains(got, "secret") {
+		t.Errorf("log = %q, want the secret entry discarded and the password redacted", got)
+	}
+	if d := droppedCount(dropHook, severityInfo) - dropped0; d != 1 {
+		t.Errorf("dropped = %d want 1", d)
+	}
+}
@@ -0,0 +1,217 @@
+// Package batch queues log entries written to a network sink
+// and sends them in batches from a background goroutine,
+// so writes never block on the network.
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"chain/log"
+	"chain/sync/retry"
+)
+
+// ErrQueueFull is returned by Write when the queue is full.
+// It is log.ErrQueueFull, so that the log package counts
+// the entry as dropped for a full queue.
+var ErrQueueFull = log.ErrQueueFull
+
+// ErrClosed is returned by Write after Close.
+var ErrClosed = errors.New("log batch writer closed")
+
+// ErrFlushTimeout is returned by Flush if the queued
+// entries aren't sent within FlushTimeout.
+var ErrFlushTimeout = errors.New("log batch flush timed out")
+
+// Limits on a Writer.
+const (
+	QueueSize     = 10000           // entries waiting to be sent
+	BatchSize     = 512             // most entries sent at once
+	FlushInterval = time.Second     // longest an entry waits
+	FlushTimeout  = 5 * time.Second // longest Flush waits
+)
+
+// A Writer is an io.Writer that queues each write
+// as one entry, without its trailing newline,
+// and calls send with the queued entries
+// once BatchSize are queued or FlushInterval passes.
+type Writer struct {
+	queue chan []byte
+	send  func([][]byte)
+	flush chan chan struct{} // closed once the queue is sent
+
+	closeOnce sync.Once
+	closing   chan struct{} // closed by Close
+	done      chan struct{} // closed when run returns
+}
+
+// New returns a Writer that sends entries with send,
+// called from a single goroutine.
+func New(send func(batch [][]byte)) *Writer {
+	w := &Writer{
+		queue:   make(chan []byte, QueueSize),
+		send:    send,
+		flush:   make(chan chan struct{}),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p without blocking.
+func (w *Writer) Write(p []byte) (int, error) {
+	err := w.Add(append([]byte(nil), bytes.TrimRight(p, "\n")...))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Add queues entry as is, without blocking.
+// Sinks that encode entries before queuing them use it.
+// The caller must not modify entry afterward.
+func (w *Writer) Add(entry []byte) error {
+	select {
+	case <-w.closing:
+		return ErrClosed
+	default:
+	}
+	select {
+	case w.queue <- entry:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Flush sends the entries queued so far, and waits
+// for them to be sent, for at most FlushTimeout.
+// Programs call it, by way of chain/log.Flush,
+// before exiting, so queued entries aren't lost.
+func (w *Writer) Flush() error {
+	done := make(chan struct{})
+	timeout := time.NewTimer(FlushTimeout)
+	defer timeout.Stop()
+	select {
+	case w.flush <- done:
+	case <-w.done:
+		return nil // closed, so nothing is queued
+	case <-timeout.C:
+		return ErrFlushTimeout
+	}
+	select {
+	case <-done:
+		return nil
+	case <-timeout.C:
+		return ErrFlushTimeout
+	}
+}
+
+// Close sends the queued entries, then stops the
+// background goroutine and returns once it has finished.
+// Writes after Close fail with ErrClosed.
+func (w *Writer) Close() error {
+	w.closeOnce.Do(func() { close(w.closing) })
+	<-w.done
+	return nil
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	var batch [][]byte
+	for {
+		select {
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+			if len(batch) < BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case done := <-w.flush:
+			w.drain(batch)
+			batch = nil
+			close(done)
+			continue
+		case <-w.closing:
+			w.drain(batch)
+			return
+		}
+		w.send(batch)
+		batch = nil
+	}
+}
+
+// drain sends batch and the entries left in the queue.
+func (w *Writer) drain(batch [][]byte) {
+	for {
+		select {
+		case entry := <-w.queue:
+			batch = append(batch, entry)
+			if len(batch) < BatchSize {
+				continue
+			}
+		default:
+			if len(batch) > 0 {
+				w.send(batch)
+			}
+			return
+		}
+		w.send(batch)
+		batch = nil
+	}
+}
+
+// Post sends body to url with the given header,
+// as the operation name, for logging,
+// retrying on network errors and on responses with status
+// 429 or 5xx. Entries about failed attempts go to stderr,
+// since the package-level log output may be the failing sink.
+func Post(name string, client *http.Client, url string, header http.Header, body []byte) error {
+	p := retry.Policy{Attempts: 3, Initial: 100 * time.Millisecond, Logger: Stderr}
+	return PostWith(name, p, client, url, header, body)
+}
+
+// PostWith is like Post, but retries as set by p.
+func PostWith(name string, p retry.Policy, client *http.Client, url string, header http.Header, body []byte) error {
+	return retry.Do(context.Background(), name, p, func(ctx context.Context) error {
+		req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+		switch {
+		case resp.StatusCode/100 == 2:
+			return nil
+		case resp.StatusCode == 429, resp.StatusCode/100 == 5:
+			return fmt.Errorf("%s: %s", url, resp.Status)
+		}
+		return retry.Permanent(fmt.Errorf("%s: %s", url, resp.Status))
+	})
+}
+
+// Stderr receives entries about failed sends.
+// Sinks use it in place of the package-level log output,
+// which may be the failing sink.
+var Stderr = log.New(os.Stderr)
@@ -0,0 +1,42 @@
+package batch
+
+import (
+	"context"
+	"expvar"
+	"testing"
+
+	"chain/log"
+)
+
+func dropped(cause string) int64 {
+	m, _ := expvar.Get("log_dropped").(*expvar.Map).Get(cause).(*expvar.Map)
+	if m == nil {
+		return 0
+	}
+	n, _ := m.Get("info").(*expvar.Int)
+	if n == nil {
+		return 0
+	}
+	return n.Value()
+}
+
+func TestQueueFullDrop(t *testing.T) {
+	release := make(chan struct{})
+	w := New(func([][]byte) { <-release })
+	defer w.Close()
+	defer close(release)
+	defer log.SetOutput(log.Output())
+	log.SetOutput(w)
+
+	queue0, write0 := dropped("queue"), dropped("write")
+	ctx := context.Background()
+	for i := 0; i < 2*QueueSize; i++ {
+		log.Printkv(ctx, "i", i)
+	}
+	if d := dropped("queue") - queue0; d < QueueSize-BatchSize {
+		t.Errorf("queue drops = %d, want at least %d", d, QueueSize-BatchSize)
+	}
+	if d := dropped("write") - write0; d != 0 {
+		t.Errorf("write drops = %d, want 0", d)
+	}
+}
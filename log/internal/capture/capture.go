@@ -0,0 +1,171 @@
+// Package capture records log entries written by tests
+// and parses their fields.
+// It is shared by package log's own tests and package logtest,
+// and so must not import package log.
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// An Entry holds the fields of one log entry, keyed by name.
+// If a key occurs more than once, the last value is kept.
+type Entry map[string]string
+
+// A Recorder is an io.Writer that keeps the log entries
+// written to it. It is safe for concurrent use.
+type Recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// Write appends p to the text held by r.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+// String returns the text written to r.
+func (r *Recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.String()
+}
+
+// Reset discards the text written to r.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf.Reset()
+}
+
+// Entries returns the entries written to r, in order.
+// Lines that don't begin with a field, such as
+// the lines of a stack trace, are left out.
+func (r *Recorder) Entries() []Entry {
+	var a []Entry
+	for _, line := range strings.Split(r.String(), "\n") {
+		if e := Parse(line); e != nil {
+			a = append(a, e)
+		}
+	}
+	return a
+}
+
+// Find returns the entries written to r
+// whose field key has the given value.
+func (r *Recorder) Find(key, value string) []Entry {
+	var a []Entry
+	for _, e := range r.Entries() {
+		if v, ok := e[key]; ok && v == value {
+			a = append(a, e)
+		}
+	}
+	return a
+}
+
+// Parse parses line as a log entry in the KV,
+// logfmt, or JSON format. It returns nil if line
+// doesn't begin with a field.
+// Values in JSON entries that aren't strings are
+// kept as their JSON text.
+func Parse(line string) Entry {
+	if strings.HasPrefix(line, "{") {
+		return parseJSON(line)
+	}
+	if i := strings.IndexAny(line, " ="); i <= 0 || line[i] != '=' {
+		return nil
+	}
+	e := make(Entry)
+	for s := line; ; {
+		s = strings.TrimLeft(s, " ")
+		i := strings.IndexByte(s, '=')
+		if i <= 0 {
+			return e
+		}
+		k := s[:i]
+		s = s[i+1:]
+		n := valueLen(s)
+		v := s[:n]
+		if strings.HasPrefix(v, `"`) {
+			if u, err := strconv.Unquote(v); err == nil {
+				v = u
+			}
+		}
+		e[k] = v
+		s = s[n:]
+	}
+}
+
+// valueLen returns the length of the value
+// at the start of s, including any quotes.
+func valueLen(s string) int {
+	if !strings.HasPrefix(s, `"`) {
+		if i := strings.IndexByte(s, ' '); i >= 0 {
+			return i
+		}
+		return len(s)
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func parseJSON(line string) Entry {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal([]byte(line), &obj) != nil {
+		return nil
+	}
+	e := make(Entry, len(obj))
+	for k, raw := range obj {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			e[k] = s
+		} else {
+			e[k] = string(raw)
+		}
+	}
+	return e
+}
+
+// Matches reports whether e has every field in want,
+// with the same value.
+func (e Entry) Matches(want Entry) bool {
+	for k, v := range want {
+		if got, ok := e[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the fields of e, sorted by key,
+// for use in test failure messages.
+func (e Entry) String() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s=%q", k, e[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package capture
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		line string
+		want Entry
+	}{
+		{`at=x.go:1 t=2017-01-01T00:00:00Z level=info message="hello \"you\" there" n=3`, Entry{
+			"at": "x.go:1", "t": "2017-01-01T00:00:00Z", "level": "info",
+			"message": `hello "you" there`, "n": "3",
+		}},
+		{`a=1 a=2 b=`, Entry{"a": "2", "b": ""}},
+		{`{"message":"hi","n":3,"ok":true}`, Entry{"message": "hi", "n": "3", "ok": "true"}},
+		{"\tchain/log.TestParse()", nil},
+		{"goroutine 1 [running]:", nil},
+		{"", nil},
+	}
+	for _, c := range cases {
+		if got := Parse(c.line); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Parse(%q) = %v want %v", c.line, got, c.want)
+		}
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := new(Recorder)
+	r.Write([]byte("message=a n=1\n\tstack line\nmessage=b n=2\n"))
+	if got := r.Entries(); len(got) != 2 || got[1]["message"] != "b" {
+		t.Errorf("Entries() = %v, want 2 entries ending with message=b", got)
+	}
+	if got := r.Find("n", "1"); len(got) != 1 || got[0]["message"] != "a" {
+		t.Errorf(`Find("n", "1") = %v, want message=a`, got)
+	}
+	if e := r.Entries()[0]; !e.Matches(Entry{"n": "1"}) || e.Matches(Entry{"n": "1", "x": ""}) {
+		t.Errorf("%v.Matches gave the wrong result", e)
+	}
+	r.Reset()
+	if got := r.Entries(); len(got) != 0 {
+		t.Errorf("Entries() after Reset = %v, want none", got)
+	}
+}
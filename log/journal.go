@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// formatJournal writes an entry in the systemd journal's
+// native protocol: one NAME=value line per field,
+// with values containing a newline written
+// as the name, a newline, the value's length
+// as a little-endian uint64, the value, and a newline.
+// See package chain/log/journald.
+//
+// MESSAGE is the entry's summary, PRIORITY its severity,
+// SYSLOG_IDENTIFIER the product set by SetDevice,
+// CODE_FILE and CODE_LINE its KeyCaller location,
+// and STACK its stack trace, if any. Every other field,
+// including the prefix fields pre, is written with its key
+// as a journal field name (see journalName),
+// so that, for example, journalctl REQID=x finds
+// the entries of one request.
+func formatJournal(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	deviceMu.Lock()
+	app := deviceProduct
+	deviceMu.Unlock()
+
+	writeJournalField(buf, "MESSAGE", summary(fields))
+	writeJournalField(buf, "PRIORITY", fmt.Sprint(syslogSeverities[sev]))
+	writeJournalField(buf, "SYSLOG_IDENTIFIER", app)
+	if i := strings.LastIndexByte(loc, ':'); i >= 0 {
+		writeJournalField(buf, "CODE_FILE", loc[:i])
+		writeJournalField(buf, "CODE_LINE", loc[i+1:])
+	}
+	for _, a := range [][]interface{}{pre, fields} {
+		for i := 0; i < len(a); i += 2 {
+			if a[i] == KeyMessage {
+				continue // in MESSAGE
+			}
+			writeJournalField(buf, journalName(formatKey(a[i])), valueString(a[i+1]))
+		}
+	}
+	if stack != nil {
+		var b bytes.Buffer
+		writeRawStack(&b, stack)
+		if b.Len() > 0 {
+			writeJournalField(buf, "STACK", b.String())
+		}
+	}
+}
+
+func writeJournalField(buf *bytes.Buffer, name, v string) {
+	buf.WriteString(name)
+	if strings.IndexByte(v, '\n') < 0 {
+		buf.WriteByte('=')
+		buf.WriteString(v)
+		buf.WriteByte('\n')
+		return
+	}
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], uint64(len(v)))
+	buf.WriteByte('\n')
+	buf.Write(n[:])
+	buf.WriteString(v)
+	buf.WriteByte('\n')
+}
+
+// journalName returns k as a journal field name:
+// at most 64 upper case letters, digits, and underscores,
+// not starting with a digit or an underscore,
+// which the journal reserves for trusted fields.
+func journalName(k string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		}
+		return '_'
+	}, k)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || ('0' <= name[0] && name[0] <= '9') {
+		name = "F_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFormatJournal(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 0, time.UTC)
+	buf := new(bytes.Buffer)
+	formatJournal(buf, []interface{}{"reqid", "r1"}, severityError, ts, "a.go:12", []interface{}{
+		KeyMessage, "saving block",
+		KeyError, errors.New("disk full"),
+		"_bad key", 1,
+		"2fa", true,
+	}, []byte("line1\nline2"))
+
+	want := "MESSAGE=saving block\n" +
+		"PRIORITY=3\n" +
+		"SYSLOG_IDENTIFIER=cored\n" +
+		"CODE_FILE=a.go\n" +
+		"CODE_LINE=12\n" +
+		"REQID=r1\n" +
+		"ERROR=disk full\n" +
+		"BAD_KEY=1\n" +
+		"F_2FA=true\n" +
+		"STACK\n\x0c\x00\x00\x00\x00\x00\x00\x00line1\nline2\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("formatJournal =\n%q want\n%q", got, want)
+	}
+}
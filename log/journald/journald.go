@@ -0,0 +1,30 @@
+// Package journald sends log entries in the systemd journal's
+// native protocol (see chain/log.Journal) to the local journal.
+package journald
+
+import (
+	"errors"
+	"io"
+)
+
+// socketPath is the journal's native protocol socket.
+var socketPath = "/run/systemd/journal/socket"
+
+// ErrUnsupported is returned by a writer on systems
+// without the systemd journal.
+var ErrUnsupported = errors.New("journald: not supported on this system")
+
+// New creates a new writer that sends each written
+// entry, formatted by chain/log in the Journal format,
+// to the local journal as one datagram.
+// Entries too large for a datagram are written
+// to an unlinked temporary file in /dev/shm,
+// whose descriptor is passed to the journal.
+//
+// It connects on the first call to Write,
+// and reconnects after a write error.
+// On systems other than Linux, every write
+// fails with ErrUnsupported.
+func New() io.Writer {
+	return new(writer)
+}
@@ -0,0 +1,76 @@
+package journald
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+)
+
+type writer struct {
+	conn *net.UnixConn
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	if w.conn == nil {
+		// An unconnected socket, as WriteMsgUnix
+		// requires for passing descriptors.
+		w.conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+		if err != nil {
+			return 0, err
+		}
+	}
+	_, _, err = w.conn.WriteMsgUnix(p, nil, addr())
+	if isTooLarge(err) {
+		err = w.writeFile(p)
+	}
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the socket, if any.
+// A later Write opens another.
+func (w *writer) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func addr() *net.UnixAddr {
+	return &net.UnixAddr{Name: socketPath, Net: "unixgram"}
+}
+
+// writeFile sends p to the journal in a file.
+func (w *writer) writeFile(p []byte) error {
+	f, err := ioutil.TempFile("/dev/shm", "journal")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	os.Remove(f.Name())
+	_, err = f.Write(p)
+	if err != nil {
+		return err
+	}
+	_, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), addr())
+	return err
+}
+
+// isTooLarge reports whether err is the error from sending
+// a datagram larger than the socket allows.
+func isTooLarge(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return err == syscall.EMSGSIZE || err == syscall.ENOBUFS
+}
@@ -0,0 +1,89 @@
+package journald
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func listen(t *testing.T) (*net.UnixConn, func()) {
+	dir, err := ioutil.TempDir("", "journald")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := socketPath
+	socketPath = dir + "/socket"
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return conn, func() {
+		conn.Close()
+		socketPath = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	conn, done := listen(t)
+	defer done()
+
+	const entry = "MESSAGE=hi\nPRIORITY=6\nREQID=r1\n"
+	w := New()
+	n, err := w.Write([]byte(entry))
+	if err != nil || n != len(entry) {
+		t.Fatalf("Write = %d, %v want %d, nil", n, err, len(entry))
+	}
+	b := make([]byte, 100)
+	n, err = conn.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b[:n]); got != entry {
+		t.Errorf("got %q want %q", got, entry)
+	}
+}
+
+func TestWriteLarge(t *testing.T) {
+	conn, done := listen(t)
+	defer done()
+
+	w := New()
+	entry := append([]byte("MESSAGE="), bytes.Repeat([]byte("x"), 1<<20)...)
+	_, err := w.Write(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 100)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(b, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("datagram has %d bytes, want 0", n)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("control messages = %v, %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("rights = %v, %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	f.Seek(0, 0)
+	got, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, entry) {
+		t.Errorf("file has %d bytes, want %d", len(got), len(entry))
+	}
+}
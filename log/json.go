@@ -0,0 +1,156 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// formatJSON writes an entry as a JSON object on one line.
+// Its members are, in order, the prefix fields pre,
+// the auto-generated fields, and fields.
+// Duplicate keys are preserved, as in the KV format.
+// The stack trace, if any, is the string member KeyStack.
+func formatJSON(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	startJSON(buf, pre, sev, t, loc)
+	for i := 0; i < len(fields); i += 2 {
+		writeJSONMember(buf, fields[i], fields[i+1])
+	}
+	if stack != nil {
+		var b bytes.Buffer
+		writeRawStack(&b, stack)
+		if b.Len() > 0 {
+			writeJSONMember(buf, KeyStack, b.String())
+		}
+	}
+	buf.Truncate(buf.Len() - 1) // trailing comma
+	buf.WriteString("}\n")
+}
+
+// startJSON writes the opening brace of a JSON entry,
+// its prefix fields, and its auto-generated fields.
+func startJSON(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string) {
+	buf.WriteByte('{')
+	for i := 0; i < len(pre); i += 2 {
+		writeJSONMember(buf, pre[i], pre[i+1])
+	}
+	writeJSONStringMember(buf, KeyCaller, loc)
+	writeJSONStringMember(buf, KeyTime, formatTime(t))
+	writeJSONStringMember(buf, KeyLevel, sev)
+}
+
+// writeJSONStringMember is writeJSONMember for a string key and value.
+func writeJSONStringMember(buf *bytes.Buffer, k, v string) {
+	writeJSONString(buf, formatKeyString(k))
+	buf.WriteByte(':')
+	writeJSONString(buf, v)
+	buf.WriteByte(',')
+}
+
+func writeJSONMember(buf *bytes.Buffer, k, v interface{}) {
+	writeJSONString(buf, formatKey(k))
+	buf.WriteByte(':')
+	if s, ok := v.(string); ok {
+		writeJSONString(buf, s)
+	} else if !writeInteger(buf, v) {
+		buf.Write(jsonValue(v))
+	}
+	buf.WriteByte(',')
+}
+
+// jsonValue returns the JSON encoding of v.
+// Numbers, booleans, strings, and values
+// implementing json.Marshaler encode as usual,
+// and nil values, as for valueString, as null.
+// Errors, fmt.Stringers, and byte slices encode as strings,
+// as they print in the KV format, and durations as numbers
+// or strings, as set by SetDurationUnit.
+// Other values encode as usual if they can,
+// and as the string fmt.Sprint(v) otherwise.
+func jsonValue(v interface{}) []byte {
+	if isNil(v) {
+		return []byte(nullValue)
+	}
+	switch v := v.(type) {
+	case json.Marshaler:
+	case []byte:
+		return jsonString(bytesString(v))
+	case time.Duration:
+		if atomic.LoadInt64(&durationUnit) > 0 {
+			return []byte(durationString(v))
+		}
+		return jsonString(v.String())
+	case error:
+		return jsonString(v.Error())
+	case fmt.Stringer:
+		return jsonString(v.String())
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return jsonString(fmt.Sprint(v))
+	}
+	return b
+}
+
+func jsonString(s string) []byte {
+	var buf bytes.Buffer
+	writeJSONString(&buf, s)
+	return buf.Bytes()
+}
+
+// writeJSONString writes s as a JSON string,
+// escaped as by encoding/json, without its reflection.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
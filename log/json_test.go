@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"chain/errors"
+)
+
+func TestFormatJSON(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	cases := []struct {
+		pre    []interface{}
+		fields []interface{}
+		want   string
+	}{
+		{nil, nil, `{"at":"a.go:1","t":"2017-03-01T13:04:05.006000000Z","level":"info"}`},
+		{
+			[]interface{}{"reqid", "r1"},
+			[]interface{}{"n", 7, "ok", true, "d", time.Second, "err", errors.New("bad"), "m", map[string]int{"x": 1}, "nil", nil},
+			`{"reqid":"r1","at":"a.go:1","t":"2017-03-01T13:04:05.006000000Z","level":"info","n":7,"ok":true,"d":"1s","err":"bad","m":{"x":1},"nil":null}`,
+		},
+		{nil, []interface{}{"a b", "quote\"d\n", "c", make(chan int)}, ``},
+	}
+	for i, c := range cases {
+		buf := new(bytes.Buffer)
+		formatJSON(buf, c.pre, severityInfo, ts, "a.go:1", c.fields, nil)
+		got := buf.String()
+		if !strings.HasSuffix(got, "}\n") || strings.Count(got, "\n") != 1 {
+			t.Errorf("%d: entry %q is not one line", i, got)
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &v); err != nil {
+			t.Errorf("%d: entry %q: %v", i, got, err)
+		}
+		if c.want != "" && got != c.want+"\n" {
+			t.Errorf("%d: formatJSON = %q want %q", i, got, c.want)
+		}
+	}
+}
+
+func TestSetFormatJSON(t *testing.T) {
+	out := captureOutput(t)
+	SetFormat(JSON)
+	defer SetFormat(KV)
+	SetPrefix("app", "cored")
+	defer SetPrefix()
+
+	ctx := AddPrefixkv(context.Background(), "reqid", "r1")
+	Printkv(ctx, KeyError, errors.New("boom"))
+
+	entries := out.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("log = %q, want one JSON entry", out)
+	}
+	v := entries[0]
+	if v["app"] != "cored" || v["reqid"] != "r1" || v[KeyError] != "boom" {
+		t.Errorf("entry = %v", v)
+	}
+	if !strings.HasPrefix(v[KeyCaller], "json_test.go:") {
+		t.Errorf("at = %v want json_test.go:...", v[KeyCaller])
+	}
+	if !strings.Contains(v[KeyStack], "TestSetFormatJSON") {
+		t.Errorf("stack = %q want trace", v[KeyStack])
+	}
+}
+
+func TestWriteJSONString(t *testing.T) {
+	cases := []string{
+		"", "plain", `quote" back\slash`, "nl\ncr\rtab\t", "\x00\x1f\x7f",
+		"<a href='x'>&</a>", "héllo, 世界", "bad \xff utf8", "line\u2028sep\u2029",
+	}
+	for _, s := range cases {
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+		want, _ := json.Marshal(s)
+		var got, wantStr string
+		json.Unmarshal(want, &wantStr)
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil || got != wantStr {
+			t.Errorf("writeJSONString(%q) = %s (%v) want %s", s, buf.Bytes(), err, want)
+		}
+		if strings.ContainsAny(buf.String(), "<>&\u2028\u2029") {
+			t.Errorf("writeJSONString(%q) = %s, want HTML and line separators escaped", s, buf.Bytes())
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Package kafka publishes log entries to a Kafka topic,
+// so that several cored processes can feed
+// a central log pipeline.
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"chain/log/internal/batch"
+	"chain/sync/retry"
+)
+
+const (
+	// DialTimeout limits how long the producer
+	// waits to connect to a broker.
+	DialTimeout = time.Second
+
+	// RequestTimeout limits how long the producer
+	// waits for a broker to respond to a request.
+	RequestTimeout = 10 * time.Second
+)
+
+var (
+	delivered = expvar.NewInt("log_kafka_delivered")
+	failed    = expvar.NewInt("log_kafka_failed")
+)
+
+// Config configures a writer that publishes to Kafka.
+type Config struct {
+	Brokers  []string // bootstrap brokers, as host:port
+	Topic    string
+	ClientID string // default "chain"
+}
+
+type writer struct {
+	w *batch.Writer
+	p *producer
+}
+
+type producer struct {
+	c             Config
+	correlationID int32
+
+	// Cluster metadata, or nil before the first
+	// request and after an error.
+	partitions int              // in the topic, with or without a leader
+	available  []int32          // partitions with a leader
+	leaders    map[int32]int32  // partition ID -> broker ID
+	addrs      map[int32]string // broker ID -> host:port
+	conns      map[string]net.Conn
+	next       int // index in available for entries with no key
+}
+
+// New creates a new writer that publishes each written
+// entry as one message to the topic configured by c.
+// A message's key is the entry's "reqid" field,
+// if it has one, so that the entries for one request
+// are kept in order in one partition.
+// Keys are assigned partitions as by the Java client's
+// default partitioner, over all the topic's partitions,
+// so a key keeps its partition when a leader fails;
+// other messages are spread over the partitions
+// that have a leader, in turn.
+//
+// Writes never block: entries are queued and published
+// in batches from a background goroutine, and
+// dropped if too many are waiting.
+// The producer waits for each partition's leader to
+// acknowledge the messages, and retries a failed
+// request after refreshing the cluster metadata.
+// Published and undeliverable messages are counted
+// in expvars "log_kafka_delivered" and "log_kafka_failed".
+func New(c Config) io.Writer {
+	if c.ClientID == "" {
+		c.ClientID = "chain"
+	}
+	p := &producer{c: c, conns: make(map[string]net.Conn)}
+	return &writer{w: batch.New(p.send), p: p}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// Flush publishes the queued entries, waiting at most batch.FlushTimeout.
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close publishes the queued entries,
+// then closes the connections to the brokers.
+func (w *writer) Close() error {
+	err := w.w.Close()
+	w.p.reset()
+	return err
+}
+
+func (p *producer) send(entries [][]byte) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	pending := make([]message, 0, len(entries))
+	for _, e := range entries {
+		pending = append(pending, message{key: reqID(e), value: e, timestamp: now})
+	}
+	policy := retry.Policy{Attempts: 3, Initial: 100 * time.Millisecond, Logger: batch.Stderr}
+	err := retry.Do(context.Background(), "kafka-produce", policy, func(context.Context) error {
+		if p.leaders == nil {
+			err := p.refresh()
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		pending, err = p.produce(pending)
+		if err != nil {
+			p.reset()
+		}
+		return err
+	})
+	if err != nil {
+		failed.Add(int64(len(pending)))
+	}
+}
+
+// produce publishes msgs and returns those
+// that were not acknowledged.
+func (p *producer) produce(msgs []message) (unsent []message, err error) {
+	if len(p.available) == 0 {
+		return msgs, errors.New("kafka: no partition has a leader")
+	}
+	byLeader := make(map[int32]map[int32][]message)
+	for _, m := range msgs {
+		part := p.partition(m)
+		leader, ok := p.leaders[part]
+		if !ok {
+			unsent = append(unsent, m)
+			err = errors.New("kafka: partition " + strconv.Itoa(int(part)) + " has no leader")
+			continue
+		}
+		if byLeader[leader] == nil {
+			byLeader[leader] = make(map[int32][]message)
+		}
+		byLeader[leader][part] = append(byLeader[leader][part], m)
+	}
+
+	for leader, parts := range byLeader {
+		failedParts, reqErr := p.produceTo(p.addrs[leader], parts)
+		for part, msgs := range parts {
+			if reqErr == nil && !failedParts[part] {
+				delivered.Add(int64(len(msgs)))
+				continue
+			}
+			unsent = append(unsent, msgs...)
+		}
+		if reqErr != nil && err == nil {
+			err = reqErr
+		}
+		if len(failedParts) > 0 && err == nil {
+			err = errors.New("kafka: produce failed for some partitions")
+		}
+	}
+	return unsent, err
+}
+
+// partition returns the partition for m.
+func (p *producer) partition(m message) int32 {
+	if m.key != nil {
+		return int32(int(murmur2(m.key)&0x7fffffff) % p.partitions)
+	}
+	part := p.available[p.next%len(p.available)]
+	p.next++
+	return part
+}
+
+// produceTo sends one produce request to the broker at addr
+// and returns the partitions whose messages were rejected.
+func (p *producer) produceTo(addr string, parts map[int32][]message) (map[int32]bool, error) {
+	var e encoder
+	e.int16(-1) // transactional ID: null
+	e.int16(1)  // acks: leader only
+	e.int32(int32(RequestTimeout / time.Millisecond))
+	e.int32(1) // topics
+	e.string(p.c.Topic)
+	e.int32(int32(len(parts)))
+	for part, msgs := range parts {
+		var set encoder
+		appendRecordBatch(&set, msgs)
+		e.int32(part)
+		e.int32(int32(set.Len()))
+		e.Write(set.Bytes())
+	}
+
+	d, err := p.roundTrip(addr, apiProduce, produceVersion, e.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	rejected := make(map[int32]bool)
+	for i := d.count(); i > 0; i-- {
+		d.string() // topic
+		for j := d.count(); j > 0; j-- {
+			part := d.int32()
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if code != 0 {
+				rejected[part] = true
+			}
+		}
+	}
+	return rejected, d.err
+}
+
+// refresh reads the topic's metadata
+// from the first bootstrap broker that responds.
+func (p *producer) refresh() error {
+	var e encoder
+	e.int32(1)
+	e.string(p.c.Topic)
+	e.int8(0) // don't create the topic
+
+	err := errors.New("kafka: no brokers")
+	for _, addr := range p.c.Brokers {
+		var d *decoder
+		d, err = p.roundTrip(addr, apiMetadata, metadataVersion, e.Bytes())
+		if err != nil {
+			continue
+		}
+		err = p.readMetadata(d)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (p *producer) readMetadata(d *decoder) error {
+	d.int32() // throttle time
+	addrs := make(map[int32]string)
+	for i := d.count(); i > 0; i-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		addrs[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster ID
+	d.int32()  // controller ID
+	leaders := make(map[int32]int32)
+	var partitions int
+	var available []int32
+	var topicErr error
+	for i := d.count(); i > 0; i-- {
+		code := d.int16()
+		name := d.string()
+		d.int8() // is internal
+		if name == p.c.Topic && code != 0 {
+			topicErr = kafkaError(code)
+		}
+		for j := d.count(); j > 0; j-- {
+			d.int16() // partition error code
+			part := d.int32()
+			leader := d.int32()
+			for k := d.count(); k > 0; k-- {
+				d.int32() // replicas
+			}
+			for k := d.count(); k > 0; k-- {
+				d.int32() // in-sync replicas
+			}
+			if name != p.c.Topic {
+				continue
+			}
+			partitions++
+			if leader >= 0 {
+				leaders[part] = leader
+				available = append(available, part)
+			}
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if topicErr != nil {
+		return topicErr
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i] < available[j] })
+	p.addrs, p.leaders, p.partitions, p.available = addrs, leaders, partitions, available
+	return nil
+}
+
+// roundTrip sends a request to the broker at addr
+// and reads its response, connecting if necessary.
+func (p *producer) roundTrip(addr string, apiKey, version int16, body []byte) (*decoder, error) {
+	conn := p.conns[addr]
+	if conn == nil {
+		var err error
+		conn, err = net.DialTimeout("tcp", addr, DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		p.conns[addr] = conn
+	}
+	p.correlationID++
+	conn.SetDeadline(time.Now().Add(RequestTimeout))
+	_, err := conn.Write(request(apiKey, version, p.correlationID, p.c.ClientID, body))
+	if err != nil {
+		conn.Close()
+		delete(p.conns, addr)
+		return nil, err
+	}
+	d, err := readResponse(conn, p.correlationID)
+	if err != nil {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+	return d, err
+}
+
+// reset closes every connection and forgets
+// the cluster metadata.
+func (p *producer) reset() {
+	for addr, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+	p.leaders = nil
+}
+
+// reqID returns the value of the "reqid" field
+// in entry, formatted as K=V pairs or JSON, or nil.
+func reqID(entry []byte) []byte {
+	for _, pat := range []string{"reqid=", `"reqid":"`} {
+		i := bytes.Index(entry, []byte(pat))
+		if i < 0 || (i > 0 && pat[0] == 'r' && entry[i-1] != ' ') {
+			continue
+		}
+		v := entry[i+len(pat):]
+		if j := bytes.IndexAny(v, " \""); j >= 0 {
+			v = v[:j]
+		}
+		if len(v) > 0 {
+			return v
+		}
+	}
+	return nil
+}
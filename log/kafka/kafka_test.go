@@ -0,0 +1,292 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMurmur2(t *testing.T) {
+	// From the Java client's tests.
+	cases := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for s, want := range cases {
+		if got := murmur2([]byte(s)); got != want {
+			t.Errorf("murmur2(%q) = %d want %d", s, got, want)
+		}
+	}
+}
+
+func TestReqID(t *testing.T) {
+	cases := map[string]string{
+		"reqid=r1 at=a.go:1 message=hi":           "r1",
+		`{"reqid":"r2","at":"a.go:1"}`:            "r2",
+		"at=a.go:1 reqid=r3":                      "r3",
+		"at=a.go:1 xreqid=r4 message=hi":          "",
+		"at=a.go:1 message=hi":                    "",
+		`{"at":"a.go:1","message":"reqid=inner"}`: "",
+	}
+	for entry, want := range cases {
+		if got := string(reqID([]byte(entry))); got != want {
+			t.Errorf("reqID(%q) = %q want %q", entry, got, want)
+		}
+	}
+}
+
+// broker is a fake Kafka broker with one topic
+// of two partitions.
+type broker struct {
+	ln   net.Listener
+	mu   sync.Mutex
+	msgs map[int32][]message // by partition
+}
+
+// writeMetadata writes a metadata response listing one broker,
+// with ID 0, and the logs topic, whose partitions are
+// led by the brokers in leaders, or -1 if none.
+func writeMetadata(e *encoder, addr string, leaders []int32) {
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	e.int32(0) // throttle time
+	e.int32(1) // brokers
+	e.int32(0)
+	e.string(host)
+	e.int32(int32(p))
+	e.int16(-1) // rack
+	e.int16(-1) // cluster ID
+	e.int32(0)  // controller ID
+	e.int32(1)  // topics
+	e.int16(0)
+	e.string("logs")
+	e.int8(0) // is internal
+	e.int32(int32(len(leaders)))
+	for part, leader := range leaders {
+		e.int16(0)
+		e.int32(int32(part))
+		e.int32(leader)
+		e.int32(1) // replicas
+		e.int32(0)
+		e.int32(1) // isr
+		e.int32(0)
+	}
+}
+
+// readRecordBatch reads the messages in a record batch.
+func readRecordBatch(t *testing.T, d *decoder) []message {
+	d.int64() // base offset
+	n := d.int32()
+	d.int32() // leader epoch
+	if magic := d.int8(); magic != 2 {
+		t.Errorf("magic = %d want 2", magic)
+	}
+	crc := uint32(d.int32())
+	body := d.next(int(n) - 4 - 1 - 4)
+	if got := crc32.Checksum(body, castagnoli); got != crc {
+		t.Errorf("crc = %x want %x", crc, got)
+	}
+	b := &decoder{b: body}
+	b.int16() // attributes
+	b.int32() // last offset delta
+	base := b.int64()
+	b.next(8 + 8 + 2 + 4) // max timestamp, producer ID and epoch, base sequence
+	var msgs []message
+	for i := b.int32(); i > 0; i-- {
+		varint := func() int64 {
+			v, n := binary.Varint(b.b)
+			b.next(n)
+			return v
+		}
+		varint() // length
+		b.int8() // attributes
+		m := message{timestamp: base + varint()}
+		varint() // offset delta
+		if n := varint(); n >= 0 {
+			m.key = b.next(int(n))
+		}
+		m.value = b.next(int(varint()))
+		varint() // headers
+		msgs = append(msgs, m)
+	}
+	if b.err != nil || len(b.b) > 0 {
+		t.Errorf("record batch: %v, %d bytes left over", b.err, len(b.b))
+	}
+	return msgs
+}
+
+func newBroker(t *testing.T) *broker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{ln: ln, msgs: make(map[int32][]message)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(t, conn)
+		}
+	}()
+	return b
+}
+
+func (b *broker) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		d := &decoder{b: req}
+		apiKey := d.int16()
+		version := d.int16()
+		id := d.int32()
+		d.string() // client ID
+
+		var resp encoder
+		resp.int32(id)
+		switch apiKey {
+		case apiMetadata:
+			if version != metadataVersion {
+				t.Errorf("metadata version = %d want %d", version, metadataVersion)
+			}
+			writeMetadata(&resp, b.ln.Addr().String(), []int32{0, 0})
+		case apiProduce:
+			if version != produceVersion {
+				t.Errorf("produce version = %d want %d", version, produceVersion)
+			}
+			d.string() // transactional ID
+			d.int16()  // acks
+			d.int32()  // timeout
+			d.count()  // topics
+			topic := d.string()
+			n := d.count()
+			resp.int32(1)
+			resp.string(topic)
+			resp.int32(int32(n))
+			for ; n > 0; n-- {
+				part := d.int32()
+				set := &decoder{b: d.next(int(d.int32()))}
+				msgs := readRecordBatch(t, set)
+				b.mu.Lock()
+				b.msgs[part] = append(b.msgs[part], msgs...)
+				b.mu.Unlock()
+				resp.int32(part)
+				resp.int16(0)
+				resp.int64(0)
+				resp.int64(-1)
+			}
+			resp.int32(0) // throttle time
+		}
+		var out encoder
+		out.bytes(resp.Bytes())
+		conn.Write(out.Bytes())
+	}
+}
+
+func TestSend(t *testing.T) {
+	b := newBroker(t)
+	defer b.ln.Close()
+
+	p := &producer{
+		c:     Config{Brokers: []string{b.ln.Addr().String()}, Topic: "logs", ClientID: "test"},
+		conns: make(map[string]net.Conn),
+	}
+	d0, f0 := delivered.Value(), failed.Value()
+	p.send([][]byte{
+		[]byte("reqid=r1 message=a"),
+		[]byte("reqid=r1 message=b"),
+		[]byte("message=c"),
+		[]byte("message=d"),
+	})
+	if got := delivered.Value() - d0; got != 4 {
+		t.Errorf("delivered = %d want 4", got)
+	}
+	if got := failed.Value() - f0; got != 0 {
+		t.Errorf("failed = %d want 0", got)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var keyed []string
+	for part, msgs := range b.msgs {
+		unkeyed := 0
+		for _, m := range msgs {
+			if m.key == nil {
+				unkeyed++
+				continue
+			}
+			if string(m.key) != "r1" {
+				t.Errorf("key = %q want r1", m.key)
+			}
+			keyed = append(keyed, string(m.value))
+			if want := int32(int(murmur2(m.key)&0x7fffffff) % 2); part != want {
+				t.Errorf("message %q in partition %d want %d", m.value, part, want)
+			}
+		}
+		if unkeyed != 1 {
+			t.Errorf("partition %d has %d unkeyed messages, want 1", part, unkeyed)
+		}
+	}
+	if got := strings.Join(keyed, ","); got != "reqid=r1 message=a,reqid=r1 message=b" {
+		t.Errorf("keyed messages = %q, want both in order", got)
+	}
+}
+
+func TestSendFail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listening
+
+	p := &producer{c: Config{Brokers: []string{addr}, Topic: "logs"}, conns: make(map[string]net.Conn)}
+	f0 := failed.Value()
+	p.send([][]byte{[]byte("message=a"), []byte("message=b")})
+	if got := failed.Value() - f0; got != 2 {
+		t.Errorf("failed = %d want 2", got)
+	}
+}
+
+func TestPartitionLeaderless(t *testing.T) {
+	var e encoder
+	writeMetadata(&e, "127.0.0.1:9092", []int32{0, -1, 0})
+	p := &producer{c: Config{Topic: "logs"}}
+	if err := p.readMetadata(&decoder{b: e.Bytes()}); err != nil {
+		t.Fatal(err)
+	}
+	if p.partitions != 3 || len(p.available) != 2 {
+		t.Fatalf("partitions = %d, available = %v, want 3 and [0 2]", p.partitions, p.available)
+	}
+
+	// A key's partition depends on the number of partitions,
+	// not on which have a leader.
+	for _, key := range []string{"r1", "r2", "r3", "r4"} {
+		want := int32(int(murmur2([]byte(key))&0x7fffffff) % 3)
+		if got := p.partition(message{key: []byte(key)}); got != want {
+			t.Errorf("partition(%s) = %d want %d", key, got, want)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		if got := p.partition(message{}); got == 1 {
+			t.Errorf("unkeyed message %d assigned leaderless partition 1", i)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Kafka API keys and the versions used here.
+const (
+	apiProduce  = 0
+	apiMetadata = 3
+
+	produceVersion  = 3 // the first with record batches (message format v2)
+	metadataVersion = 4
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+var errShort = errors.New("kafka: short response")
+
+// A kafkaError is an error code returned by a broker.
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	return fmt.Sprintf("kafka: broker error code %d", int16(e))
+}
+
+// An encoder builds a request in the Kafka protocol's
+// big-endian encoding.
+type encoder struct {
+	bytes.Buffer
+}
+
+func (e *encoder) int8(v int8) { e.WriteByte(byte(v)) }
+
+func (e *encoder) int16(v int16) {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], uint16(v))
+	e.Write(b[:])
+}
+
+func (e *encoder) int32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	e.Write(b[:])
+}
+
+func (e *encoder) int64(v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	e.Write(b[:])
+}
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.WriteString(s)
+}
+
+// bytes writes b, or a null if b is nil.
+func (e *encoder) bytes(b []byte) {
+	if b == nil {
+		e.int32(-1)
+		return
+	}
+	e.int32(int32(len(b)))
+	e.Write(b)
+}
+
+// varint writes v in the zigzag varint encoding
+// used within record batches.
+func (e *encoder) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+// varbytes writes b with a varint length,
+// or a null if b is nil.
+func (e *encoder) varbytes(b []byte) {
+	if b == nil {
+		e.varint(-1)
+		return
+	}
+	e.varint(int64(len(b)))
+	e.Write(b)
+}
+
+// A decoder reads a response. After the first error,
+// such as a short response, every read returns zero
+// and err is set.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || len(d.b) < n {
+		d.err = errShort
+		return make([]byte, n)
+	}
+	b := d.b[:n]
+	d.b = d.b[n:]
+	return b
+}
+
+func (d *decoder) int8() int8   { return int8(d.next(1)[0]) }
+func (d *decoder) int16() int16 { return int16(binary.BigEndian.Uint16(d.next(2))) }
+func (d *decoder) int32() int32 { return int32(binary.BigEndian.Uint32(d.next(4))) }
+func (d *decoder) int64() int64 { return int64(binary.BigEndian.Uint64(d.next(8))) }
+
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+// count reads an array length.
+func (d *decoder) count() int {
+	n := d.int32()
+	if n > int32(len(d.b)) {
+		d.err = errShort
+	}
+	if n < 0 || d.err != nil {
+		return 0 // null array
+	}
+	return int(n)
+}
+
+// request returns a request with the given API key
+// and body, including its size and header.
+func request(apiKey, version int16, correlationID int32, clientID string, body []byte) []byte {
+	var e encoder
+	e.int32(0) // size, set below
+	e.int16(apiKey)
+	e.int16(version)
+	e.int32(correlationID)
+	e.string(clientID)
+	e.Write(body)
+	b := e.Bytes()
+	binary.BigEndian.PutUint32(b, uint32(len(b)-4))
+	return b
+}
+
+// readResponse reads a response and returns
+// a decoder for it, positioned after its correlation ID.
+func readResponse(r io.Reader, correlationID int32) (*decoder, error) {
+	var size [4]byte
+	_, err := io.ReadFull(r, size[:])
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, binary.BigEndian.Uint32(size[:]))
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{b: b}
+	if id := d.int32(); id != correlationID {
+		return nil, fmt.Errorf("kafka: got correlation ID %d want %d", id, correlationID)
+	}
+	return d, nil
+}
+
+// A message is one entry to publish.
+type message struct {
+	key, value []byte
+	timestamp  int64 // milliseconds since the epoch
+}
+
+// appendRecordBatch appends msgs to e as one
+// record batch, in message format v2.
+// The caller must pass at least one message.
+func appendRecordBatch(e *encoder, msgs []message) {
+	base, max := msgs[0].timestamp, msgs[0].timestamp
+	for _, m := range msgs {
+		if m.timestamp > max {
+			max = m.timestamp
+		}
+	}
+
+	// The part of the batch covered by its CRC.
+	var body encoder
+	body.int16(0) // attributes: no compression, create time
+	body.int32(int32(len(msgs) - 1))
+	body.int64(base)
+	body.int64(max)
+	body.int64(-1) // producer ID: not idempotent
+	body.int16(-1) // producer epoch
+	body.int32(-1) // base sequence
+	body.int32(int32(len(msgs)))
+	for i, m := range msgs {
+		var rec encoder
+		rec.int8(0) // attributes, unused
+		rec.varint(m.timestamp - base)
+		rec.varint(int64(i)) // offset delta
+		rec.varbytes(m.key)
+		rec.varbytes(m.value)
+		rec.varint(0) // headers
+		body.varint(int64(rec.Len()))
+		body.Write(rec.Bytes())
+	}
+
+	// The batch length counts the bytes after it:
+	// the leader epoch, magic, CRC, and body.
+	e.int64(0) // base offset, assigned by the broker
+	e.int32(int32(4 + 1 + 4 + body.Len()))
+	e.int32(-1) // partition leader epoch, set by the broker
+	e.int8(2)   // magic
+	e.int32(int32(crc32.Checksum(body.Bytes(), castagnoli)))
+	e.Write(body.Bytes())
+}
+
+// murmur2 is the hash the Java client's default
+// partitioner uses, so entries with the same key
+// land in the same partition as they would
+// from other producers.
+func murmur2(data []byte) int32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	h := uint32(seed ^ len(data))
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
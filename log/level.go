@@ -0,0 +1,130 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// A Level is a minimum severity for entries
+// to be written to the log output.
+type Level int32
+
+// Levels, in increasing order of severity.
+// The zero Level is LevelInfo.
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+// minLevel holds the Level set by SetLevel.
+var minLevel int32
+
+var levelNames = map[Level]string{
+	LevelDebug:   severityDebug,
+	LevelInfo:    severityInfo,
+	LevelWarning: severityWarning,
+	LevelError:   severityError,
+}
+
+func (l Level) String() string {
+	if s, ok := levelNames[l]; ok {
+		return s
+	}
+	return fmt.Sprintf("Level(%d)", int32(l))
+}
+
+// ParseLevel returns the Level with the given name:
+// "debug", "info", "warning", or "error".
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if s == name {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// SetLevel sets the minimum severity of entries written
+// to the log output. Entries below l are discarded
+// and counted in expvar "log_dropped".
+// The default is LevelInfo, which writes every entry
+// except those at LevelDebug.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&minLevel, int32(l))
+}
+
+// CurrentLevel returns the minimum severity set by SetLevel.
+func CurrentLevel() Level {
+	return Level(atomic.LoadInt32(&minLevel))
+}
+
+// enabled reports whether entries with severity sev
+// are at or above the level set by SetLevel.
+func enabled(sev string) bool {
+	return int32(levelOf(sev)) >= atomic.LoadInt32(&minLevel)
+}
+
+// levelOf returns the Level of entries with severity sev.
+func levelOf(sev string) Level {
+	switch sev {
+	case severityDebug:
+		return LevelDebug
+	case severityWarning:
+		return LevelWarning
+	case severityError:
+		return LevelError
+	}
+	return LevelInfo
+}
+
+// Enabled reports whether an entry at level l, logged from
+// the function calling Enabled, would be kept by SetLevel
+// and SetVModule (or SetDebugBuffer, for LevelDebug), so that
+// callers can skip building the fields of an entry that
+// would be discarded. Filters and sampling can still
+// discard an entry for which it reports true.
+func Enabled(l Level) bool {
+	return levelEnabled(l)
+}
+
+// DebugEnabled is Enabled(LevelDebug).
+func DebugEnabled() bool {
+	return levelEnabled(LevelDebug)
+}
+
+// WriteIf writes an entry at level l with the fields
+// returned by keyvals, as Printkv, but calls keyvals
+// only if Enabled(l), for fields that are costly to build:
+//
+//	log.WriteIf(ctx, log.LevelDebug, func() []interface{} {
+//		return []interface{}{"mempool", pool.Dump()}
+//	})
+func WriteIf(ctx context.Context, l Level, keyvals func() []interface{}) {
+	sev := l.String()
+	if !levelEnabled(l) {
+		countDrop(dropLevel, sev)
+		return
+	}
+	printkv(ctx, caller, sev, keyvals())
+}
+
+// levelEnabled implements Enabled
+// for the function calling its caller.
+func levelEnabled(l Level) bool {
+	if int32(l) >= atomic.LoadInt32(&minLevel) {
+		return true
+	}
+	if l == LevelDebug && debugBufferEnabled() {
+		return true // the entry may be buffered
+	}
+	if vr := currentVModule(); len(vr.rules) > 0 {
+		fn, _ := caller()
+		if min, matched := vr.level(fn); matched {
+			return l >= min
+		}
+	}
+	return false
+}
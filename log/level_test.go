@@ -0,0 +1,113 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestSetLevel(t *testing.T) {
+	out := captureOutput(t)
+	SetLevel(LevelWarning)
+	defer SetLevel(LevelInfo)
+
+	dropped0 := droppedCount(dropLevel, severityInfo)
+	ctx := context.Background()
+	Printkv(ctx, "message", "hello")
+	Printkv(ctx, "warning", "careful")
+	Error(ctx, errors.New("boom"))
+	Audit(ctx, "test")
+
+	entries := out.Entries()
+	if len(entries) != 3 {
+		t.Errorf("entries = %v, want 3", entries)
+	}
+	if got := out.Find(KeyMessage, "hello"); len(got) != 0 {
+		t.Errorf("entries = %v, want info entry discarded", got)
+	}
+	if got := droppedCount(dropLevel, severityInfo) - dropped0; got != 1 {
+		t.Errorf("dropped = %d want 1", got)
+	}
+}
+
+func TestDebugf(t *testing.T) {
+	out := captureOutput(t)
+
+	ctx := context.Background()
+	Debugf(ctx, "hidden %d", 1)
+	if got := out.Entries(); len(got) != 0 {
+		t.Errorf("entries = %v, want debug entry discarded", got)
+	}
+
+	SetLevel(LevelDebug)
+	defer SetLevel(LevelInfo)
+	Debugf(ctx, "shown %d", 2)
+	want := capture.Entry{KeyLevel: "debug", KeyMessage: "shown 2"}
+	if got := out.Entries(); len(got) != 1 || !got[0].Matches(want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestExplicitLevel(t *testing.T) {
+	out := captureOutput(t)
+
+	Printkv(context.Background(), KeyLevel, LevelError, "message", "explicit")
+	want := capture.Entry{KeyLevel: "error", KeyMessage: "explicit"}
+	if got := out.Entries(); len(got) != 1 || !got[0].Matches(want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	// The level is written once, in its usual place,
+	// rather than again among the entry's fields.
+	if got := out.String(); strings.Count(got, KeyLevel+"=") != 1 {
+		t.Errorf("log = %q, want one level field", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{LevelDebug, LevelInfo, LevelWarning, LevelError} {
+		got, err := ParseLevel(l.String())
+		if err != nil || got != l {
+			t.Errorf("ParseLevel(%q) = %v, %v want %v", l.String(), got, err, l)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) err = nil, want error")
+	}
+}
+
+func TestWriteIf(t *testing.T) {
+	out := captureOutput(t)
+	defer SetVModule("")
+
+	ctx := context.Background()
+	calls := 0
+	build := func() []interface{} {
+		calls++
+		return []interface{}{"costly", calls}
+	}
+	if DebugEnabled() {
+		t.Error("DebugEnabled() = true at LevelInfo")
+	}
+	WriteIf(ctx, LevelDebug, build)
+	if got := out.Entries(); calls != 0 || len(got) != 0 {
+		t.Errorf("disabled WriteIf: %d calls, entries = %v; want none", calls, got)
+	}
+
+	SetVModule("chain/log=debug")
+	if !DebugEnabled() {
+		t.Error("DebugEnabled() = false with chain/log=debug")
+	}
+	WriteIf(ctx, LevelDebug, build)
+	want := capture.Entry{KeyLevel: "debug", "costly": "1"}
+	if got := out.Entries(); calls != 1 || len(got) != 1 || !got[0].Matches(want) {
+		t.Errorf("enabled WriteIf: %d calls, entries = %v; want 1, %v", calls, got, want)
+	}
+
+	SetVModule("chain/other=debug")
+	if Enabled(LevelDebug) || !Enabled(LevelWarning) {
+		t.Error("Enabled with chain/other=debug: want debug off, warning on")
+	}
+}
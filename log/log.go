@@ -1,9 +1,10 @@
 // Package log implements a standard convention for structured logging.
-// Log entries are formatted as K=V pairs.
+// Log entries are formatted as K=V pairs, or as set with SetFormat.
 // By default, output is written to stdout; this can be changed with SetOutput.
 package log
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -21,14 +22,45 @@ import (
 type key int
 
 var (
-	logWriterMu sync.Mutex // protects the following
-	logWriter   io.Writer  = os.Stdout
-	procPrefix  []byte     // process-global prefix; see SetPrefix vs AddPrefixkv
+	logWriterMu sync.Mutex  // protects the following
+	logWriter   io.Writer   = os.Stdout
+	procPrefix  fieldPrefix // process-global prefix; see SetPrefix vs AddPrefixkv
 
-	// context key for log line prefixes
-	prefixKey key = 0
+	// context keys for log line prefixes
+	prefixKey       key = 0 // K=V text
+	prefixFieldsKey key = 1 // key-value pairs
+	withFieldsKey   key = 2 // key-value pairs following each entry's own; see With
 )
 
+// A fieldPrefix holds fields written at the start of
+// every entry, both as K=V text, for the KV and Console
+// formats, and as key-value pairs, for the others.
+type fieldPrefix struct {
+	text   []byte
+	fields []interface{}
+}
+
+func newFieldPrefix(keyval ...interface{}) fieldPrefix {
+	return fieldPrefix{
+		text:   appendPrefix(nil, keyval...),
+		fields: appendFields(nil, keyval...),
+	}
+}
+
+// appendFields appends keyval to fields, with keys
+// formatted as in the K=V format.
+// It does not modify the contents of fields.
+func appendFields(fields []interface{}, keyval ...interface{}) []interface{} {
+	if len(keyval) == 0 {
+		return fields
+	}
+	fields = fields[:len(fields):len(fields)]
+	for i := 0; i < len(keyval); i += 2 {
+		fields = append(fields, formatKey(keyval[i]), keyval[i+1])
+	}
+	return fields
+}
+
 const (
 	rfc3339NanoFixed = "2006-01-02T15:04:05.000000000Z07:00"
 
@@ -50,6 +82,7 @@ const (
 	KeyMessage = "message" // produced by Message
 	KeyError   = "error"   // produced by Error
 	KeyStack   = "stack"   // used by Printkv to print stack on subsequent lines
+	KeyLevel   = "level"   // severity of the entry, e.g. "debug"; written by the KV, Logfmt, and JSON formats
 
 	keyLogError = "log-error" // for errors produced by the log package itself
 )
@@ -57,12 +90,26 @@ const (
 // SetOutput sets the log output to w.
 // If SetOutput hasn't been called,
 // the default behavior is to write to stdout.
+// It is safe to call concurrently with logging:
+// each entry is written entirely to either
+// the old output or w.
+// As io.Writer requires, w must not retain
+// the entries passed to Write, whose memory is reused.
 func SetOutput(w io.Writer) {
 	logWriterMu.Lock()
 	logWriter = w
 	logWriterMu.Unlock()
 }
 
+// Output returns the log output set by SetOutput.
+// Tests that redirect the log can use it
+// to restore the previous output.
+func Output() io.Writer {
+	logWriterMu.Lock()
+	defer logWriterMu.Unlock()
+	return logWriter
+}
+
 func appendPrefix(b []byte, keyval ...interface{}) []byte {
 	// Invariant: len(keyval) is always even.
 	if len(keyval)%2 != 0 {
@@ -79,14 +126,28 @@ func appendPrefix(b []byte, keyval ...interface{}) []byte {
 	return b
 }
 
-// SetPrefix sets the global output prefix.
+// SetPrefix sets the global output prefix: fields
+// written at the start of every entry, in every format,
+// before any prefix stored in the context.
+// It is meant for static fields identifying the process,
+// such as its host name, process ID, and build version,
+// so that entries from several processes can be told apart
+// once they are collected together.
+// Each call replaces the previous prefix.
 func SetPrefix(keyval ...interface{}) {
-	b := appendPrefix(nil, keyval...)
+	p := newFieldPrefix(keyval...)
 	logWriterMu.Lock()
-	procPrefix = b
+	procPrefix = p
 	logWriterMu.Unlock()
 }
 
+// processPrefix returns the prefix set by SetPrefix.
+func processPrefix() fieldPrefix {
+	logWriterMu.Lock()
+	defer logWriterMu.Unlock()
+	return procPrefix
+}
+
 // AddPrefixkv appends keyval to any prefix stored in ctx,
 // and returns a new context with the longer prefix.
 func AddPrefixkv(ctx context.Context, keyval ...interface{}) context.Context {
@@ -94,18 +155,46 @@ func AddPrefixkv(ctx context.Context, keyval ...interface{}) context.Context {
 	// Note: subsequent calls will append to p, so set cap(p) here.
 	// See TestAddPrefixkvAppendTwice.
 	p = p[0:len(p):len(p)]
+	ctx = context.WithValue(ctx, prefixFieldsKey, appendFields(prefixFields(ctx), keyval...))
 	return context.WithValue(ctx, prefixKey, p)
 }
 
+// With returns a new context with keyval appended to any
+// fields stored in ctx by With. Entries logged with the new
+// context have those fields following their own, so fields
+// such as an account ID needn't be passed to every call.
+// Unlike a prefix from AddPrefixkv, the values are formatted
+// for each entry, and filters and hooks see them.
+// Odd-length keyval is treated as in Printkv.
+func With(ctx context.Context, keyval ...interface{}) context.Context {
+	if len(keyval)%2 != 0 {
+		keyval = append(keyval, "", keyLogError, "odd number of log params")
+	}
+	w := withFields(ctx)
+	return context.WithValue(ctx, withFieldsKey, append(w[:len(w):len(w)], keyval...))
+}
+
+// withFields returns the fields stored in ctx by With.
+func withFields(ctx context.Context) []interface{} {
+	a, _ := ctx.Value(withFieldsKey).([]interface{})
+	return a
+}
+
 func prefix(ctx context.Context) []byte {
 	b, _ := ctx.Value(prefixKey).([]byte)
 	return b
 }
 
+func prefixFields(ctx context.Context) []interface{} {
+	a, _ := ctx.Value(prefixFieldsKey).([]interface{})
+	return a
+}
+
 // Printkv prints a structured log entry to stdout. Log fields are
 // specified as a variadic sequence of alternating keys and values.
 //
-// Duplicate keys will be preserved.
+// Duplicate keys will be preserved, unless SetDuplicateKeys
+// says otherwise.
 //
 // Two fields are automatically added to the log entry: t=[time]
 // and at=[file:line] indicating the location of the caller.
@@ -118,21 +207,289 @@ func prefix(ctx context.Context) []byte {
 //   - a KeyStack value with type []byte or *runtime.Frames
 //   - a KeyError value with type error, using the result of errors.Stack
 func Printkv(ctx context.Context, keyvals ...interface{}) {
+	printkv(ctx, caller, "", keyvals)
+}
+
+// printkv is Printkv, with the caller's function name
+// and location provided by where.
+// If sev is empty, the entry's severity is inferred from keyvals.
+func printkv(ctx context.Context, where func() (fn, loc string), sev string, keyvals []interface{}) {
+	printkvLevel(ctx, where, sev, nil, keyvals)
+}
+
+// printkvLevel is printkv, but if min is not nil,
+// it replaces the package-level minimum level.
+// SetVModule rules still take precedence.
+func printkvLevel(ctx context.Context, where func() (fn, loc string), sev string, min *Level, keyvals []interface{}) {
 	// Invariant: len(keyvals) is always even.
-	if len(keyvals)%2 != 0 {
+	logError := len(keyvals)%2 != 0
+	if logError {
 		keyvals = append(keyvals, "", keyLogError, "odd number of log params")
 	}
+	if w := withFields(ctx); len(w) > 0 {
+		keyvals = append(keyvals[:len(keyvals):len(keyvals)], w...)
+	}
+	hooked, keep := runHooks(ctx, keyvals)
+	if !keep {
+		if sev == "" {
+			sev = severity(keyvals)
+		}
+		countDrop(dropHook, sev)
+		return
+	}
+	keyvals = hooked
+	if sev == "" {
+		sev = severity(keyvals)
+	}
+	var fn, loc string
+	ok := enabled(sev)
+	if min != nil {
+		ok = levelOf(sev) >= *min
+	}
+	if vr := currentVModule(); len(vr.rules) > 0 {
+		fn, loc = where()
+		if vmin, matched := vr.level(fn); matched {
+			ok = levelOf(sev) >= vmin
+		}
+	}
+	if !ok {
+		if sev == severityDebug && debugBufferEnabled() && bufferDebug(ctx, where, keyvals) {
+			return
+		}
+		countDrop(dropLevel, sev)
+		return
+	}
+	if filtered(keyvals) {
+		countDrop(dropFilter, sev)
+		return
+	}
 
 	t := time.Now().UTC()
+	if fn == "" {
+		fn, loc = where()
+	}
+	if rateLimitEnabled() {
+		if ok, first := allowRequest(requestID(ctx), t); !ok {
+			countDrop(dropRate, sev)
+			if first {
+				writeSuppressed(ctx, t, loc)
+			}
+			return
+		}
+	}
+	if sev != severityError && samplingEnabled() {
+		keep, rate := sample(t, fn+" "+loc)
+		if !keep {
+			countDrop(dropSample, sev)
+			return
+		}
+		if rate > 0 {
+			keyvals = append(keyvals[:len(keyvals):len(keyvals)], KeySampleRate, rate)
+		}
+	}
+	keyvals = resolveValuers(keyvals)
+	if dedupEnabled() && dedup(ctx, sev, t, loc, keyvals) {
+		countDrop(dropRepeat, sev)
+		return
+	}
+	countEntry(sev, logError)
+	if sev == severityError {
+		recordError(t, fn, loc, keyvals)
+		if debugBufferEnabled() {
+			flushDebug(requestID(ctx))
+		}
+	}
+
+	buf := getBuffer()
+	encodeEntry(buf, ctx, currentFormat(), processPrefix(), sev, t, loc, keyvals)
+	writeEntry(t, sev, buf.Bytes())
+	putBuffer(buf)
+}
 
-	// Prepend the log entry with auto-generated fields.
-	out := fmt.Sprintf(
-		"%s=%s %s=%s",
-		KeyCaller, caller(),
-		KeyTime, formatValue(t.Format(rfc3339NanoFixed)),
-	)
+// writeEntry writes entry, the formatted text of an entry
+// with severity sev, to the log output,
+// or to a shard if SetWriteShards enabled them.
+func writeEntry(t time.Time, sev string, entry []byte) {
+	if shardEntry(t, sev, entry) {
+		return
+	}
+	logWriterMu.Lock()
+	writeEntryLocked(t, sev, entry)
+	logWriterMu.Unlock()
+}
 
-	var stack interface{}
+// writeEntryLocked writes entry to the log output.
+// The caller must hold logWriterMu.
+func writeEntryLocked(t time.Time, sev string, entry []byte) {
+	n, err := logWriter.Write(entry)
+	recordWrite(n, err)
+	recordSize(len(entry))
+	alert, vol := recordVolume(t, len(entry))
+	if err == ErrQueueFull {
+		countDrop(dropQueue, sev)
+	} else if err != nil {
+		countDrop(dropWrite, sev)
+	}
+	if alert != nil {
+		go alert(vol)
+	}
+}
+
+// maxPooledBuffer is the capacity beyond which an entry's
+// buffer is not reused, so that one huge entry doesn't
+// pin its memory for good.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer for encoding an entry.
+// The caller returns it with putBuffer once the entry is written.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// formatEntry returns the text of an entry with severity sev
+// and the given fields, encoded in format f,
+// preceded by outer, ctx's prefix, and the auto-generated fields,
+// and followed by its stack trace, if any.
+// Valuer values are resolved.
+// If encoding a value panics, as by a faulty String
+// or Error method, the entry is encoded without it.
+func formatEntry(ctx context.Context, f Format, outer fieldPrefix, sev string, t time.Time, loc string, keyvals []interface{}) []byte {
+	var buf bytes.Buffer
+	encodeEntry(&buf, ctx, f, outer, sev, t, loc, keyvals)
+	return buf.Bytes()
+}
+
+// encodeEntry appends the text of an entry, as formatEntry
+// returns it, to buf, writing the whole entry into buf at once
+// so sinks see (and can account for) one write per entry.
+func encodeEntry(buf *bytes.Buffer, ctx context.Context, f Format, outer fieldPrefix, sev string, t time.Time, loc string, keyvals []interface{}) {
+	n := buf.Len()
+	defer func() {
+		if r := recover(); r != nil {
+			safe, errs := safeFields(keyvals)
+			pre, preErrs := safeFields(prefixFields(ctx))
+			errs = append(errs, preErrs...)
+			if len(errs) == 0 {
+				panic(r) // not from a value
+			}
+			buf.Truncate(n)
+			ctx = context.WithValue(ctx, prefixFieldsKey, pre)
+			encodeEntry(buf, ctx, f, outer, sev, t, loc, append(safe[:len(safe):len(safe)], errs...))
+		}
+	}()
+	fields, stack := splitStack(resolveValuers(keyvals))
+	fields = uniqueKeys(fields)
+
+	switch f {
+	case Console:
+		buf.Write(outer.text)
+		formatConsole(buf, prefix(ctx), sev, t, loc, fields)
+	case Dev:
+		buf.Write(outer.text)
+		formatDev(buf, prefix(ctx), sev, t, loc, fields)
+	case JSON, Logfmt, GELF, CEF, LEEF, OTLP, Proto, RFC5424, Journal:
+		pre := appendFields(outer.fields, prefixFields(ctx)...)
+		encodeStructured(buf, f, pre, sev, t, loc, fields, stack)
+		return
+	default:
+		buf.Write(outer.text)
+		formatKV(buf, prefix(ctx), sev, t, loc, fields)
+	}
+	writeRawStack(buf, stack)
+}
+
+// encodeStructured appends an entry to buf in f, one of
+// the formats that take the prefix as fields, pre,
+// rather than as K=V text.
+func encodeStructured(buf *bytes.Buffer, f Format, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	switch f {
+	case JSON:
+		formatJSON(buf, pre, sev, t, loc, fields, stack)
+	case Logfmt:
+		formatLogfmt(buf, pre, sev, t, loc, fields, stack)
+	case GELF:
+		formatGELF(buf, pre, sev, t, loc, fields, stack)
+	case CEF, LEEF:
+		formatSIEM(buf, f, pre, sev, t, loc, fields)
+	case OTLP:
+		formatOTLP(buf, pre, sev, t, loc, fields, stack)
+	case Proto:
+		formatProto(buf, pre, sev, t, loc, fields, stack)
+	case RFC5424:
+		formatRFC5424(buf, pre, sev, t, loc, fields)
+	case Journal:
+		formatJournal(buf, pre, sev, t, loc, fields, stack)
+	}
+}
+
+// encodeFields appends the text of an entry with the given
+// typed fields to buf, as encodeEntry would for their values,
+// in format f, which must be KV, Logfmt, or JSON.
+// Each field's value is written by its kind, without
+// converting it to interface{}. As in splitStack,
+// KeyLevel fields are left out.
+func encodeFields(buf *bytes.Buffer, ctx context.Context, f Format, outer fieldPrefix, sev string, t time.Time, loc string, fields []Field) {
+	switch f {
+	case JSON:
+		startJSON(buf, appendFields(outer.fields, prefixFields(ctx)...), sev, t, loc)
+		for _, fl := range fields {
+			if fl.Key == KeyLevel {
+				continue
+			}
+			writeJSONString(buf, formatKeyString(fl.Key))
+			buf.WriteByte(':')
+			fl.writeValue(buf, f)
+			buf.WriteByte(',')
+		}
+		buf.Truncate(buf.Len() - 1) // trailing comma
+		buf.WriteString("}\n")
+	case Logfmt:
+		startLogfmt(buf, appendFields(outer.fields, prefixFields(ctx)...), sev, t, loc)
+		for _, fl := range fields {
+			if fl.Key == KeyLevel {
+				continue
+			}
+			buf.WriteString(logfmtKeyString(fl.Key))
+			buf.WriteByte('=')
+			fl.writeValue(buf, f)
+			buf.WriteByte(' ')
+		}
+		buf.Truncate(buf.Len() - 1) // trailing space
+		buf.WriteByte('\n')
+	default:
+		buf.Write(outer.text)
+		startKV(buf, prefix(ctx), sev, t, loc)
+		for _, fl := range fields {
+			if fl.Key == KeyLevel {
+				continue
+			}
+			buf.WriteByte(' ')
+			buf.WriteString(formatKeyString(fl.Key))
+			buf.WriteByte('=')
+			fl.writeValue(buf, f)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// splitStack returns keyvals without any KeyStack field,
+// or KeyLevel field, which formatEntry writes from the
+// entry's severity, and the stack trace to print
+// following the entry, if any.
+func splitStack(keyvals []interface{}) (fields []interface{}, stack interface{}) {
+	fields = make([]interface{}, 0, len(keyvals))
 	for i := 0; i < len(keyvals); i += 2 {
 		k := keyvals[i]
 		v := keyvals[i+1]
@@ -140,26 +497,36 @@ func Printkv(ctx context.Context, keyvals ...interface{}) {
 			stack = v
 			continue
 		}
+		if k == KeyLevel {
+			continue
+		}
 		if k == KeyError {
 			if e, ok := v.(error); ok && stack == nil {
 				stack = errors.Stack(errors.Wrap(e)) // wrap to ensure callstack
 			}
 		}
-		out += " " + formatKey(k) + "=" + formatValue(v)
+		fields = append(fields, k, v)
 	}
+	return fields, stack
+}
 
-	logWriterMu.Lock()
-	logWriter.Write(procPrefix)
-	logWriter.Write(prefix(ctx))
-	logWriter.Write([]byte(out)) // ignore errors
-	logWriter.Write([]byte{'\n'})
-	writeRawStack(logWriter, stack)
-	logWriterMu.Unlock()
+// formatKV writes an entry as Splunk-style K=V pairs.
+func formatKV(buf *bytes.Buffer, prefix []byte, sev string, t time.Time, loc string, fields []interface{}) {
+	startKV(buf, prefix, sev, t, loc)
+	for i := 0; i < len(fields); i += 2 {
+		buf.WriteByte(' ')
+		writeKV(buf, fields[i], fields[i+1])
+	}
+	buf.WriteByte('\n')
 }
 
 // Fatalkv is equivalent to Printkv() followed by a call to os.Exit(1).
+// It flushes the log output before exiting; see Flush.
+// It also dumps the ring registered with SetRing; see DumpRing.
 func Fatalkv(ctx context.Context, keyvals ...interface{}) {
 	Printkv(ctx, keyvals...)
+	Flush()
+	DumpRing()
 	os.Exit(1)
 }
 
@@ -189,31 +556,87 @@ func isStackVal(v interface{}) bool {
 
 // Printf prints a log entry containing a message assigned to the
 // "message" key. Arguments are handled as in fmt.Printf.
+// The entry is at LevelInfo.
 func Printf(ctx context.Context, format string, a ...interface{}) {
 	Printkv(ctx, KeyMessage, fmt.Sprintf(format, a...))
 }
 
+// Debugf is like Printf, but the entry is at LevelDebug,
+// so it is discarded unless enabled by SetLevel or SetVModule.
+func Debugf(ctx context.Context, format string, a ...interface{}) {
+	if !enabled(severityDebug) && len(currentVModule().rules) == 0 && !debugBufferEnabled() {
+		countDrop(dropLevel, severityDebug) // without formatting the message
+		return
+	}
+	printkv(ctx, caller, severityDebug, []interface{}{KeyMessage, fmt.Sprintf(format, a...)})
+}
+
+// Warnf is like Printf, but the entry is at LevelWarning,
+// with the message assigned to the "warning" key.
+func Warnf(ctx context.Context, format string, a ...interface{}) {
+	printkv(ctx, caller, severityWarning, []interface{}{"warning", fmt.Sprintf(format, a...)})
+}
+
 // Error prints a log entry containing an error message assigned to the
-// "error" key.
+// "error" key. The entry is at LevelError.
 // Optionally, an error message prefix can be included. Prefix arguments are
 // handled as in fmt.Print.
 func Error(ctx context.Context, err error, a ...interface{}) {
+	Printkv(ctx, KeyError, errorWithPrefix(err, a...))
+}
+
+// errorWithPrefix returns err with the message prefix a,
+// if any, keeping err's stack.
+func errorWithPrefix(err error, a ...interface{}) error {
 	if _, hasStack := errors.Stack(err).Next(); len(a) > 0 && hasStack {
-		err = errors.Wrap(err, a...) // keep err's stack
+		return errors.Wrap(err, a...) // keep err's stack
 	} else if len(a) > 0 {
-		err = fmt.Errorf("%s: %s", fmt.Sprint(a...), err) // don't add a stack here
+		return fmt.Errorf("%s: %s", fmt.Sprint(a...), err) // don't add a stack here
+	}
+	return err
+}
+
+// startKV writes the prefix and auto-generated
+// fields of a K=V entry.
+func startKV(buf *bytes.Buffer, prefix []byte, sev string, t time.Time, loc string) {
+	buf.Write(prefix)
+	buf.WriteString(KeyCaller + "=")
+	buf.WriteString(loc)
+	buf.WriteString(" " + KeyTime + "=")
+	buf.WriteString(quoteValue(formatTime(t)))
+	buf.WriteString(" " + KeyLevel + "=")
+	buf.WriteString(sev)
+}
+
+// writeKV writes a K=V pair, as formatKey
+// and formatValue format it, to buf.
+func writeKV(buf *bytes.Buffer, k, v interface{}) {
+	buf.WriteString(formatKey(k))
+	buf.WriteByte('=')
+	if !writeInteger(buf, v) {
+		buf.WriteString(formatValue(v))
 	}
-	Printkv(ctx, KeyError, err)
 }
 
 // formatKey ensures that the stringified key is valid for use in a
 // Splunk-style K=V format. It stubs out delimeter and quoter characters in
 // the key string with hyphens.
 func formatKey(k interface{}) string {
-	s := fmt.Sprint(k)
+	s, ok := k.(string)
+	if !ok {
+		s = fmt.Sprint(k)
+	}
+	return formatKeyString(s)
+}
+
+// formatKeyString is formatKey for a string key.
+func formatKeyString(s string) string {
 	if s == "" {
 		return "?"
 	}
+	if !strings.ContainsAny(s, illegalKeyChars) {
+		return s
+	}
 
 	for _, c := range illegalKeyChars {
 		s = strings.Replace(s, string(c), "-", -1)
@@ -226,7 +649,15 @@ func formatKey(k interface{}) string {
 // Splunk-style K=V format. It quotes the string value if delimeter or quoter
 // characters are present in the value string.
 func formatValue(v interface{}) string {
-	s := fmt.Sprint(v)
+	if isNullString(v) {
+		return strconv.Quote(nullValue)
+	}
+	return quoteValue(valueString(v))
+}
+
+// quoteValue quotes s, the text of a value,
+// as formatValue does.
+func quoteValue(s string) string {
 	if strings.ContainsAny(s, pairDelims) {
 		return strconv.Quote(s)
 	}
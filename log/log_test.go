@@ -3,21 +3,25 @@ package log
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"chain/errors"
+	"chain/log/internal/capture"
 )
 
 func TestSetOutput(t *testing.T) {
 	var buf bytes.Buffer
 	want := "foobar"
 	SetOutput(&buf)
-	Printf(context.Background(), want)
+	Printf(context.Background(), "%s", want)
 	SetOutput(os.Stdout)
 	got := buf.String()
 	if !strings.Contains(got, want) {
@@ -25,6 +29,38 @@ func TestSetOutput(t *testing.T) {
 	}
 }
 
+func TestOutput(t *testing.T) {
+	old := Output()
+	defer SetOutput(old)
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	if got := Output(); got != &buf {
+		t.Errorf("Output() = %v want %v", got, &buf)
+	}
+
+	// Swapping outputs while logging must not race
+	// or split entries between outputs.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			Printkv(context.Background(), "i", i)
+		}
+		close(done)
+	}()
+	var buf2 bytes.Buffer
+	SetOutput(&buf2)
+	<-done
+	SetOutput(old)
+	for _, b := range []*bytes.Buffer{&buf, &buf2} {
+		for _, line := range strings.SplitAfter(b.String(), "\n") {
+			if line != "" && !strings.HasSuffix(line, "\n") {
+				t.Errorf("partial entry %q", line)
+			}
+		}
+	}
+}
+
 func TestNoExtraFormatDirectives(t *testing.T) {
 	buf := new(bytes.Buffer)
 	SetOutput(buf)
@@ -50,9 +86,20 @@ func TestPrefix(t *testing.T) {
 		t.Errorf("output = %q want prefix %q", got, wantPrefix)
 	}
 
+	buf.Reset()
+	SetOutput(buf)
+	SetFormat(JSON)
+	SetPrefix("hostname", "h1", "pid", 42)
+	Printkv(context.Background(), "baz", 1)
+	SetFormat(KV)
+	SetOutput(os.Stdout)
+	if got, w := buf.String(), `{"hostname":"h1","pid":42,`; !strings.HasPrefix(got, w) {
+		t.Errorf("output = %q want prefix %q", got, w)
+	}
+
 	SetPrefix()
-	if procPrefix != nil {
-		t.Errorf("procPrefix = %q want nil", procPrefix)
+	if procPrefix.text != nil || procPrefix.fields != nil {
+		t.Errorf("procPrefix = %+v want zero", procPrefix)
 	}
 }
 
@@ -102,6 +149,35 @@ func TestAddPrefixkvAppendTwice(t *testing.T) {
 	}
 }
 
+func TestWith(t *testing.T) {
+	out := captureOutput(t)
+
+	ctx := With(context.Background(), "account_id", "acc1")
+	ctx2a := With(ctx, "n", 1)
+	ctx2b := With(ctx, "n", 2)
+	Printkv(ctx2a, "message", "a")
+	Printkv(ctx2b, "message", "b")
+	New(out).Printkv(ctx, "message", "c")
+
+	want := []capture.Entry{
+		{KeyMessage: "a", "account_id": "acc1", "n": "1"},
+		{KeyMessage: "b", "account_id": "acc1", "n": "2"},
+		{KeyMessage: "c", "account_id": "acc1"},
+	}
+	entries := out.Entries()
+	if len(entries) != len(want) {
+		t.Fatalf("entries = %v, want %d", entries, len(want))
+	}
+	for i, w := range want {
+		if !entries[i].Matches(w) {
+			t.Errorf("entry %d = %v, want %v", i, entries[i], w)
+		}
+	}
+	if _, ok := entries[2]["n"]; ok {
+		t.Errorf("entry c = %v, want no n from the derived contexts", entries[2])
+	}
+}
+
 func TestPrintkv(t *testing.T) {
 	examples := []struct {
 		keyvals []interface{}
@@ -357,7 +433,7 @@ func TestFormatValue(t *testing.T) {
 		{1.5, "1.5"},
 		{true, "true"},
 		{errors.New("this is an error"), `"this is an error"`},
-		{[]byte{'a', 'b', 'c'}, `"[97 98 99]"`},
+		{[]byte{'a', 'b', 'c'}, "616263"},
 		{bytes.NewBuffer([]byte{'a', 'b', 'c'}), "abc"},
 		{"a b\"c\nd;e\tf龜g", `"a b\"c\nd;e\tf龜g"`},
 	}
@@ -370,3 +446,42 @@ func TestFormatValue(t *testing.T) {
 		}
 	}
 }
+
+func BenchmarkPrintkv(b *testing.B) {
+	defer SetOutput(Output())
+	SetOutput(ioutil.Discard)
+	ctx := AddPrefixkv(context.Background(), "reqid", "r1")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Printkv(ctx, "height", i, "hash", "a1b2c3", "ok", true, KeyMessage, "made block")
+	}
+}
+
+func BenchmarkFormatKV(b *testing.B) {
+	fields := []interface{}{"height", 7, "hash", "a1b2c3", "ok", true, KeyMessage, "made block"}
+	t := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := getBuffer()
+		formatKV(buf, nil, severityInfo, t, "a.go:1", fields)
+		putBuffer(buf)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	for i := 0; i < 2; i++ { // the second time, from the cache
+		fn, loc := caller()
+		_, file, line, _ := runtime.Caller(0)
+		want := fmt.Sprintf("%s:%d", filepath.Base(file), line-1)
+		if fn != "chain/log.TestCaller" || loc != want {
+			t.Errorf("caller() = %s, %s want chain/log.TestCaller, %s", fn, loc, want)
+		}
+	}
+}
+
+func BenchmarkCaller(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		caller()
+	}
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// formatLogfmt writes an entry as K=V pairs, as formatKV,
+// but quoting every value that a strict logfmt parser
+// could misread: any value that is empty or contains
+// a space, '=', '"', a control character, or non-ASCII text.
+// Keys are restricted to printable ASCII, excluding '=' and '"'.
+// The stack trace, if any, is the quoted value of KeyStack,
+// so the entry stays on one line.
+func formatLogfmt(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	startLogfmt(buf, pre, sev, t, loc)
+	for i := 0; i < len(fields); i += 2 {
+		writeLogfmtPair(buf, fields[i], fields[i+1])
+	}
+	if stack != nil {
+		var b bytes.Buffer
+		writeRawStack(&b, stack)
+		if b.Len() > 0 {
+			writeLogfmtPair(buf, KeyStack, strings.TrimSuffix(b.String(), "\n"))
+		}
+	}
+	buf.Truncate(buf.Len() - 1) // trailing space
+	buf.WriteByte('\n')
+}
+
+// startLogfmt writes the prefix fields and
+// auto-generated fields of a logfmt entry.
+func startLogfmt(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string) {
+	for i := 0; i < len(pre); i += 2 {
+		writeLogfmtPair(buf, pre[i], pre[i+1])
+	}
+	writeLogfmtStringPair(buf, KeyCaller, loc)
+	writeLogfmtStringPair(buf, KeyTime, formatTime(t))
+	writeLogfmtStringPair(buf, KeyLevel, sev)
+}
+
+// writeLogfmtStringPair is writeLogfmtPair for a string key and value.
+func writeLogfmtStringPair(buf *bytes.Buffer, k, v string) {
+	buf.WriteString(logfmtKeyString(k))
+	buf.WriteByte('=')
+	buf.WriteString(logfmtString(v))
+	buf.WriteByte(' ')
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, k, v interface{}) {
+	buf.WriteString(logfmtKey(k))
+	buf.WriteByte('=')
+	if !writeInteger(buf, v) {
+		buf.WriteString(logfmtValue(v))
+	}
+	buf.WriteByte(' ')
+}
+
+func logfmtKey(k interface{}) string {
+	s, ok := k.(string)
+	if !ok {
+		s = fmt.Sprint(k)
+	}
+	return logfmtKeyString(s)
+}
+
+// logfmtKeyString is logfmtKey for a string key.
+func logfmtKeyString(s string) string {
+	if s == "" {
+		return "?"
+	}
+	if strings.IndexFunc(s, func(r rune) bool { return !isLogfmtSafe(r) }) < 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if !isLogfmtSafe(r) {
+			return '-'
+		}
+		return r
+	}, s)
+}
+
+func logfmtValue(v interface{}) string {
+	if isNullString(v) {
+		return strconv.Quote(nullValue)
+	}
+	return logfmtQuote(valueString(v))
+}
+
+// logfmtString returns the logfmt text of the string value s.
+func logfmtString(s string) string {
+	if s == nullValue {
+		return strconv.Quote(s)
+	}
+	return logfmtQuote(s)
+}
+
+// logfmtQuote quotes s, the text of a value,
+// as logfmtValue does.
+func logfmtQuote(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return !isLogfmtSafe(r) }) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// isLogfmtSafe reports whether r can appear unquoted
+// in a strict logfmt key or value.
+func isLogfmtSafe(r rune) bool {
+	return r > ' ' && r < utf8.RuneSelf && r != 0x7f && r != '=' && r != '"'
+}
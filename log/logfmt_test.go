@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"chain/errors"
+)
+
+func TestFormatLogfmt(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	cases := []struct {
+		pre    []interface{}
+		fields []interface{}
+		want   string
+	}{
+		{nil, nil, `at=a.go:1 t=2017-03-01T13:04:05.006000000Z level=info`},
+		{[]interface{}{"reqid", "r1"}, []interface{}{"n", 7}, `reqid=r1 at=a.go:1 t=2017-03-01T13:04:05.006000000Z level=info n=7`},
+		{nil, []interface{}{"q", "a=b", "e", "", "c", "x\x01y", "u", "héllo", "s", "it's"}, `at=a.go:1 t=2017-03-01T13:04:05.006000000Z level=info q="a=b" e="" c="x\x01y" u="héllo" s=it's`},
+		{nil, []interface{}{"a b", 1, `k="`, 2, "", 3, "ké", 4}, `at=a.go:1 t=2017-03-01T13:04:05.006000000Z level=info a-b=1 k--=2 ?=3 k-=4`},
+	}
+	for _, c := range cases {
+		buf := new(bytes.Buffer)
+		formatLogfmt(buf, c.pre, severityInfo, ts, "a.go:1", c.fields, nil)
+		if got := buf.String(); got != c.want+"\n" {
+			t.Errorf("formatLogfmt(%v) = %q want %q", c.fields, got, c.want+"\n")
+		}
+	}
+}
+
+func TestSetFormatLogfmt(t *testing.T) {
+	out := captureOutput(t)
+	SetFormat(Logfmt)
+	defer SetFormat(KV)
+
+	ctx := AddPrefixkv(context.Background(), "path", "/a=b")
+	Printkv(ctx, KeyError, errors.New("boom"))
+	got := out.String()
+	if !strings.HasPrefix(got, `path="/a=b" at=logfmt_test.go:`) {
+		t.Errorf("entry = %q want quoted prefix", got)
+	}
+	entries := out.Entries()
+	if strings.Count(got, "\n") != 1 || len(entries) != 1 || !strings.Contains(entries[0][KeyStack], "TestSetFormatLogfmt") {
+		t.Errorf("entry = %q want one line with quoted stack", got)
+	}
+}
@@ -0,0 +1,154 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A Logger writes entries to its own output, with its own
+// configuration, independently of the package-level functions
+// and their settings. Its methods are safe for concurrent use.
+//
+// Entries written with a Logger are formatted as in Printkv,
+// including any prefix stored in the context,
+// but are not included in the package's counters.
+//
+// The exceptions are Default, and named loggers with no
+// output of their own, and loggers derived from them with With,
+// which write as the package-level functions do.
+type Logger struct {
+	std    bool    // for the logger returned by Default
+	name   string  // for loggers returned by Named
+	out    *output // shared with loggers derived by With
+	prefix fieldPrefix
+	fields []interface{} // bound by With
+	level  Level
+	format Format
+}
+
+type output struct {
+	mu sync.Mutex // protects w
+	w  io.Writer
+}
+
+// An Option configures a Logger.
+type Option func(*Logger)
+
+// MinLevel sets the minimum severity of entries the Logger writes.
+// The default is LevelInfo.
+func MinLevel(l Level) Option {
+	return func(lg *Logger) { lg.level = l }
+}
+
+// EntryFormat sets the encoding of the Logger's entries.
+// The default is KV.
+func EntryFormat(f Format) Option {
+	return func(lg *Logger) { lg.format = f }
+}
+
+// Prefix sets fields written at the start of every
+// entry, before any prefix stored in the context.
+// It is the equivalent of SetPrefix for a Logger.
+func Prefix(keyval ...interface{}) Option {
+	return func(lg *Logger) { lg.prefix = newFieldPrefix(keyval...) }
+}
+
+// New returns a Logger that writes to w.
+func New(w io.Writer, opts ...Option) *Logger {
+	lg := &Logger{out: &output{w: w}}
+	for _, opt := range opts {
+		opt(lg)
+	}
+	return lg
+}
+
+// Default returns a Logger that writes with the package-level
+// output and settings, exactly as the package-level functions,
+// so that a long-lived subsystem can bind identifying fields
+// once with With:
+//
+//	lg := log.Default().With("component", "generator")
+//	lg.Printf(ctx, "made block %d", height)
+func Default() *Logger {
+	return &Logger{std: true}
+}
+
+// Printkv writes a structured log entry, as the package-level Printkv.
+func (lg *Logger) Printkv(ctx context.Context, keyvals ...interface{}) {
+	if lg.std {
+		printkv(ctx, caller, "", append(lg.fields[:len(lg.fields):len(lg.fields)], keyvals...))
+		return
+	}
+	level, out, format := lg.level, lg.out, lg.format
+	if lg.name != "" {
+		level, out = namedConfig(lg.name)
+		format = currentFormat()
+		if out == nil {
+			// A named logger with no output of its own
+			// writes as the package-level functions do,
+			// at its own level.
+			printkvLevel(ctx, caller, "", &level, append(lg.fields[:len(lg.fields):len(lg.fields)], keyvals...))
+			return
+		}
+	}
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "", keyLogError, "odd number of log params")
+	}
+	if len(lg.fields) > 0 {
+		keyvals = append(lg.fields[:len(lg.fields):len(lg.fields)], keyvals...)
+	}
+	if w := withFields(ctx); len(w) > 0 {
+		keyvals = append(keyvals[:len(keyvals):len(keyvals)], w...)
+	}
+	keyvals, keep := runHooks(ctx, keyvals)
+	if !keep {
+		return
+	}
+	sev := severity(keyvals)
+	if levelOf(sev) < level || filtered(keyvals) {
+		return
+	}
+	t := time.Now().UTC()
+	_, loc := caller()
+
+	buf := getBuffer()
+	encodeEntry(buf, ctx, format, lg.prefix, sev, t, loc, keyvals)
+	out.mu.Lock()
+	out.w.Write(buf.Bytes())
+	out.mu.Unlock()
+	putBuffer(buf)
+}
+
+// With returns a Logger that writes to the same output as lg,
+// with the same configuration, and includes keyvals
+// in every entry, following the auto-generated fields.
+// Odd-length keyvals is treated as in Printkv.
+func (lg *Logger) With(keyvals ...interface{}) *Logger {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "", keyLogError, "odd number of log params")
+	}
+	child := *lg
+	child.fields = append(lg.fields[:len(lg.fields):len(lg.fields)], keyvals...)
+	return &child
+}
+
+// Printf writes an entry containing a message,
+// as the package-level Printf.
+func (lg *Logger) Printf(ctx context.Context, format string, a ...interface{}) {
+	lg.Printkv(ctx, KeyMessage, fmt.Sprintf(format, a...))
+}
+
+// Debugf writes an entry containing a message at LevelDebug,
+// as the package-level Debugf.
+func (lg *Logger) Debugf(ctx context.Context, format string, a ...interface{}) {
+	lg.Printkv(ctx, KeyLevel, severityDebug, KeyMessage, fmt.Sprintf(format, a...))
+}
+
+// Error writes an entry containing an error,
+// as the package-level Error.
+func (lg *Logger) Error(ctx context.Context, err error, a ...interface{}) {
+	lg.Printkv(ctx, KeyError, errorWithPrefix(err, a...))
+}
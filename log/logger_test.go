@@ -0,0 +1,99 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestLogger(t *testing.T) {
+	global := captureOutput(t)
+
+	out := new(capture.Recorder)
+	lg := New(out, Prefix("app", "test"), MinLevel(LevelWarning))
+
+	ctx := AddPrefixkv(context.Background(), "reqid", "r1")
+	lg.Printf(ctx, "hello")
+	lg.Printkv(ctx, "warning", "careful")
+	lg.Error(ctx, errors.New("boom"), "doing thing")
+
+	if got := global.String(); got != "" {
+		t.Errorf("global log = %q, want empty", got)
+	}
+	if w := "app=test reqid=r1 at=logger_test.go:"; !strings.HasPrefix(out.String(), w) {
+		t.Errorf("log = %q, want prefix %q", out, w)
+	}
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want info entry discarded", entries)
+	}
+	if entries[0]["warning"] != "careful" {
+		t.Errorf("entry = %v, want warning=careful", entries[0])
+	}
+	if entries[1][KeyError] != "doing thing: boom" {
+		t.Errorf("entry = %v, want error=%q", entries[1], "doing thing: boom")
+	}
+}
+
+func TestLoggerWith(t *testing.T) {
+	out := new(capture.Recorder)
+	lg := New(out)
+	query := lg.With("component", "query")
+	main := query.With("indexer", "main")
+
+	ctx := context.Background()
+	main.Printf(ctx, "indexed")
+	query.Printf(ctx, "searched")
+	lg.Printf(ctx, "plain")
+
+	entries := out.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v, want 3", entries)
+	}
+	wants := []capture.Entry{
+		{"component": "query", "indexer": "main", KeyMessage: "indexed"},
+		{"component": "query", KeyMessage: "searched"},
+		{KeyMessage: "plain"},
+	}
+	for i, w := range wants {
+		if !entries[i].Matches(w) {
+			t.Errorf("entry %d = %v, want %v", i, entries[i], w)
+		}
+	}
+	if _, ok := entries[2]["component"]; ok {
+		t.Errorf("entry = %v, want no bound fields from derived loggers", entries[2])
+	}
+}
+
+func TestDefault(t *testing.T) {
+	out := captureOutput(t)
+	SetLevel(LevelWarning)
+	defer SetLevel(LevelInfo)
+
+	entries0 := ReadState().Entries[severityWarning]
+	lg := Default().With("component", "generator")
+	ctx := context.Background()
+	lg.Printf(ctx, "hidden")
+	lg.Printkv(ctx, "warning", "late")
+	lg.With("height", 7).Error(ctx, errors.New("boom"))
+
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want the info entry discarded by SetLevel", entries)
+	}
+	wants := []capture.Entry{
+		{"component": "generator", "warning": "late"},
+		{"component": "generator", "height": "7", KeyError: "boom"},
+	}
+	for i, w := range wants {
+		if !entries[i].Matches(w) || !strings.HasPrefix(entries[i][KeyCaller], "logger_test.go:") {
+			t.Errorf("entry %d = %v, want %v at logger_test.go", i, entries[i], w)
+		}
+	}
+	if n := ReadState().Entries[severityWarning] - entries0; n != 1 {
+		t.Errorf("counted %d warnings, want 1", n)
+	}
+}
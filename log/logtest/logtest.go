@@ -0,0 +1,30 @@
+// Package logtest helps tests check the entries
+// written with package log.
+package logtest
+
+import (
+	"testing"
+
+	"chain/log"
+	"chain/log/internal/capture"
+)
+
+// A Recorder keeps the log entries written to it
+// and parses their fields. See Capture.
+type Recorder = capture.Recorder
+
+// An Entry holds the fields of one log entry, keyed by name.
+type Entry = capture.Entry
+
+// Capture sets the log output to a new Recorder
+// for the rest of the test, restoring the previous
+// output when the test and its subtests finish.
+// Tests that call Capture must not run in parallel
+// with other tests that log.
+func Capture(t testing.TB) *Recorder {
+	r := new(Recorder)
+	old := log.Output()
+	log.SetOutput(r)
+	t.Cleanup(func() { log.SetOutput(old) })
+	return r
+}
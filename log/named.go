@@ -0,0 +1,113 @@
+package log
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyLogger identifies the named logger that wrote an entry.
+const KeyLogger = "logger"
+
+// namedSettings is the configuration set for one logger name.
+type namedSettings struct {
+	level  *Level
+	output *output
+}
+
+var (
+	namedMu sync.RWMutex // protects named
+	named   = map[string]*namedSettings{}
+)
+
+// Named returns a Logger for the subsystem with the given name.
+// Names are hierarchical, with components separated by dots,
+// such as "core", "core.query", or "database.pg".
+// Every entry it writes includes a KeyLogger field
+// containing the name.
+//
+// Its level and output are those set with SetNamedLevel and
+// SetNamedOutput for the name or, failing that, for its
+// nearest ancestor. If none are set, it uses the
+// package-level settings.
+func Named(name string) *Logger {
+	return &Logger{name: name, fields: []interface{}{KeyLogger, name}}
+}
+
+// Named returns a Logger for the subsystem named
+// by appending "." and sub to lg's name.
+// Fields bound to lg with With are not inherited.
+// If lg is not a named logger, it is equivalent
+// to the package-level Named(sub).
+func (lg *Logger) Named(sub string) *Logger {
+	if lg.name == "" {
+		return Named(sub)
+	}
+	return Named(lg.name + "." + sub)
+}
+
+// SetNamedLevel sets the minimum severity of entries
+// written by the named logger and its descendants,
+// unless they have a level of their own.
+func SetNamedLevel(name string, l Level) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	settings(name).level = &l
+}
+
+// SetNamedOutput sets the output of the named logger
+// and its descendants, unless they have an output of their own.
+// Setting a nil w restores the inherited output.
+func SetNamedOutput(name string, w io.Writer) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+	if w == nil {
+		settings(name).output = nil
+		return
+	}
+	settings(name).output = &output{w: w}
+}
+
+// settings returns the settings for name, creating them if necessary.
+// The caller must hold namedMu for writing.
+func settings(name string) *namedSettings {
+	s := named[name]
+	if s == nil {
+		s = new(namedSettings)
+		named[name] = s
+	}
+	return s
+}
+
+// namedConfig returns the level and output in effect
+// for the named logger. A nil output means
+// the package-level output.
+func namedConfig(name string) (Level, *output) {
+	namedMu.RLock()
+	defer namedMu.RUnlock()
+	var (
+		level *Level
+		out   *output
+	)
+	for {
+		if s := named[name]; s != nil {
+			if level == nil {
+				level = s.level
+			}
+			if out == nil {
+				out = s.output
+			}
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 || (level != nil && out != nil) {
+			break
+		}
+		name = name[:i]
+	}
+	if level == nil {
+		l := Level(atomic.LoadInt32(&minLevel))
+		level = &l
+	}
+	return *level, out
+}
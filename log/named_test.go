@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chain/log/internal/capture"
+)
+
+func TestNamed(t *testing.T) {
+	global := captureOutput(t)
+
+	dbOut := new(capture.Recorder)
+	SetNamedOutput("test-db", dbOut)
+	SetNamedLevel("test-core", LevelWarning)
+	SetNamedLevel("test-core.query", LevelInfo)
+	defer func() {
+		namedMu.Lock()
+		delete(named, "test-db")
+		delete(named, "test-core")
+		delete(named, "test-core.query")
+		namedMu.Unlock()
+	}()
+
+	ctx := context.Background()
+	Named("test-core").Printf(ctx, "core info")                 // below level
+	Named("test-core").Named("query").Printf(ctx, "query info") // overridden level
+	Named("test-core.txdb").Printkv(ctx, "warning", "core warning")
+	Named("test-db.pg").Printf(ctx, "db info")
+
+	entries := global.Entries()
+	wants := []capture.Entry{
+		{KeyLogger: "test-core.query", KeyMessage: "query info"},
+		{KeyLogger: "test-core.txdb", "warning": "core warning"},
+	}
+	if len(entries) != len(wants) {
+		t.Fatalf("entries = %v, want core info discarded and db info routed elsewhere", entries)
+	}
+	for i, w := range wants {
+		if !entries[i].Matches(w) {
+			t.Errorf("entry %d = %v, want %v", i, entries[i], w)
+		}
+	}
+	w := capture.Entry{KeyLogger: "test-db.pg", KeyMessage: "db info"}
+	if got := dbOut.Entries(); len(got) != 1 || !got[0].Matches(w) {
+		t.Errorf("db entries = %v, want %v", got, w)
+	}
+}
+
+func TestNamedPipeline(t *testing.T) {
+	out := captureOutput(t)
+	SetRequestRateLimit(0.001, 2)
+	defer SetRequestRateLimit(0, 0)
+	SetDedup(time.Hour)
+	defer SetDedup(0)
+
+	lg := Named("test-pipeline")
+	ctx := AddPrefixkv(context.Background(), "reqid", "r1")
+	for i := 0; i < 3; i++ {
+		lg.Printkv(ctx, "warning", "slow")
+	}
+	for i := 0; i < 5; i++ {
+		lg.Printkv(ctx, "i", i)
+	}
+
+	// The first entry is written, and the two repeats within the
+	// burst are coalesced; after that, the request is limited.
+	entries := out.Find(KeyLogger, "test-pipeline")
+	if len(entries) != 1 || !entries[0].Matches(capture.Entry{"warning": "slow"}) {
+		t.Errorf("entries = %v, want one warning", out.Entries())
+	}
+	if got := out.Find("warning", "log entries suppressed: request exceeded its rate limit"); len(got) != 1 {
+		t.Errorf("entries = %v, want a suppression warning", out.Entries())
+	}
+}
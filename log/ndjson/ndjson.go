@@ -0,0 +1,116 @@
+// Package ndjson posts log entries, as newline-delimited JSON,
+// to an HTTP endpoint, such as a Loki or Datadog gateway
+// or a homegrown collector.
+package ndjson
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"chain/log/internal/batch"
+	"chain/sync/retry"
+)
+
+// MaxBackoff is the longest the writer waits
+// between attempts to post a batch.
+const MaxBackoff = 30 * time.Second
+
+// Config configures a writer that posts to an endpoint.
+type Config struct {
+	URL    string
+	Header http.Header // added to every request, e.g. for an API key
+	Gzip   bool        // compress request bodies
+}
+
+type writer struct {
+	c      Config
+	header http.Header
+	client *http.Client
+	w      *batch.Writer
+}
+
+// New creates a new writer that posts written entries
+// to the endpoint configured by c, one per line.
+// An entry formatted as a JSON object (see chain/log.JSON)
+// is sent as is; any other entry is sent
+// as the "log" field of an object.
+//
+// Writes never block: entries are queued and posted
+// in batches from a background goroutine, and dropped
+// if too many are waiting. Once a batch is dequeued,
+// delivery is at least once: it is retried,
+// with backoff up to MaxBackoff, until the endpoint
+// accepts it, unless the endpoint rejects it
+// with a 4xx status other than 429.
+// Later entries wait in the queue meanwhile.
+// Entries still queued when the process exits are lost,
+// unless the writer is flushed first, as chain/log.Flush
+// (and so chain/log.Fatalkv) does for the log output.
+func New(c Config) io.Writer {
+	header := http.Header{"Content-Type": {"application/x-ndjson"}}
+	for k, v := range c.Header {
+		header[k] = v
+	}
+	if c.Gzip {
+		header.Set("Content-Encoding", "gzip")
+	}
+	w := &writer{
+		c:      c,
+		header: header,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	w.w = batch.New(w.send)
+	return w
+}
+
+// Write queues a copy of p without blocking.
+// It is sent as a JSON object, as New describes.
+func (w *writer) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+// Flush posts the queued entries, waiting at most batch.FlushTimeout.
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close posts the queued entries and stops the background goroutine.
+func (w *writer) Close() error {
+	return w.w.Close()
+}
+
+func (w *writer) send(entries [][]byte) {
+	var body bytes.Buffer
+	var dst io.Writer = &body
+	var zw *gzip.Writer
+	if w.c.Gzip {
+		zw = gzip.NewWriter(&body)
+		dst = zw
+	}
+	for _, e := range entries {
+		dst.Write(object(e))
+		dst.Write([]byte{'\n'})
+	}
+	if zw != nil {
+		zw.Close()
+	}
+	p := retry.Policy{Initial: time.Second, Max: MaxBackoff, Logger: batch.Stderr}
+	batch.PostWith("ndjson-post", p, w.client, w.c.URL, w.header, body.Bytes())
+}
+
+// object returns entry if it is a JSON object, or else
+// a JSON object with entry as its "log" field.
+// It runs in the batch goroutine, not in Write, so the
+// cost of parsing isn't paid with the log output locked.
+func object(entry []byte) []byte {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(entry, &obj) == nil {
+		return entry
+	}
+	b, _ := json.Marshal(map[string]string{"log": string(entry)}) // can't fail for a string
+	return b
+}
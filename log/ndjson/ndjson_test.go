@@ -0,0 +1,118 @@
+package ndjson
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/log"
+	"chain/log/internal/batch"
+)
+
+func TestWrite(t *testing.T) {
+	got := make(chan []string, 1)
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if k := req.Header.Get("Dd-Api-Key"); k != "key" {
+			t.Errorf("DD-API-KEY = %q", k)
+		}
+		if ct := req.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if fail {
+			fail = false // the retry should succeed
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		zr, err := gzip.NewReader(req.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var lines []string
+		s := bufio.NewScanner(zr)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		got <- lines
+	}))
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL, Header: http.Header{"Dd-Api-Key": {"key"}}, Gzip: true})
+	for _, line := range []string{`{"at":"a.go:1","n":1}` + "\n", "at=a.go:2 n=2\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var lines []string
+	select {
+	case lines = <-got:
+	case <-time.After(5 * batch.FlushInterval):
+		t.Fatal("timed out waiting for entries")
+	}
+	want := []string{`{"at":"a.go:1","n":1}`, `{"log":"at=a.go:2 n=2"}`}
+	if len(lines) != len(want) {
+		t.Fatalf("got %q want %q", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q want %q", i, lines[i], want[i])
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	var lines []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s := bufio.NewScanner(req.Body)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+	}))
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL})
+	old := log.Output()
+	log.SetOutput(w)
+	defer log.SetOutput(old)
+	w.Write([]byte("n=1\n"))
+	err := log.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flush waits for the queued entry to be posted,
+	// well before batch.FlushInterval.
+	if len(lines) != 1 || lines[0] != `{"log":"n=1"}` {
+		t.Errorf("got %q after Flush, want the queued entry", lines)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var lines []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s := bufio.NewScanner(req.Body)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+	}))
+	defer srv.Close()
+
+	w := New(Config{URL: srv.URL})
+	w.Write([]byte(`{"n":1}` + "\n"))
+	err := w.(io.Closer).Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Close waits for the queued entry to be posted.
+	if len(lines) != 1 || lines[0] != `{"n":1}` {
+		t.Errorf("got %q after Close, want the queued entry", lines)
+	}
+	if _, err := w.Write([]byte(`{"n":2}` + "\n")); err != batch.ErrClosed {
+		t.Errorf("Write after Close: err = %v want %v", err, batch.ErrClosed)
+	}
+}
@@ -0,0 +1,90 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// OpenTelemetry severity numbers and names.
+var otlpSeverities = map[string]struct {
+	number int
+	text   string
+}{
+	severityDebug:   {5, "DEBUG"},
+	severityInfo:    {9, "INFO"},
+	severityWarning: {13, "WARN"},
+	severityError:   {17, "ERROR"},
+}
+
+// formatOTLP writes an entry as an OpenTelemetry LogRecord
+// in the OTLP/JSON encoding, on one line.
+// The record's body is the entry's summary.
+// Its attributes are the prefix fields pre, KeyCaller
+// (as "code.location"), fields, and the stack trace,
+// if any (as "exception.stacktrace").
+func formatOTLP(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	s := otlpSeverities[sev]
+	fmt.Fprintf(buf, `{"timeUnixNano":"%d","severityNumber":%d,"severityText":"%s","body":{"stringValue":`, t.UnixNano(), s.number, s.text)
+	writeJSONString(buf, summary(fields))
+	buf.WriteString(`},"attributes":[`)
+	for i := 0; i < len(pre); i += 2 {
+		writeOTLPAttribute(buf, pre[i], pre[i+1])
+	}
+	writeOTLPAttribute(buf, "code.location", loc)
+	for i := 0; i < len(fields); i += 2 {
+		writeOTLPAttribute(buf, fields[i], fields[i+1])
+	}
+	if stack != nil {
+		var b bytes.Buffer
+		writeRawStack(&b, stack)
+		if b.Len() > 0 {
+			writeOTLPAttribute(buf, "exception.stacktrace", b.String())
+		}
+	}
+	buf.Truncate(buf.Len() - 1) // trailing comma
+	buf.WriteString("]}\n")
+}
+
+// writeOTLPAttribute writes a KeyValue
+// whose AnyValue has the type closest to v's,
+// or is empty if v is nil.
+func writeOTLPAttribute(buf *bytes.Buffer, k, v interface{}) {
+	buf.WriteString(`{"key":`)
+	writeJSONString(buf, formatKey(k))
+	buf.WriteString(`,"value":{`)
+	if isNil(v) {
+		buf.WriteString("}},") // an empty AnyValue is null
+		return
+	}
+	switch v := v.(type) {
+	case bool:
+		fmt.Fprintf(buf, `"boolValue":%t`, v)
+	case int, int8, int16, int32, int64, uint8, uint16, uint32:
+		fmt.Fprintf(buf, `"intValue":"%d"`, v) // int64 is a string in OTLP/JSON
+	case float32:
+		writeOTLPDouble(buf, float64(v))
+	case float64:
+		writeOTLPDouble(buf, v)
+	default:
+		buf.WriteString(`"stringValue":`)
+		writeJSONString(buf, valueString(v))
+	}
+	buf.WriteString("}},")
+}
+
+func writeOTLPDouble(buf *bytes.Buffer, f float64) {
+	buf.WriteString(`"doubleValue":`)
+	switch {
+	case math.IsNaN(f):
+		buf.WriteString(`"NaN"`)
+	case math.IsInf(f, 1):
+		buf.WriteString(`"Infinity"`)
+	case math.IsInf(f, -1):
+		buf.WriteString(`"-Infinity"`)
+	default:
+		buf.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+}
@@ -0,0 +1,76 @@
+// Package otlp exports log entries in the OTLP format
+// (see chain/log.OTLP) to an OpenTelemetry collector
+// using OTLP over HTTP with JSON encoding, so they land
+// in the same backend as traces and the metrics
+// exported by chain/metrics/otlp.
+package otlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"chain/log/internal/batch"
+)
+
+// An Exporter is an io.Writer that sends each written
+// LogRecord, as one entry formatted by chain/log,
+// to an OTLP/HTTP endpoint, in batches,
+// from a background goroutine.
+// Writes fail, dropping the entry, when too many
+// records are waiting to be sent.
+type Exporter struct {
+	url      string
+	client   *http.Client
+	resource []byte // JSON-encoded Resource
+	w        *batch.Writer
+}
+
+// New returns an Exporter that posts log records to url,
+// typically "http://<collector>:4318/v1/logs".
+// The resource attribute service.name is set to service.
+func New(url, service string) *Exporter {
+	name, _ := json.Marshal(service) // can't fail for a string
+	e := &Exporter{
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		resource: []byte(`{"attributes":[{"key":"service.name","value":{"stringValue":` + string(name) + `}}]}`),
+	}
+	e.w = batch.New(e.send)
+	return e
+}
+
+// Write queues the record p without blocking.
+func (e *Exporter) Write(p []byte) (int, error) {
+	return e.w.Write(p)
+}
+
+// Flush sends the queued records and waits,
+// for a few seconds at most, for them to be sent.
+func (e *Exporter) Flush() error {
+	return e.w.Flush()
+}
+
+// Close sends the queued records and stops the background goroutine.
+// Writes after Close fail.
+func (e *Exporter) Close() error {
+	return e.w.Close()
+}
+
+func (e *Exporter) send(records [][]byte) {
+	header := http.Header{"Content-Type": {"application/json"}}
+	batch.Post("otlp-export", e.client, e.url, header, e.request(records))
+}
+
+// request returns an ExportLogsServiceRequest
+// containing records.
+func (e *Exporter) request(records [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"resourceLogs":[{"resource":`)
+	buf.Write(e.resource)
+	buf.WriteString(`,"scopeLogs":[{"scope":{"name":"chain"},"logRecords":[`)
+	buf.Write(bytes.Join(records, []byte{','}))
+	buf.WriteString(`]}]}]}`)
+	return buf.Bytes()
+}
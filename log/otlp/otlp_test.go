@@ -0,0 +1,51 @@
+package otlp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/log/internal/batch"
+)
+
+func TestExport(t *testing.T) {
+	got := make(chan map[string]interface{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		b, _ := ioutil.ReadAll(req.Body)
+		var v map[string]interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			t.Errorf("request body %q: %v", b, err)
+		}
+		got <- v
+	}))
+	defer srv.Close()
+
+	e := New(srv.URL+"/v1/logs", "cored")
+	for _, rec := range []string{`{"severityNumber":9}` + "\n", `{"severityNumber":17}` + "\n"} {
+		if _, err := e.Write([]byte(rec)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var v map[string]interface{}
+	select {
+	case v = <-got:
+	case <-time.After(5 * batch.FlushInterval):
+		t.Fatal("timed out waiting for export")
+	}
+	rl := v["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	attrs := rl["resource"].(map[string]interface{})["attributes"].([]interface{})
+	if name := attrs[0].(map[string]interface{})["value"].(map[string]interface{})["stringValue"]; name != "cored" {
+		t.Errorf("service.name = %v want cored", name)
+	}
+	recs := rl["scopeLogs"].([]interface{})[0].(map[string]interface{})["logRecords"].([]interface{})
+	if len(recs) != 2 {
+		t.Errorf("got %d records want 2", len(recs))
+	}
+}
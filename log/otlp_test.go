@@ -0,0 +1,43 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatOTLP(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	buf := new(bytes.Buffer)
+	fields := []interface{}{KeyError, "boom", "n", 7, "ok", true, "f", 1.5, "nil", nil}
+	formatOTLP(buf, []interface{}{"reqid", "r1"}, severityError, ts, "a.go:1", fields, []byte("trace"))
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("entry %q: %v", buf, err)
+	}
+	attr := func(k string, v map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"key": k, "value": v}
+	}
+	want := map[string]interface{}{
+		"timeUnixNano":   "1488373445006000000",
+		"severityNumber": 17.0,
+		"severityText":   "ERROR",
+		"body":           map[string]interface{}{"stringValue": "boom"},
+		"attributes": []interface{}{
+			attr("reqid", map[string]interface{}{"stringValue": "r1"}),
+			attr("code.location", map[string]interface{}{"stringValue": "a.go:1"}),
+			attr("error", map[string]interface{}{"stringValue": "boom"}),
+			attr("n", map[string]interface{}{"intValue": "7"}),
+			attr("ok", map[string]interface{}{"boolValue": true}),
+			attr("f", map[string]interface{}{"doubleValue": 1.5}),
+			attr("nil", map[string]interface{}{}),
+			attr("exception.stacktrace", map[string]interface{}{"stringValue": "trace\n"}),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("formatOTLP = %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Field numbers and wire types from entry.proto.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+
+	entryTime     = 1
+	entrySeverity = 2
+	entryCaller   = 3
+	entryFields   = 4
+	entryStack    = 5
+
+	fieldKey    = 1
+	fieldKind   = 2
+	fieldString = 3
+	fieldInt    = 4
+	fieldDouble = 5
+	fieldBool   = 6
+
+	kindString = 0
+	kindInt    = 1
+	kindDouble = 2
+	kindBool   = 3
+)
+
+var protoSeverities = map[string]uint64{
+	severityInfo:    0,
+	severityWarning: 1,
+	severityError:   2,
+	severityDebug:   3,
+}
+
+// formatProto writes an entry as a length-delimited Entry
+// message, described in entry.proto.
+// Integers, floats, and booleans keep their type;
+// other values are encoded as strings, as in the KV format.
+// It encodes by hand, rather than with package proto,
+// to avoid reflection on the logging path.
+func formatProto(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}, stack interface{}) {
+	var m []byte
+	m = appendTag(m, entryTime, wireFixed64)
+	m = appendFixed64(m, uint64(t.UnixNano()))
+	if s := protoSeverities[sev]; s != 0 {
+		m = appendTag(m, entrySeverity, wireVarint)
+		m = appendUvarint(m, s)
+	}
+	m = appendStringField(m, entryCaller, loc)
+	var f []byte
+	for _, a := range [][]interface{}{pre, fields} {
+		for i := 0; i < len(a); i += 2 {
+			f = appendProtoField(f[:0], formatKey(a[i]), a[i+1])
+			m = appendTag(m, entryFields, wireBytes)
+			m = appendUvarint(m, uint64(len(f)))
+			m = append(m, f...)
+		}
+	}
+	if stack != nil {
+		var b bytes.Buffer
+		writeRawStack(&b, stack)
+		m = appendStringField(m, entryStack, b.String())
+	}
+
+	buf.Write(appendUvarint(nil, uint64(len(m))))
+	buf.Write(m)
+}
+
+func appendProtoField(b []byte, k string, v interface{}) []byte {
+	b = appendStringField(b, fieldKey, k)
+	var i int64
+	switch v := v.(type) {
+	case int:
+		i = int64(v)
+	case int8:
+		i = int64(v)
+	case int16:
+		i = int64(v)
+	case int32:
+		i = int64(v)
+	case int64:
+		i = v
+	case uint8:
+		i = int64(v)
+	case uint16:
+		i = int64(v)
+	case uint32:
+		i = int64(v)
+	case float32:
+		return appendDouble(b, float64(v))
+	case float64:
+		return appendDouble(b, v)
+	case bool:
+		b = appendTag(b, fieldKind, wireVarint)
+		b = appendUvarint(b, kindBool)
+		if v {
+			b = appendTag(b, fieldBool, wireVarint)
+			b = appendUvarint(b, 1)
+		}
+		return b
+	default:
+		return appendStringField(b, fieldString, valueString(v))
+	}
+	b = appendTag(b, fieldKind, wireVarint)
+	b = appendUvarint(b, kindInt)
+	if i != 0 {
+		b = appendTag(b, fieldInt, wireVarint)
+		b = appendUvarint(b, uint64(i<<1^i>>63)) // zigzag, for sint64
+	}
+	return b
+}
+
+func appendDouble(b []byte, f float64) []byte {
+	b = appendTag(b, fieldKind, wireVarint)
+	b = appendUvarint(b, kindDouble)
+	if f != 0 || math.Signbit(f) {
+		b = appendTag(b, fieldDouble, wireFixed64)
+		b = appendFixed64(b, math.Float64bits(f))
+	}
+	return b
+}
+
+// appendStringField appends a string field,
+// omitting it if s is empty, as proto3 does.
+func appendStringField(b []byte, num int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendTag(b, num, wireBytes)
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendTag(b []byte, num, wire int) []byte {
+	return appendUvarint(b, uint64(num<<3|wire))
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var a [8]byte
+	binary.LittleEndian.PutUint64(a[:], v)
+	return append(b, a[:]...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var a [binary.MaxVarintLen64]byte
+	return append(b, a[:binary.PutUvarint(a[:], v)]...)
+}
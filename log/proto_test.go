@@ -0,0 +1,94 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatProto(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	buf := new(bytes.Buffer)
+	fields := []interface{}{KeyError, "boom", "n", -7, "zero", 0, "ok", true, "f", 1.5}
+	formatProto(buf, []interface{}{"reqid", "r1"}, severityError, ts, "a.go:1", fields, []byte("trace"))
+
+	n, w := binary.Uvarint(buf.Bytes())
+	msg := buf.Bytes()[w:]
+	if int(n) != len(msg) {
+		t.Fatalf("length prefix = %d, message has %d bytes", n, len(msg))
+	}
+
+	got := map[uint64][]interface{}{}
+	decodeProto(t, msg, func(num uint64, v interface{}) {
+		if raw, ok := v.([]byte); ok {
+			if num == entryFields {
+				var f []interface{}
+				decodeProto(t, raw, func(num uint64, v interface{}) {
+					if raw, ok := v.([]byte); ok {
+						v = string(raw)
+					}
+					f = append(f, num, v)
+				})
+				v = f
+			} else {
+				v = string(raw)
+			}
+		}
+		got[num] = append(got[num], v)
+	})
+
+	want := map[uint64][]interface{}{
+		entryTime:     {uint64(ts.UnixNano())},
+		entrySeverity: {uint64(2)},
+		entryCaller:   {"a.go:1"},
+		entryFields: {
+			[]interface{}{uint64(fieldKey), "reqid", uint64(fieldString), "r1"},
+			[]interface{}{uint64(fieldKey), "error", uint64(fieldString), "boom"},
+			[]interface{}{uint64(fieldKey), "n", uint64(fieldKind), uint64(kindInt), uint64(fieldInt), uint64(13)}, // zigzag
+			[]interface{}{uint64(fieldKey), "zero", uint64(fieldKind), uint64(kindInt)},
+			[]interface{}{uint64(fieldKey), "ok", uint64(fieldKind), uint64(kindBool), uint64(fieldBool), uint64(1)},
+			[]interface{}{uint64(fieldKey), "f", uint64(fieldKind), uint64(kindDouble), uint64(fieldDouble), math.Float64bits(1.5)},
+		},
+		entryStack: {"trace\n"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded entry = %v\nwant %v", got, want)
+	}
+}
+
+// decodeProto calls f with the number and value of each field in msg.
+// Values are uint64 for varint and fixed64 fields
+// and []byte for length-delimited ones.
+func decodeProto(t *testing.T, msg []byte, f func(num uint64, v interface{})) {
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			t.Fatalf("bad tag in %x", msg)
+		}
+		msg = msg[n:]
+		switch tag & 7 {
+		case wireVarint:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				t.Fatalf("bad varint in %x", msg)
+			}
+			msg = msg[n:]
+			f(tag>>3, v)
+		case wireFixed64:
+			f(tag>>3, binary.LittleEndian.Uint64(msg))
+			msg = msg[8:]
+		case wireBytes:
+			l, n := binary.Uvarint(msg)
+			if n <= 0 || int(l) > len(msg[n:]) {
+				t.Fatalf("bad length in %x", msg)
+			}
+			f(tag>>3, msg[n:n+int(l)])
+			msg = msg[n+int(l):]
+		default:
+			t.Fatalf("unexpected wire type %d", tag&7)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keyReqID is the prefix field that chain/net/http/reqid
+// adds to a request's Context.
+const keyReqID = "reqid"
+
+// reqIdle is how long a request's bucket is kept
+// after its last entry.
+const reqIdle = time.Minute
+
+var (
+	reqLimitOn int32 // 1 if SetRequestRateLimit enabled limiting
+
+	reqLimitMu    sync.Mutex // protects the following
+	reqRate       float64    // tokens per second; 0 disables limiting
+	reqBurst      float64
+	reqBuckets    map[string]*bucket
+	reqLastPruned time.Time
+)
+
+// A bucket is a token bucket for one request's entries.
+type bucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed bool // the last entry was discarded
+}
+
+// SetRequestRateLimit limits the entries logged for each request,
+// as identified by the "reqid" prefix field of its Context
+// (see chain/net/http/reqid), so that one pathological request
+// can't flood the log. Each request may log burst entries at once,
+// and rate entries per second after that.
+// When a request first exceeds the limit, one warning entry
+// says so; its further entries are discarded, and counted
+// in expvar "log_dropped", until it is within the limit again.
+// Entries without a request ID are not limited.
+// It has no effect on a Logger with an output of its own.
+// A rate of zero disables limiting, the default.
+func SetRequestRateLimit(rate float64, burst int) {
+	reqLimitMu.Lock()
+	defer reqLimitMu.Unlock()
+	reqRate = rate
+	reqBurst = float64(burst)
+	reqBuckets = nil
+	var on int32
+	if rate > 0 {
+		on = 1
+	}
+	atomic.StoreInt32(&reqLimitOn, on)
+}
+
+// rateLimitEnabled reports whether SetRequestRateLimit
+// has enabled limiting.
+func rateLimitEnabled() bool {
+	return atomic.LoadInt32(&reqLimitOn) != 0
+}
+
+// requestID returns the value of the "reqid"
+// prefix field of ctx, if any.
+func requestID(ctx context.Context) string {
+	a := prefixFields(ctx)
+	for i := 0; i+1 < len(a); i += 2 {
+		if a[i] == keyReqID {
+			s, _ := a[i+1].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// allowRequest reports whether the request with ID id
+// may log an entry at time t, and whether a denied entry
+// is the first since the request was last within the limit.
+func allowRequest(id string, t time.Time) (ok, first bool) {
+	reqLimitMu.Lock()
+	defer reqLimitMu.Unlock()
+	if reqRate <= 0 || id == "" {
+		return true, false
+	}
+	if reqBuckets == nil {
+		reqBuckets = make(map[string]*bucket)
+	}
+	if t.Sub(reqLastPruned) >= reqIdle {
+		for id, b := range reqBuckets {
+			if t.Sub(b.last) >= reqIdle {
+				delete(reqBuckets, id)
+			}
+		}
+		reqLastPruned = t
+	}
+
+	b := reqBuckets[id]
+	if b == nil {
+		b = &bucket{tokens: reqBurst, last: t}
+		reqBuckets[id] = b
+	}
+	b.tokens += t.Sub(b.last).Seconds() * reqRate
+	if b.tokens > reqBurst {
+		b.tokens = reqBurst
+	}
+	b.last = t
+	if b.tokens < 1 {
+		first = !b.suppressed
+		b.suppressed = true
+		return false, first
+	}
+	b.tokens--
+	b.suppressed = false
+	return true, false
+}
+
+// writeSuppressed writes the warning that the request
+// in ctx exceeded its limit, as of an entry logged
+// at time t from loc.
+func writeSuppressed(ctx context.Context, t time.Time, loc string) {
+	keyvals := []interface{}{"warning", "log entries suppressed: request exceeded its rate limit"}
+	countEntry(severityWarning, false)
+	writeEntry(t, severityWarning, formatEntry(ctx, currentFormat(), processPrefix(), severityWarning, t, loc, keyvals))
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAllowRequest(t *testing.T) {
+	SetRequestRateLimit(2, 3)
+	defer SetRequestRateLimit(0, 0)
+
+	t0 := time.Now()
+	for i := 0; i < 3; i++ {
+		if ok, _ := allowRequest("r1", t0); !ok {
+			t.Fatalf("entry %d denied within burst", i)
+		}
+	}
+	if ok, first := allowRequest("r1", t0); ok || !first {
+		t.Errorf("allowRequest = %t, %t want false, true", ok, first)
+	}
+	if ok, first := allowRequest("r1", t0); ok || first {
+		t.Errorf("allowRequest = %t, %t want false, false", ok, first)
+	}
+	if ok, _ := allowRequest("r2", t0); !ok {
+		t.Error("other request denied")
+	}
+	if ok, _ := allowRequest("", t0); !ok {
+		t.Error("entry without a request ID denied")
+	}
+	if ok, _ := allowRequest("r1", t0.Add(500*time.Millisecond)); !ok {
+		t.Error("entry denied after refill")
+	}
+}
+
+func TestRequestRateLimit(t *testing.T) {
+	out := captureOutput(t)
+	SetRequestRateLimit(0.001, 5)
+	defer SetRequestRateLimit(0, 0)
+
+	ctx := AddPrefixkv(context.Background(), "reqid", "r1")
+	dropped0 := droppedCount(dropRate, severityInfo)
+	for i := 0; i < 100; i++ {
+		Printkv(ctx, "i", i)
+	}
+	if got := out.Find("reqid", "r1"); len(got) != 6 {
+		t.Errorf("entries = %v, want 5 entries and a warning", got)
+	}
+	if got := out.Find("warning", "log entries suppressed: request exceeded its rate limit"); len(got) != 1 {
+		t.Errorf("got %d suppression warnings, want 1", len(got))
+	}
+	if d := droppedCount(dropRate, severityInfo) - dropped0; d != 95 {
+		t.Errorf("dropped = %d want 95", d)
+	}
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"expvar"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recentErrorLimit is the number of distinct errors
+// remembered for each subsystem.
+const recentErrorLimit = 10
+
+// ErrorSummary describes a group of similar error entries.
+// Entries are grouped by fingerprint, which is derived from the
+// location of the call to Printkv and the error text with any
+// digits removed, so errors that differ only in IDs, heights,
+// or addresses are counted together.
+type ErrorSummary struct {
+	Fingerprint string    `json:"fingerprint"`
+	Caller      string    `json:"caller"`
+	Message     string    `json:"message"` // most recent
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+var (
+	recentMu     sync.Mutex
+	recentErrors = map[string][]*ErrorSummary{} // by subsystem
+)
+
+func init() {
+	expvar.Publish("log_recent_errors", expvar.Func(func() interface{} {
+		return RecentErrors()
+	}))
+}
+
+// RecentErrors returns summaries of the error entries
+// most recently written, keyed by subsystem (the import
+// path of the package that called Printkv).
+// Each subsystem's summaries are ordered by LastSeen,
+// most recent first.
+func RecentErrors() map[string][]ErrorSummary {
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	m := make(map[string][]ErrorSummary)
+	for sub, a := range recentErrors {
+		s := make([]ErrorSummary, len(a))
+		for i, e := range a {
+			s[i] = *e
+		}
+		sort.Slice(s, func(i, j int) bool { return s[i].LastSeen.After(s[j].LastSeen) })
+		m[sub] = s
+	}
+	return m
+}
+
+// recordError remembers an error entry with the given fields,
+// written at time t by function fn at location loc.
+func recordError(t time.Time, fn, loc string, keyvals []interface{}) {
+	var msg string
+	for i := 0; i < len(keyvals); i += 2 {
+		if keyvals[i] == KeyError {
+			msg = fmt.Sprint(keyvals[i+1])
+			break
+		}
+	}
+	sub := funcPackage(fn)
+	fp := fingerprint(loc, msg)
+
+	recentMu.Lock()
+	defer recentMu.Unlock()
+	a := recentErrors[sub]
+	for _, e := range a {
+		if e.Fingerprint == fp {
+			e.Count++
+			e.Message = msg
+			e.LastSeen = t
+			return
+		}
+	}
+	e := &ErrorSummary{
+		Fingerprint: fp,
+		Caller:      loc,
+		Message:     msg,
+		Count:       1,
+		FirstSeen:   t,
+		LastSeen:    t,
+	}
+	if len(a) < recentErrorLimit {
+		recentErrors[sub] = append(a, e)
+		return
+	}
+	// Replace the least recently seen.
+	old := 0
+	for i := range a {
+		if a[i].LastSeen.Before(a[old].LastSeen) {
+			old = i
+		}
+	}
+	a[old] = e
+}
+
+func fingerprint(loc, msg string) string {
+	msg = strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return -1
+		}
+		return r
+	}, msg)
+	h := fnv.New64a()
+	h.Write([]byte(loc))
+	h.Write([]byte{0})
+	h.Write([]byte(msg))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// funcPackage returns the package import path
+// from a fully-qualified function name
+// such as chain/core.(*API).info.func1.
+func funcPackage(name string) string {
+	dir, base := path.Split(name)
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		base = base[:i]
+	}
+	return dir + base
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestRecentErrors(t *testing.T) {
+	captureOutput(t)
+	recentMu.Lock()
+	recentErrors = map[string][]*ErrorSummary{}
+	recentMu.Unlock()
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		Error(ctx, fmt.Errorf("block %d not found", i))
+	}
+	Error(ctx, fmt.Errorf("something else"))
+	Printkv(ctx, "message", "not an error")
+
+	got := RecentErrors()["chain/log"]
+	if len(got) != 2 {
+		t.Fatalf("RecentErrors = %+v, want 2 summaries", got)
+	}
+	if got[0].Message != "something else" || got[0].Count != 1 {
+		t.Errorf("got[0] = %+v, want something else x1", got[0])
+	}
+	if got[1].Message != "block 2 not found" || got[1].Count != 3 {
+		t.Errorf("got[1] = %+v, want block 2 not found x3", got[1])
+	}
+	if got[0].Fingerprint == got[1].Fingerprint {
+		t.Errorf("fingerprints are equal: %s", got[0].Fingerprint)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	cases := map[string]string{
+		"chain/core.(*API).info.func1": "chain/core",
+		"main.main":                    "main",
+		"github.com/a/b.F":             "github.com/a/b",
+	}
+	for name, want := range cases {
+		if got := funcPackage(name); got != want {
+			t.Errorf("funcPackage(%q) = %q want %q", name, got, want)
+		}
+	}
+}
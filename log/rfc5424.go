@@ -0,0 +1,96 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// syslogFacility is the facility of messages
+// in the RFC5424 format: system daemons.
+const syslogFacility = 3
+
+// sdID is the SD-ID of the structured-data element
+// holding an entry's fields in the RFC5424 format.
+// 32473 is the private enterprise number
+// RFC 5612 reserves for documentation and examples.
+const sdID = "fields@32473"
+
+// syslog severities, as in GELF.
+var syslogSeverities = gelfLevels
+
+var syslogProcID = fmt.Sprint(os.Getpid())
+
+// formatRFC5424 writes an entry as an RFC 5424 syslog message
+// on one line. The APP-NAME is the product set by SetDevice,
+// the MSGID is the entry's KeyAudit field, if any,
+// and the MSG is the entry's summary.
+// Every field, including the prefix fields pre and KeyCaller,
+// is a parameter of one structured-data element, sdID.
+// The stack trace is omitted.
+func formatRFC5424(buf *bytes.Buffer, pre []interface{}, sev string, t time.Time, loc string, fields []interface{}) {
+	deviceMu.Lock()
+	app := deviceProduct
+	deviceMu.Unlock()
+
+	msgID := "-"
+	for i := 0; i < len(fields); i += 2 {
+		if fields[i] == KeyAudit {
+			msgID = syslogName(fmt.Sprint(fields[i+1]), 32)
+			break
+		}
+	}
+	fmt.Fprintf(buf, "<%d>1 %s %s %s %s %s [%s",
+		syslogFacility*8+syslogSeverities[sev],
+		t.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogName(hostname, 255),
+		syslogName(app, 48),
+		syslogProcID,
+		msgID,
+		sdID,
+	)
+	writeSDParam(buf, KeyCaller, loc)
+	for _, a := range [][]interface{}{pre, fields} {
+		for i := 0; i < len(a); i += 2 {
+			writeSDParam(buf, a[i], a[i+1])
+		}
+	}
+	buf.WriteString("] ")
+	buf.WriteString(strings.Replace(summary(fields), "\n", " ", -1))
+	buf.WriteByte('\n')
+}
+
+var sdValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`, "\n", " ", "\r", " ")
+
+func writeSDParam(buf *bytes.Buffer, k, v interface{}) {
+	name := strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 0x7f || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, formatKey(k))
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	fmt.Fprintf(buf, ` %s="%s"`, name, sdValueEscaper.Replace(valueString(v)))
+}
+
+// syslogName returns s as a header field of at most n
+// printable ASCII characters, or "-" if s is empty.
+func syslogName(s string, n int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 0x7f {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > n {
+		s = s[:n]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatRFC5424(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	head := "1 2017-03-01T13:04:05.006000Z " + syslogName(hostname, 255) + " cored " + syslogProcID
+	cases := []struct {
+		sev    string
+		fields []interface{}
+		want   string
+	}{{
+		severityInfo,
+		[]interface{}{KeyMessage, "block committed", "height", 7},
+		"<30>" + head + ` - [fields@32473 at="a.go:1" reqid="r1" message="block committed" height="7"] block committed`,
+	}, {
+		severityError,
+		[]interface{}{KeyAudit, "sign", KeyError, `bad "key" [x]`, "a b=c", 1},
+		"<27>" + head + ` sign [fields@32473 at="a.go:1" reqid="r1" audit="sign" error="bad \"key\" [x\]" a-b-c="1"] bad "key" [x]`,
+	}}
+	for i, c := range cases {
+		buf := new(bytes.Buffer)
+		formatRFC5424(buf, []interface{}{"reqid", "r1"}, c.sev, ts, "a.go:1", c.fields)
+		if got := buf.String(); got != c.want+"\n" {
+			t.Errorf("%d: formatRFC5424 =\n%q want\n%q", i, got, c.want+"\n")
+		}
+	}
+}
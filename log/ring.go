@@ -0,0 +1,108 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+// A Ring is a writer that keeps the latest entries
+// written to it in memory, for a post-mortem dump
+// when the log output itself is lossy.
+type Ring struct {
+	mu      sync.Mutex // protects the following
+	entries [][]byte   // a circular buffer; next is the oldest once full
+	next    int
+	full    bool
+}
+
+// NewRing returns a Ring that keeps the latest n entries.
+// Programs typically add it to the log output with FanOut,
+// and register it with SetRing.
+func NewRing(n int) *Ring {
+	if n < 1 {
+		n = 1
+	}
+	return &Ring{entries: make([][]byte, n)}
+}
+
+// Write keeps a copy of p, discarding the oldest entry
+// if the ring is full. It never fails.
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := append(r.entries[r.next][:0], p...) // reuse the discarded entry's memory
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next, r.full = 0, true
+	}
+	return len(p), nil
+}
+
+// WriteTo writes the entries in the ring to w,
+// oldest first. It copies them first, so a slow w
+// doesn't hold up writes to r.
+func (r *Ring) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	r.mu.Lock()
+	if r.full {
+		for _, e := range r.entries[r.next:] {
+			buf.Write(e)
+		}
+	}
+	for _, e := range r.entries[:r.next] {
+		buf.Write(e)
+	}
+	r.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+var (
+	ringMu   sync.Mutex // protects the following
+	ring     *Ring
+	ringDump string
+)
+
+// SetRing registers r as the process's ring,
+// which CurrentRing returns, for serving on demand
+// by a debug endpoint. If dumpFile isn't empty,
+// Fatalkv writes the entries in r to that file,
+// replacing it, before exiting.
+// A nil r removes the registered ring.
+func SetRing(r *Ring, dumpFile string) {
+	ringMu.Lock()
+	defer ringMu.Unlock()
+	ring, ringDump = r, dumpFile
+}
+
+// CurrentRing returns the Ring registered with SetRing,
+// or nil if there is none.
+func CurrentRing() *Ring {
+	ringMu.Lock()
+	defer ringMu.Unlock()
+	return ring
+}
+
+// DumpRing writes the entries in the Ring
+// registered with SetRing to its dump file, if any.
+// Fatalkv calls it before exiting; programs can call it,
+// too, such as when recovering from a panic.
+func DumpRing() error {
+	ringMu.Lock()
+	r, name := ring, ringDump
+	ringMu.Unlock()
+	if r == nil || name == "" {
+		return nil
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) // #nosec
+	if err != nil {
+		return err
+	}
+	_, err = r.WriteTo(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("empty ring wrote %q", buf.String())
+	}
+
+	for _, s := range []string{"a\n", "b\n"} {
+		r.Write([]byte(s))
+	}
+	buf.Reset()
+	r.WriteTo(&buf)
+	if got, want := buf.String(), "a\nb\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+
+	for _, s := range []string{"c\n", "d\n", "e\n"} {
+		r.Write([]byte(s))
+	}
+	buf.Reset()
+	r.WriteTo(&buf)
+	if got, want := buf.String(), "c\nd\ne\n"; got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestDumpRing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "dump.log")
+
+	r := NewRing(2)
+	SetRing(r, name)
+	defer SetRing(nil, "")
+	if CurrentRing() != r {
+		t.Error("CurrentRing() != r")
+	}
+	p := []byte("a\n")
+	r.Write(p)
+	p[0] = 'x' // r must keep its own copy
+
+	err = DumpRing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "a\n"; got != want {
+		t.Errorf("dump = %q want %q", got, want)
+	}
+}
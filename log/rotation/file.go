@@ -3,8 +3,17 @@ package rotation
 
 import (
 	"bytes"
+	"compress/gzip"
+	"io"
 	"os"
 	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	filesMu sync.Mutex
+	files   []*File // every File made by Create, for ReopenAll
 )
 
 // A File is a log file with associated rotation files.
@@ -16,15 +25,74 @@ import (
 // (and base.1 is renamed to base.2, and so on)
 // and a new base file is opened for subsequent writes.
 //
+// Options can also rotate the base file periodically,
+// compress rotated files, and remove them after a time.
+//
 // Any errors encountered while rotating files are ignored.
 // Only errors opening and writing the base file are reported.
 type File struct {
-	base string   // file name
-	size int64    // max size of f (limit on w)
-	n    int      // number of rotated files
-	buf  []byte   // partial line from last write
-	f    *os.File // current base file
-	w    int64    // bytes written to f
+	mu   sync.Mutex // protects the following
+	base string     // file name
+	size int64      // max size of f (limit on w)
+	n    int        // number of rotated files
+	buf  []byte     // partial line from last write
+	f    *os.File   // current base file
+	w    int64      // bytes written to f
+
+	every    time.Duration // rotation period, or 0
+	period   time.Time     // start of the period f was written in
+	compress bool
+	gz       chan struct{} // closed when the last rotated file is compressed; nil if none
+	maxAge   time.Duration // of rotated files, or 0
+	now      func() time.Time
+
+	bufSize  int // complete lines held before writing, or 0
+	bufEvery time.Duration
+	pending  []byte      // complete lines not yet written
+	timer    *time.Timer // flushes pending; nil if pending is empty
+}
+
+// An Option configures a File.
+type Option func(*File)
+
+// Every rotates the base file at the start of each period d,
+// such as time.Hour or 24*time.Hour,
+// in addition to when it reaches its maximum size.
+// Periods begin at multiples of d since the zero time,
+// so daily periods begin at midnight UTC.
+// A base file last written in an earlier period
+// is rotated when reopened.
+func Every(d time.Duration) Option {
+	return func(f *File) { f.every = d }
+}
+
+// Compress gzips each file as it is rotated,
+// naming the rotation files name.1.gz, name.2.gz, and so on.
+// The rotated file is renamed to name.1 and compressed in the
+// background, so the Write that rotates doesn't wait for it.
+// The next rotation, and Close, wait for it to finish.
+func Compress() Option {
+	return func(f *File) { f.compress = true }
+}
+
+// MaxAge removes rotation files last modified
+// more than d ago, whenever the base file rotates
+// (after compressing, with the Compress option).
+// It limits retention in addition to the
+// number of rotation files.
+func MaxAge(d time.Duration) Option {
+	return func(f *File) { f.maxAge = d }
+}
+
+// Buffer holds complete lines in memory and writes them
+// to the base file together, with one write call, once
+// size bytes are held or interval after the first of them,
+// rather than with one call per Write, so that high-volume
+// logging doesn't cost a system call per entry.
+// Flush writes the held lines at once.
+// Lines held when the process crashes are lost.
+func Buffer(size int, interval time.Duration) Option {
+	return func(f *File) { f.bufSize, f.bufEvery = size, interval }
 }
 
 // Create creates a log writing to the named file
@@ -34,11 +102,71 @@ type File struct {
 // up to name.n.
 // The minimum value for n is 1;
 // lesser values will be taken as 1.
-func Create(name string, size, n int) *File {
-	return &File{
+func Create(name string, size, n int, opts ...Option) *File {
+	f := &File{
 		base: name,
 		size: int64(size),
 		n:    n,
+		now:  time.Now,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	filesMu.Lock()
+	files = append(files, f)
+	filesMu.Unlock()
+	return f
+}
+
+// Reopen closes the base file, so the next write
+// opens the file now at its path. After an external
+// tool such as logrotate renames the file, this makes
+// subsequent writes go to a new file rather than
+// the renamed one.
+func (f *File) Reopen() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flush()
+	if f.f != nil {
+		f.f.Close()
+		f.f = nil
+	}
+}
+
+// Close writes the lines held by the Buffer option, if any,
+// waits for the last rotated file to be compressed,
+// and closes the base file. It removes f from the Files
+// reopened by ReopenAll. A later Write reopens the file.
+func (f *File) Close() error {
+	filesMu.Lock()
+	for i, g := range files {
+		if g == f {
+			files = append(files[:i], files[i+1:]...)
+			break
+		}
+	}
+	filesMu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.flush()
+	f.waitCompress()
+	if f.f != nil {
+		if cerr := f.f.Close(); err == nil {
+			err = cerr
+		}
+		f.f = nil
+	}
+	return err
+}
+
+// ReopenAll calls Reopen for every File made by Create.
+// Programs typically call it on SIGHUP.
+func ReopenAll() {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+	for _, f := range files {
+		f.Reopen()
 	}
 }
 
@@ -48,10 +176,22 @@ var dropmsg = []byte("\nlog write error; some data dropped\n")
 // It writes only complete lines to the underlying file.
 // Incomplete lines are buffered in memory
 // and written once a NL is encountered.
+// With the Buffer option, complete lines
+// are held, too, as it describes.
 func (f *File) Write(p []byte) (n int, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	f.buf = append(f.buf, p...)
 	n = len(p)
-	if i := bytes.LastIndexByte(f.buf, '\n'); i >= 0 {
+	if i := bytes.LastIndexByte(f.buf, '\n'); i >= 0 && f.bufSize > 0 {
+		f.pending = append(f.pending, f.buf[:i+1]...)
+		f.buf = f.buf[i+1:]
+		if len(f.pending) >= f.bufSize {
+			err = f.flush()
+		} else if f.timer == nil {
+			f.timer = time.AfterFunc(f.bufEvery, func() { f.Flush() })
+		}
+	} else if i >= 0 {
 		_, err = f.write(f.buf[:i+1])
 		// Even if the write failed, discard the entire
 		// requested write payload. If we kept it around,
@@ -69,41 +209,167 @@ func (f *File) Write(p []byte) (n int, err error) {
 	return
 }
 
+// Flush writes the complete lines held
+// by the Buffer option, if any.
+func (f *File) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flush()
+}
+
+// flush writes the lines in f.pending.
+// The caller must hold f.mu.
+func (f *File) flush() error {
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	if len(f.pending) == 0 {
+		return nil
+	}
+	_, err := f.write(f.pending)
+	// As in Write, discard the lines even if the write failed.
+	f.pending = f.pending[:0]
+	if err != nil {
+		f.buf = append(dropmsg, f.buf...)
+	}
+	return err
+}
+
 // write writes the given data to f,
 // rotating files if necessary.
 func (f *File) write(p []byte) (int, error) {
-	// If p would increase the file over the
-	// max size, it is time to rotate.
-	if f.w+int64(len(p)) > f.size {
-		// best-effort; ignore errors
-		f.rotate()
-		f.f.Close()
-		f.f = nil
-		f.w = 0
+	var period time.Time
+	if f.every > 0 {
+		period = f.now().Truncate(f.every)
 	}
 	if f.f == nil {
-		var err error
-		f.f, err = os.OpenFile(f.base, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644) // #nosec
+		err := f.open()
 		if err != nil {
 			return 0, err
 		}
-		f.w, err = f.f.Seek(0, os.SEEK_END)
+		f.period = period
+		if fi, err := f.f.Stat(); err == nil && f.every > 0 && f.w > 0 {
+			f.period = fi.ModTime().Truncate(f.every)
+		}
+	}
+	// If p would increase the file over the
+	// max size, or a new period has begun,
+	// it is time to rotate.
+	if f.w+int64(len(p)) > f.size || !period.Equal(f.period) {
+		// best-effort; ignore errors
+		f.f.Close()
+		f.rotate()
+		f.f = nil
+		err := f.open()
 		if err != nil {
 			return 0, err
 		}
+		f.period = period
 	}
 	n, err := f.f.Write(p)
 	f.w += int64(n)
 	return n, err
 }
 
+// open opens the base file for appending.
+func (f *File) open() error {
+	var err error
+	f.f, err = os.OpenFile(f.base, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644) // #nosec
+	if err != nil {
+		f.f = nil
+		return err
+	}
+	f.w, err = f.f.Seek(0, os.SEEK_END)
+	if err != nil {
+		f.f.Close()
+		f.f = nil
+	}
+	return err
+}
+
+// rotate renames the rotation files and the base file.
+// The caller must hold f.mu.
 func (f *File) rotate() {
+	f.waitCompress()
 	for i := f.n - 1; i > 0; i-- {
 		os.Rename(f.name(i), f.name(i+1))
 	}
-	os.Rename(f.base, f.name(1))
+	if !f.compress {
+		os.Rename(f.base, f.name(1))
+		f.expire()
+		return
+	}
+	tmp := f.base + ".1"
+	if os.Rename(f.base, tmp) != nil {
+		f.expire()
+		return
+	}
+	done := make(chan struct{})
+	f.gz = done
+	go func() {
+		defer close(done)
+		if gzipFile(tmp, f.name(1)) == nil {
+			os.Remove(tmp)
+		}
+		f.expire()
+	}()
+}
+
+// waitCompress waits for the last rotated file
+// to be compressed, if it is still in progress.
+// The caller must hold f.mu.
+func (f *File) waitCompress() {
+	if f.gz != nil {
+		<-f.gz
+		f.gz = nil
+	}
+}
+
+// expire removes rotation files older than f.maxAge.
+// It uses only fields set by Create, so it is safe
+// to call without f.mu.
+func (f *File) expire() {
+	if f.maxAge <= 0 {
+		return
+	}
+	cutoff := f.now().Add(-f.maxAge)
+	for i := 1; i <= f.n; i++ {
+		if fi, err := os.Stat(f.name(i)); err == nil && fi.ModTime().Before(cutoff) {
+			os.Remove(f.name(i))
+		}
+	}
 }
 
 func (f *File) name(i int) string {
-	return f.base + "." + strconv.Itoa(i)
+	name := f.base + "." + strconv.Itoa(i)
+	if f.compress {
+		name += ".gz"
+	}
+	return name
+}
+
+// gzipFile writes the contents of src, compressed, to dst.
+func gzipFile(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) // #nosec
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	_, err = io.Copy(zw, r)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
 }
@@ -2,9 +2,11 @@ package rotation
 
 import (
 	"bytes"
+	"compress/gzip"
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestRotate(t *testing.T) {
@@ -242,3 +244,165 @@ func touch(name string) {
 		f.Close()
 	}
 }
+
+func TestRotateEvery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := dir + "/x"
+
+	now := time.Date(2017, 3, 1, 23, 59, 0, 0, time.UTC)
+	f := Create(base, 1e6, 2, Every(24*time.Hour))
+	f.now = func() time.Time { return now }
+
+	f.Write([]byte("a\n"))
+	now = now.Add(30 * time.Second)
+	f.Write([]byte("b\n"))
+	if isRegular(base + ".1") {
+		t.Fatal("want no rotated file x.1 within a day")
+	}
+	now = now.Add(time.Minute)
+	f.Write([]byte("c\n"))
+	if got := readFile(t, base+".1"); got != "a\nb\n" {
+		t.Errorf("x.1 = %q want %q", got, "a\nb\n")
+	}
+	if got := readFile(t, base); got != "c\n" {
+		t.Errorf("x = %q want %q", got, "c\n")
+	}
+}
+
+func TestCompressMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := dir + "/x"
+
+	now := time.Now()
+	f := Create(base, 4, 3, Compress(), MaxAge(time.Hour))
+
+	f.Write([]byte("abc\n"))
+	f.Write([]byte("def\n")) // rotates abc to x.1.gz
+	f.Close()                // waits for compression
+	if isRegular(base + ".1") {
+		t.Error("want no uncompressed file x.1")
+	}
+	r, err := os.Open(base + ".1.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(zr)
+	r.Close()
+	if err != nil || string(b) != "abc\n" {
+		t.Errorf("x.1.gz = %q, %v want %q", b, err, "abc\n")
+	}
+
+	old := now.Add(-2 * time.Hour)
+	os.Chtimes(base+".1.gz", old, old)
+	f.Write([]byte("ghi\n")) // rotates def to x.1.gz, expires abc in x.2.gz
+	f.Close()
+	if !isRegular(base + ".1.gz") {
+		t.Error("want rotated file x.1.gz")
+	}
+	if isRegular(base + ".2.gz") {
+		t.Error("want expired file x.2.gz removed")
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := dir + "/x"
+
+	f := Create(base, 1e6, 1)
+	f.Write([]byte("abc\n"))
+	os.Rename(base, base+".old") // as logrotate does
+	f.Write([]byte("def\n"))     // still goes to the renamed file
+	f.Reopen()
+	f.Write([]byte("ghi\n"))
+
+	if got := readFile(t, base+".old"); got != "abc\ndef\n" {
+		t.Errorf("x.old = %q want %q", got, "abc\ndef\n")
+	}
+	if got := readFile(t, base); got != "ghi\n" {
+		t.Errorf("x = %q want %q", got, "ghi\n")
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := dir + "/x"
+
+	f := Create(base, 1e6, 1, Buffer(8, time.Hour))
+	f.Write([]byte("abc\nd"))
+	if b, _ := ioutil.ReadFile(base); len(b) > 0 {
+		t.Errorf("x = %q before the buffer filled, want empty", b)
+	}
+	f.Write([]byte("ef\n")) // 8 bytes of lines
+	if got := readFile(t, base); got != "abc\ndef\n" {
+		t.Errorf("x = %q want %q", got, "abc\ndef\n")
+	}
+	f.Write([]byte("ghi\n"))
+	if err := f.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, base); got != "abc\ndef\nghi\n" {
+		t.Errorf("x = %q after Flush, want %q", got, "abc\ndef\nghi\n")
+	}
+
+	f = Create(base, 1e6, 1, Buffer(1<<10, time.Millisecond))
+	f.Write([]byte("jkl\n"))
+	for i := 0; i < 100 && readFile(t, base) != "abc\ndef\nghi\njkl\n"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := readFile(t, base); got != "abc\ndef\nghi\njkl\n" {
+		t.Errorf("x = %q after the interval, want %q", got, "abc\ndef\nghi\njkl\n")
+	}
+}
+
+func TestClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	base := dir + "/x"
+
+	f := Create(base, 1e6, 1, Buffer(1<<10, time.Hour))
+	f.Write([]byte("abc\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, base); got != "abc\n" {
+		t.Errorf("x = %q after Close, want %q", got, "abc\n")
+	}
+	filesMu.Lock()
+	for _, g := range files {
+		if g == f {
+			t.Error("closed File still reopened by ReopenAll")
+		}
+	}
+	filesMu.Unlock()
+}
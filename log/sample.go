@@ -0,0 +1,77 @@
+package log
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeySampleRate is the key of a field added to entries
+// kept by sampling (see SetSampling). Its value, N,
+// means the entry stands for about N entries
+// from its call site, so counts of sampled entries
+// can be scaled to estimate the true counts.
+const KeySampleRate = "sample-rate"
+
+// sampleInterval is the interval over which sampling
+// counts the entries from each call site.
+const sampleInterval = time.Second
+
+var (
+	sampleN int32 // set by SetSampling; 0 or 1 disables sampling
+
+	sampleMu    sync.Mutex // protects the following
+	sampleBurst int
+	sampleStart time.Time
+	sampleSites map[string]int // entries this interval, by call site
+	sampleRand  = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// SetSampling thins the entries from call sites that log
+// more than burst entries in a second, such as hot loops.
+// Beyond the first burst entries from a call site in each
+// second, each entry is kept with probability 1/n,
+// and has a KeySampleRate field with value n.
+// Entries discarded by sampling are counted
+// in expvar "log_dropped".
+// Errors are always kept.
+// It has no effect on a Logger with an output of its own.
+// An n of 1 or less disables sampling, the default.
+func SetSampling(burst, n int) {
+	sampleMu.Lock()
+	defer sampleMu.Unlock()
+	sampleBurst = burst
+	sampleSites = nil
+	if n < 1 {
+		n = 1
+	}
+	atomic.StoreInt32(&sampleN, int32(n))
+}
+
+// samplingEnabled reports whether SetSampling
+// has enabled sampling.
+func samplingEnabled() bool {
+	return atomic.LoadInt32(&sampleN) > 1
+}
+
+// sample reports whether to keep an entry logged at time t
+// from the given call site, and, if the entry is kept
+// only by chance, the sample rate to record with it.
+func sample(t time.Time, site string) (keep bool, rate int) {
+	sampleMu.Lock()
+	defer sampleMu.Unlock()
+	if t.Sub(sampleStart) >= sampleInterval || sampleSites == nil {
+		sampleStart = t
+		sampleSites = make(map[string]int)
+	}
+	sampleSites[site]++
+	if sampleSites[site] <= sampleBurst {
+		return true, 0
+	}
+	n := int(atomic.LoadInt32(&sampleN))
+	if n <= 1 {
+		return true, 0
+	}
+	return sampleRand.Intn(n) == 0, n
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestSampling(t *testing.T) {
+	out := captureOutput(t)
+	sampleRand = rand.New(rand.NewSource(1))
+	SetSampling(10, 4)
+	defer SetSampling(0, 0)
+
+	ctx := context.Background()
+	dropped0 := droppedCount(dropSample, severityInfo)
+	for i := 0; i < 1000; i++ {
+		Printkv(ctx, "hot", i)
+		if i%100 == 0 {
+			Error(ctx, errors.New("boom"))
+		}
+	}
+	Printkv(ctx, "cold", 1)
+
+	if n := len(out.Find(KeyError, "boom")); n != 10 {
+		t.Errorf("got %d errors, want all 10", n)
+	}
+	if len(out.Find("cold", "1")) != 1 {
+		t.Error("entry from another call site was discarded")
+	}
+	for i := 0; i < 10; i++ {
+		e := out.Find("hot", strconv.Itoa(i))
+		if len(e) != 1 || e[0][KeySampleRate] != "" {
+			t.Errorf("burst entry hot=%d = %v, want one unsampled entry", i, e)
+		}
+	}
+	sampled := len(out.Find(KeySampleRate, "4"))
+	if sampled < 180 || sampled > 320 {
+		t.Errorf("kept %d sampled entries, want about 247", sampled)
+	}
+	if d := droppedCount(dropSample, severityInfo) - dropped0; d != int64(990-sampled) {
+		t.Errorf("dropped = %d want %d", d, 990-sampled)
+	}
+}
@@ -0,0 +1,181 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shardFlushLen is the number of entries in a shard
+// that prompts a flush before the interval passes.
+const shardFlushLen = 256
+
+var (
+	shardsOn int32 // 1 if SetWriteShards enabled shards
+
+	// Logging goroutines hold shardsMu for reading
+	// while they add an entry to a shard.
+	shardsMu   sync.RWMutex
+	shards     []*writeShard
+	shardsStop chan struct{} // closed to stop the flusher
+	shardsDone chan struct{} // closed when the flusher has stopped
+	shardKick  = make(chan struct{}, 1)
+
+	shardNext    uint64     // picks the shard for the next entry
+	shardSeq     uint64     // orders entries across shards; see shardEntry
+	shardFlushMu sync.Mutex // serializes flushes, to keep them in order
+)
+
+// A writeShard holds entries waiting to be written
+// to the log output.
+type writeShard struct {
+	mu      sync.Mutex // protects the following
+	buf     []byte     // the entries' text, one after another
+	entries []heldEntry
+
+	// used only by flushes, holding shardFlushMu
+	spareBuf     []byte
+	spareEntries []heldEntry
+}
+
+// A heldEntry is an entry in a writeShard.
+type heldEntry struct {
+	seq uint64
+	t   time.Time
+	sev string
+	n   int // bytes
+}
+
+// SetWriteShards spreads entries over n shards, each with its
+// own lock, rather than writing each entry to the log output
+// while holding the output's lock, so that many goroutines
+// logging at once don't queue up behind one another.
+// A background goroutine takes the entries from the shards
+// and writes them to the output, one write per entry, in the
+// order they were logged, every interval or sooner if
+// a shard fills. Flush writes them at once.
+// Entries reach the output up to interval late,
+// and they are lost if the process crashes before then;
+// Fatalkv flushes them.
+// An n of zero writes each entry as it is logged, the default.
+func SetWriteShards(n int, interval time.Duration) {
+	shardsMu.Lock()
+	old, stop, done := shards, shardsStop, shardsDone
+	shards, shardsStop, shardsDone = nil, nil, nil
+	atomic.StoreInt32(&shardsOn, 0)
+	if n > 0 {
+		shards = make([]*writeShard, n)
+		for i := range shards {
+			shards[i] = new(writeShard)
+		}
+		shardsStop, shardsDone = make(chan struct{}), make(chan struct{})
+		go runShards(shards, interval, shardsStop, shardsDone)
+		atomic.StoreInt32(&shardsOn, 1)
+	}
+	shardsMu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	flushShardList(old)
+}
+
+// shardEntry adds entry to a shard, if SetWriteShards
+// enabled them, and reports whether it did.
+func shardEntry(t time.Time, sev string, entry []byte) bool {
+	if atomic.LoadInt32(&shardsOn) == 0 {
+		return false
+	}
+	shardsMu.RLock()
+	defer shardsMu.RUnlock()
+	if len(shards) == 0 {
+		return false
+	}
+	s := shards[atomic.AddUint64(&shardNext, 1)%uint64(len(shards))]
+	s.mu.Lock()
+	// The entry takes its place in the order while holding
+	// the shard's lock, so a flush that has read shardSeq
+	// finds every entry up to then in its shard.
+	seq := atomic.AddUint64(&shardSeq, 1)
+	s.buf = append(s.buf, entry...)
+	s.entries = append(s.entries, heldEntry{seq: seq, t: t, sev: sev, n: len(entry)})
+	full := len(s.entries) >= shardFlushLen
+	s.mu.Unlock()
+	if full {
+		select {
+		case shardKick <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+func runShards(list []*writeShard, interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-shardKick:
+		case <-stop:
+			return // SetWriteShards flushes list
+		}
+		flushShardList(list)
+	}
+}
+
+// flushShards writes the entries in the current shards.
+func flushShards() {
+	shardsMu.RLock()
+	list := shards
+	shardsMu.RUnlock()
+	flushShardList(list)
+}
+
+// flushShardList writes the entries in list
+// to the log output, in the order they were logged.
+// Entries logged after the flush begins are left
+// for the next one, since an earlier entry could
+// still be on its way into a shard already read.
+func flushShardList(list []*writeShard) {
+	shardFlushMu.Lock()
+	defer shardFlushMu.Unlock()
+	type span struct {
+		e   heldEntry
+		buf []byte
+	}
+	mark := atomic.LoadUint64(&shardSeq)
+	var all []span
+	for _, s := range list {
+		s.mu.Lock()
+		k := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].seq > mark })
+		n := 0
+		for _, e := range s.entries[:k] {
+			n += e.n
+		}
+		buf, entries := s.buf[:n], s.entries[:k]
+		s.buf = append(s.spareBuf[:0], s.buf[n:]...)
+		s.entries = append(s.spareEntries[:0], s.entries[k:]...)
+		s.mu.Unlock()
+		off := 0
+		for _, e := range entries {
+			all = append(all, span{e, buf[off : off+e.n]})
+			off += e.n
+		}
+		s.spareBuf, s.spareEntries = buf, entries
+	}
+	if len(all) == 0 {
+		return
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].e.seq < all[j].e.seq })
+	logWriterMu.Lock()
+	for _, sp := range all {
+		writeEntryLocked(sp.e.t, sp.e.sev, sp.buf)
+	}
+	logWriterMu.Unlock()
+}
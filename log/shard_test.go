@@ -0,0 +1,91 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetWriteShards(t *testing.T) {
+	out := captureOutput(t)
+	SetWriteShards(4, time.Hour)
+	defer SetWriteShards(0, 0)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				Printkv(ctx, "g", g, "i", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	if got := out.String(); got != "" {
+		t.Fatalf("entries written before the interval: %q", got)
+	}
+	Flush()
+
+	entries := out.Entries()
+	if len(entries) != 400 {
+		t.Fatalf("wrote %d entries, want 400", len(entries))
+	}
+	next := make(map[string]int)
+	for _, e := range entries {
+		g := e["g"]
+		if i := strconv.Itoa(next[g]); e["i"] != i {
+			t.Fatalf("goroutine %s: entry %s, want %s", g, e["i"], i)
+		}
+		next[g]++
+	}
+}
+
+func TestShardFlushMark(t *testing.T) {
+	out := captureOutput(t)
+
+	s0, s1 := new(writeShard), new(writeShard)
+	add := func(s *writeShard, seq uint64, text string) {
+		s.buf = append(s.buf, text...)
+		s.entries = append(s.entries, heldEntry{seq: seq, sev: severityInfo, n: len(text)})
+	}
+	mark := atomic.LoadUint64(&shardSeq)
+	add(s1, mark, "n=1\n")
+	add(s0, mark+1, "n=2\n") // logged after the flush began
+	flushShardList([]*writeShard{s0, s1})
+	if got := out.Entries(); len(got) != 1 || got[0]["n"] != "1" {
+		t.Fatalf("entries = %v, want only n=1", got)
+	}
+	if len(s0.entries) != 1 || string(s0.buf) != "n=2\n" {
+		t.Fatalf("shard holds %d entries, %q, want the later entry", len(s0.entries), s0.buf)
+	}
+
+	atomic.AddUint64(&shardSeq, 1)
+	flushShardList([]*writeShard{s0, s1})
+	if got := out.Entries(); len(got) != 2 || got[1]["n"] != "2" {
+		t.Errorf("entries = %v, want n=1 then n=2", got)
+	}
+}
+
+func BenchmarkPrintkvParallel(b *testing.B) {
+	for _, n := range []int{0, 8} {
+		b.Run(fmt.Sprintf("shards=%d", n), func(b *testing.B) {
+			defer SetOutput(Output())
+			SetOutput(ioutil.Discard)
+			SetWriteShards(n, 10*time.Millisecond)
+			defer SetWriteShards(0, 0)
+			ctx := context.Background()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					Printkv(ctx, "height", 7, KeyMessage, "made block")
+				}
+			})
+		})
+	}
+}
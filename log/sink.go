@@ -0,0 +1,133 @@
+package log
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"chain/metrics"
+)
+
+// sinkLatencyLimit is the largest write latency
+// recorded precisely in a sink's histogram.
+const sinkLatencyLimit = time.Second
+
+var (
+	sinkHealthy  = expvar.NewMap("log_sink_healthy")
+	sinkDropped  = expvar.NewMap("log_sink_dropped")
+	sinkBytes    = expvar.NewMap("log_sink_bytes")
+	sinkFailures = expvar.NewMap("log_sink_failures") // consecutive
+
+	sinksMu       sync.Mutex
+	sinks         = map[string]*sink{}
+	sinkLatencies = map[string]*metrics.RotatingLatency{}
+)
+
+func init() {
+	expvar.Publish("log_sink_latency", expvar.Func(func() interface{} {
+		sinksMu.Lock()
+		defer sinksMu.Unlock()
+		m := make(map[string]map[string]time.Duration)
+		for name, s := range sinks {
+			q := s.latency.Quantiles(50, 99)
+			m[name] = map[string]time.Duration{"p50": q[0], "p99": q[1]}
+		}
+		return m
+	}))
+}
+
+// sink is an io.Writer that records the latency
+// and outcome of each write to a log sink.
+type sink struct {
+	w        io.Writer
+	latency  *metrics.RotatingLatency
+	healthy  expvar.Int
+	dropped  expvar.Int
+	bytes    expvar.Int
+	failures expvar.Int
+
+	mu           sync.Mutex
+	failingSince time.Time // zero if the last write succeeded
+	lastErr      error
+}
+
+// InstrumentSink returns a writer that forwards writes to w,
+// recording, under the given name, the write latency,
+// bytes written, writes dropped, and the number of
+// consecutive failed writes.
+// These are published as expvars with the prefix "log_sink_".
+//
+// Instrumenting a second sink with the same name
+// replaces the first one's stats, but it keeps recording
+// into the same latency histogram, so outputs that are
+// reconfigured don't pile up histograms in package metrics.
+func InstrumentSink(name string, w io.Writer) io.Writer {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	latency := sinkLatencies[name]
+	if latency == nil {
+		latency = metrics.NewRotatingLatency(5, sinkLatencyLimit)
+		sinkLatencies[name] = latency
+	}
+	s := &sink{w: w, latency: latency}
+	s.healthy.Set(1)
+	sinkHealthy.Set(name, &s.healthy)
+	sinkDropped.Set(name, &s.dropped)
+	sinkBytes.Set(name, &s.bytes)
+	sinkFailures.Set(name, &s.failures)
+	sinks[name] = s
+	return s
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	t0 := time.Now()
+	n, err := s.w.Write(p)
+	s.latency.RecordSince(t0)
+	s.bytes.Add(int64(n))
+	s.mu.Lock()
+	if err != nil {
+		s.healthy.Set(0)
+		s.dropped.Add(1)
+		s.failures.Add(1)
+		if s.failingSince.IsZero() {
+			s.failingSince = t0
+		}
+		s.lastErr = err
+	} else {
+		s.healthy.Set(1)
+		s.failures.Set(0)
+		s.failingSince = time.Time{}
+		s.lastErr = nil
+	}
+	s.mu.Unlock()
+	return n, err
+}
+
+// Flush flushes the sink, if it has a Flush method.
+func (s *sink) Flush() error {
+	return flushWriter(s.w)
+}
+
+// Close closes the sink, if it has a Close method.
+func (s *sink) Close() error {
+	return closeWriter(s.w)
+}
+
+// FailingSinks returns an error, keyed by sink name, for each
+// instrumented sink whose writes have failed continuously
+// for at least d.
+func FailingSinks(d time.Duration) map[string]error {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	m := make(map[string]error)
+	for name, s := range sinks {
+		s.mu.Lock()
+		if !s.failingSince.IsZero() && time.Since(s.failingSince) >= d {
+			m[name] = fmt.Errorf("failing since %s: %v", s.failingSince.UTC().Format(time.RFC3339), s.lastErr)
+		}
+		s.mu.Unlock()
+	}
+	return m
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInstrumentSink(t *testing.T) {
+	var buf bytes.Buffer
+	w := InstrumentSink("test", &buf)
+	s := w.(*sink)
+
+	defer SetOutput(Output())
+	SetOutput(w)
+	Printkv(context.Background(), "message", "hello")
+
+	if got := s.bytes.Value(); got != int64(buf.Len()) {
+		t.Errorf("bytes = %d want %d", got, buf.Len())
+	}
+	if s.healthy.Value() != 1 || s.failures.Value() != 0 {
+		t.Errorf("healthy = %d failures = %d, want 1 0", s.healthy.Value(), s.failures.Value())
+	}
+
+	s.w = errWriter{}
+	Printkv(context.Background(), "message", "lost")
+	Printkv(context.Background(), "message", "lost")
+	if s.healthy.Value() != 0 || s.failures.Value() != 2 || s.dropped.Value() != 2 {
+		t.Errorf("healthy = %d failures = %d dropped = %d, want 0 2 2",
+			s.healthy.Value(), s.failures.Value(), s.dropped.Value())
+	}
+	if err := FailingSinks(0)["test"]; err == nil {
+		t.Error("FailingSinks(0) has no error for failing sink")
+	}
+	if err := FailingSinks(time.Hour)["test"]; err != nil {
+		t.Errorf("FailingSinks(1h) = %v, want no error for recent failure", err)
+	}
+
+	s.w = &buf
+	Printkv(context.Background(), "message", "recovered")
+	if s.healthy.Value() != 1 || s.failures.Value() != 0 || s.dropped.Value() != 2 {
+		t.Errorf("healthy = %d failures = %d dropped = %d, want 1 0 2",
+			s.healthy.Value(), s.failures.Value(), s.dropped.Value())
+	}
+	if err := FailingSinks(0)["test"]; err != nil {
+		t.Errorf("FailingSinks(0) = %v, want no error after recovery", err)
+	}
+}
+
+func TestInstrumentSinkReuseLatency(t *testing.T) {
+	a := InstrumentSink("reused", &bytes.Buffer{}).(*sink)
+	b := InstrumentSink("reused", &bytes.Buffer{}).(*sink)
+	if a.latency != b.latency {
+		t.Error("second sink with the same name got a new latency histogram")
+	}
+	if sinks["reused"] != b {
+		t.Error("second sink didn't replace the first")
+	}
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"expvar"
+
+	"github.com/codahale/hdrhistogram"
+)
+
+// sizeLimit is the largest entry size, in bytes,
+// recorded precisely in the size histogram.
+// Larger entries are counted separately.
+const sizeLimit = 1 << 20
+
+var (
+	// The following are protected by logWriterMu.
+	sizeHist  = hdrhistogram.New(1, sizeLimit, 2)
+	sizeOver  int64
+	sizeMax   int64
+	sizeCount int64
+)
+
+func init() {
+	expvar.Publish("log_entry_size", expvar.Func(func() interface{} {
+		logWriterMu.Lock()
+		defer logWriterMu.Unlock()
+		return entrySizes()
+	}))
+}
+
+// recordSize records an encoded entry of n bytes
+// in the size histogram.
+// The caller must hold logWriterMu.
+func recordSize(n int) {
+	v := int64(n)
+	sizeCount++
+	if v > sizeMax {
+		sizeMax = v
+	}
+	if v > sizeLimit {
+		sizeOver++
+		return
+	}
+	sizeHist.RecordValue(v)
+}
+
+// entrySizes reports the distribution of entry sizes,
+// in bytes, since the process started.
+// Entries larger than sizeLimit are included only
+// in "count", "over", and "max".
+// The caller must hold logWriterMu.
+func entrySizes() map[string]int64 {
+	return map[string]int64{
+		"p50":   sizeHist.ValueAtQuantile(50),
+		"p90":   sizeHist.ValueAtQuantile(90),
+		"p99":   sizeHist.ValueAtQuantile(99),
+		"max":   sizeMax,
+		"over":  sizeOver,
+		"count": sizeCount,
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestEntrySizes(t *testing.T) {
+	out := captureOutput(t)
+
+	logWriterMu.Lock()
+	before := entrySizes()
+	logWriterMu.Unlock()
+
+	Printkv(context.Background(), "big", strings.Repeat("x", 2*sizeLimit))
+
+	logWriterMu.Lock()
+	after := entrySizes()
+	logWriterMu.Unlock()
+
+	if got := after["count"] - before["count"]; got != 1 {
+		t.Errorf("count delta = %d want 1", got)
+	}
+	if got := after["over"] - before["over"]; got != 1 {
+		t.Errorf("over delta = %d want 1", got)
+	}
+	if got, want := after["max"], int64(len(out.String())); got != want {
+		t.Errorf("max = %d want %d", got, want)
+	}
+}
@@ -0,0 +1,61 @@
+package splunk
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/log/internal/batch"
+)
+
+func TestHEC(t *testing.T) {
+	got := make(chan []hecEvent, 1)
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if a := req.Header.Get("Authorization"); a != "Splunk tok" {
+			t.Errorf("Authorization = %q", a)
+		}
+		if fail {
+			fail = false // the retry should succeed
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		var evs []hecEvent
+		dec := json.NewDecoder(req.Body)
+		for {
+			var ev hecEvent
+			if err := dec.Decode(&ev); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Error(err)
+				break
+			}
+			evs = append(evs, ev)
+		}
+		got <- evs
+	}))
+	defer srv.Close()
+
+	w := NewHEC(HECConfig{URL: srv.URL, Token: "tok", Index: "chain", SourceType: "cored"})
+	for _, line := range []string{"at=a.go:1 n=1\n", "at=a.go:2 n=2\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var evs []hecEvent
+	select {
+	case evs = <-got:
+	case <-time.After(5 * batch.FlushInterval):
+		t.Fatal("timed out waiting for events")
+	}
+	if len(evs) != 2 {
+		t.Fatalf("got %d events want 2", len(evs))
+	}
+	if ev := evs[1]; ev.Event != "at=a.go:2 n=2" || ev.Index != "chain" || ev.SourceType != "cored" || ev.Time == "" {
+		t.Errorf("event = %+v", ev)
+	}
+}
@@ -76,3 +76,14 @@ func (s *splunk) Write(p []byte) (n int, err error) {
 	}
 	return n, s.err
 }
+
+// Close closes the connection, if any.
+// A later Write reconnects.
+func (s *splunk) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
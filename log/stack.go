@@ -4,14 +4,34 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"sync"
 )
 
 var skipFunc = map[string]bool{
 	"chain/log.Printkv":            true,
+	"chain/log.printkv":            true,
+	"chain/log.printkvLevel":       true,
 	"chain/log.Printf":             true,
+	"chain/log.Debugf":             true,
+	"chain/log.Warnf":              true,
 	"chain/log.Error":              true,
 	"chain/log.Fatalkv":            true,
 	"chain/log.RecoverAndLogError": true,
+	"chain/log.RecordSince":        true,
+	"chain/log.Deprecated":         true,
+	"chain/log.Audit":              true,
+	"chain/log.WriteFields":        true,
+	"chain/log.WriteIf":            true,
+	"chain/log.Enabled":            true,
+	"chain/log.DebugEnabled":       true,
+	"chain/log.levelEnabled":       true,
+
+	"chain/log.(*Logger).Printkv": true,
+	"chain/log.(*Logger).Printf":  true,
+	"chain/log.(*Logger).Debugf":  true,
+	"chain/log.(*Logger).Error":   true,
+
+	"chain/log.(*lineWriter).Write": true,
 }
 
 // SkipFunc removes the named function from stack traces
@@ -25,20 +45,64 @@ func SkipFunc(name string) {
 	skipFunc[name] = true
 }
 
-// caller returns a string containing filename and line number of
-// the deepest function invocation on the calling goroutine's stack,
+// A callSite is a function invocation at a program counter;
+// an inlined call gives one PC several.
+type callSite struct {
+	fn  string // fully-qualified function name
+	loc string // file:line
+}
+
+var (
+	// callSites caches the functions and locations
+	// of PCs seen by caller, so each call site's cost
+	// is paid once. Once warm, it is only read.
+	callSitesMu sync.RWMutex
+	callSites   = map[uintptr][]callSite{}
+)
+
+// caller returns the fully-qualified name of, and a string
+// containing filename and line number of, the deepest function
+// invocation on the calling goroutine's stack,
 // after skipping functions in skipFunc.
-// If no stack information is available, it returns "?:?".
-func caller() string {
-	for i := 1; ; i++ {
-		// NOTE(kr): This is quadratic in the number of frames we
-		// ultimately have to skip. Consider using Callers instead.
-		pc, file, line, ok := runtime.Caller(i)
-		if !ok {
-			return "?:?"
+// If no stack information is available, it returns "?" and "?:?".
+func caller() (fn, loc string) {
+	var pcs [32]uintptr
+	for skip := 2; ; skip += len(pcs) { // skip runtime.Callers and caller
+		n := runtime.Callers(skip, pcs[:])
+		for _, pc := range pcs[:n] {
+			for _, s := range sitesAt(pc) {
+				if !skipFunc[s.fn] {
+					return s.fn, s.loc
+				}
+			}
 		}
-		if !skipFunc[runtime.FuncForPC(pc).Name()] {
-			return filepath.Base(file) + ":" + strconv.Itoa(line)
+		if n < len(pcs) {
+			return "?", "?:?"
 		}
 	}
 }
+
+// sitesAt returns the invocations at pc, a return address
+// from runtime.Callers, innermost first.
+func sitesAt(pc uintptr) []callSite {
+	callSitesMu.RLock()
+	s, ok := callSites[pc]
+	callSitesMu.RUnlock()
+	if ok {
+		return s
+	}
+	var sites []callSite
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		f, more := frames.Next()
+		sites = append(sites, callSite{f.Function, filepath.Base(f.File) + ":" + strconv.Itoa(f.Line)})
+		if !more {
+			break
+		}
+	}
+
+	callSitesMu.Lock()
+	callSites[pc] = sites
+	callSitesMu.Unlock()
+	return sites
+}
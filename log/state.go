@@ -0,0 +1,105 @@
+package log
+
+import (
+	"expvar"
+	"sort"
+	"time"
+)
+
+func init() {
+	// The log package's counters are published individually,
+	// with the prefix "log_". This adds its configuration.
+	expvar.Publish("log_config", expvar.Func(func() interface{} {
+		st := ReadState()
+		var names []string
+		for name := range st.Sinks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return map[string]interface{}{
+			"level":                 st.Level,
+			"format":                st.Format,
+			"separate_audit_output": st.SeparateAudit,
+			"sinks":                 names,
+		}
+	}))
+}
+
+// State describes the log package's current
+// configuration and counters.
+type State struct {
+	Level            string                      `json:"level"`
+	Format           string                      `json:"format"`
+	VModule          string                      `json:"vmodule"`
+	SeparateAudit    bool                        `json:"separate_audit_output"`
+	MaxBytesPerSec   float64                     `json:"volume_alert_bytes_per_sec"`
+	MaxEntriesPerSec float64                     `json:"volume_alert_entries_per_sec"`
+	Sinks            map[string]SinkState        `json:"sinks"`
+	Entries          map[string]int64            `json:"entries"`
+	Dropped          map[string]map[string]int64 `json:"dropped"` // by cause, then severity
+	Stats            Counts                      `json:"stats"`
+}
+
+// SinkState describes a sink registered with InstrumentSink.
+type SinkState struct {
+	Healthy      bool       `json:"healthy"`
+	Bytes        int64      `json:"bytes"`
+	Dropped      int64      `json:"dropped"`
+	Failures     int64      `json:"consecutive_failures"`
+	FailingSince *time.Time `json:"failing_since,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// ReadState returns the current state of the log package.
+func ReadState() State {
+	st := State{
+		Level:   CurrentLevel().String(),
+		Format:  currentFormat().String(),
+		VModule: currentVModule().spec,
+		Sinks:   make(map[string]SinkState),
+		Entries: make(map[string]int64),
+		Dropped: make(map[string]map[string]int64),
+		Stats:   Stats(),
+	}
+
+	auditMu.Lock()
+	st.SeparateAudit = auditWriter != nil
+	auditMu.Unlock()
+
+	logWriterMu.Lock()
+	st.MaxBytesPerSec, st.MaxEntriesPerSec = volMaxBytes, volMaxEntries
+	logWriterMu.Unlock()
+
+	sinksMu.Lock()
+	for name, s := range sinks {
+		ss := SinkState{
+			Healthy:  s.healthy.Value() == 1,
+			Bytes:    s.bytes.Value(),
+			Dropped:  s.dropped.Value(),
+			Failures: s.failures.Value(),
+		}
+		s.mu.Lock()
+		if !s.failingSince.IsZero() {
+			t := s.failingSince
+			ss.FailingSince = &t
+		}
+		if s.lastErr != nil {
+			ss.LastError = s.lastErr.Error()
+		}
+		s.mu.Unlock()
+		st.Sinks[name] = ss
+	}
+	sinksMu.Unlock()
+
+	entryCounts.Do(func(kv expvar.KeyValue) {
+		st.Entries[kv.Key] = kv.Value.(*expvar.Int).Value()
+	})
+	dropCounts.Do(func(kv expvar.KeyValue) {
+		m := make(map[string]int64)
+		kv.Value.(*expvar.Map).Do(func(kv expvar.KeyValue) {
+			m[kv.Key] = kv.Value.(*expvar.Int).Value()
+		})
+		st.Dropped[kv.Key] = m
+	})
+	return st
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestReadState(t *testing.T) {
+	defer SetOutput(Output())
+	SetOutput(InstrumentSink("state-test", ioutil.Discard))
+	SetLevel(LevelWarning)
+	defer SetLevel(LevelInfo)
+
+	ctx := context.Background()
+	Printkv(ctx, "message", "hello")
+	Error(ctx, errors.New("boom"))
+
+	st := ReadState()
+	if st.Level != "warning" || st.Format != "kv" {
+		t.Errorf("level = %q format = %q, want warning kv", st.Level, st.Format)
+	}
+	if s, ok := st.Sinks["state-test"]; !ok || !s.Healthy || s.Bytes == 0 {
+		t.Errorf("sink = %+v (ok = %t), want healthy with bytes written", s, ok)
+	}
+	if st.Entries["error"] == 0 {
+		t.Errorf("entries = %v, want errors counted", st.Entries)
+	}
+	if st.Dropped[dropLevel]["info"] == 0 {
+		t.Errorf("dropped = %v, want info entries dropped by level", st.Dropped)
+	}
+}
+
+func TestConfigVar(t *testing.T) {
+	InstrumentSink("config-var-test", ioutil.Discard)
+	SetLevel(LevelError)
+	defer SetLevel(LevelInfo)
+
+	got := expvar.Get("log_config").String()
+	for _, w := range []string{`"level":"error"`, `"config-var-test"`} {
+		if !strings.Contains(got, w) {
+			t.Errorf("log_config = %s, want substring %s", got, w)
+		}
+	}
+}
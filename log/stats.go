@@ -0,0 +1,163 @@
+package log
+
+import (
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Severities used to classify log entries.
+// An entry's severity is the value of its KeyLevel field,
+// if that is the name of a Level, or else is inferred
+// from its keys: entries with a KeyError field are errors,
+// entries with a "warning" field are warnings,
+// and all others are informational.
+const (
+	severityDebug   = "debug"
+	severityInfo    = "info"
+	severityWarning = "warning"
+	severityError   = "error"
+)
+
+// The log package's counters are published as expvars
+// with the prefix "log_".
+var (
+	// entryCounts counts entries written, by severity,
+	// and entries produced with a log-error condition.
+	entryCounts = expvar.NewMap("log_entries")
+
+	// dropCounts counts discarded entries,
+	// by cause and then by severity.
+	dropCounts = expvar.NewMap("log_dropped")
+	dropMu     sync.Mutex
+)
+
+// Causes for discarding an entry, as recorded in
+// expvar "log_dropped".
+const (
+	dropWrite  = "write"  // the log output returned an error
+	dropLevel  = "level"  // the entry was below the level set by SetLevel
+	dropFilter = "filter" // a filter registered with AddFilter matched the entry
+	dropSample = "sample" // sampling set by SetSampling discarded the entry
+	dropRepeat = "repeat" // the entry repeated the previous one; see SetDedup
+	dropRate   = "rate"   // the entry's request exceeded SetRequestRateLimit
+	dropHook   = "hook"   // a hook registered with AddHook discarded the entry
+	dropQueue  = "queue"  // the queue of an AsyncWriter or batched sink was full
+)
+
+// severity returns the severity of an entry
+// with the given fields.
+func severity(keyvals []interface{}) string {
+	sev := severityInfo
+	for i := 0; i < len(keyvals); i += 2 {
+		switch keyvals[i] {
+		case KeyLevel:
+			if l, err := ParseLevel(fmt.Sprint(keyvals[i+1])); err == nil {
+				return l.String()
+			}
+		case KeyError:
+			sev = severityError
+		case "warning":
+			if sev == severityInfo {
+				sev = severityWarning
+			}
+		}
+	}
+	return sev
+}
+
+// countDrop records an entry discarded for the given cause.
+func countDrop(cause, sev string) {
+	m, _ := dropCounts.Get(cause).(*expvar.Map)
+	if m == nil {
+		// Set is not atomic with Get;
+		// serialize creation of the per-cause maps.
+		dropMu.Lock()
+		m, _ = dropCounts.Get(cause).(*expvar.Map)
+		if m == nil {
+			m = new(expvar.Map).Init()
+			dropCounts.Set(cause, m)
+		}
+		dropMu.Unlock()
+	}
+	m.Add(sev, 1)
+}
+
+// droppedCount returns the number of entries
+// discarded for cause with severity sev.
+func droppedCount(cause, sev string) int64 {
+	m, _ := dropCounts.Get(cause).(*expvar.Map)
+	if m == nil {
+		return 0
+	}
+	v, _ := m.Get(sev).(*expvar.Int)
+	if v == nil {
+		return 0
+	}
+	return v.Value()
+}
+
+// countEntry records an entry in the severity counters.
+func countEntry(sev string, logError bool) {
+	entryCounts.Add(sev, 1)
+	if logError {
+		entryCounts.Add(keyLogError, 1)
+	}
+}
+
+// Counts holds the totals reported by Stats.
+type Counts struct {
+	Entries     int64 `json:"entries"`      // written to the log output
+	Bytes       int64 `json:"bytes"`        // written to the log output
+	WriteErrors int64 `json:"write_errors"` // writes the log output failed
+	Dropped     int64 `json:"dropped"`      // discarded, for any cause
+}
+
+var (
+	statEntries     int64
+	statBytes       int64
+	statWriteErrors int64
+)
+
+func init() {
+	expvar.Publish("log_stats", expvar.Func(func() interface{} { return Stats() }))
+}
+
+// Stats returns totals of the entries written
+// to the package-level log output since the process
+// started, so that operators can graph logging health
+// and notice entries being lost.
+// Dropped counts every entry discarded, including
+// failed writes; expvar "log_dropped" breaks it down
+// by cause and severity.
+func Stats() Counts {
+	c := Counts{
+		Entries:     atomic.LoadInt64(&statEntries),
+		Bytes:       atomic.LoadInt64(&statBytes),
+		WriteErrors: atomic.LoadInt64(&statWriteErrors),
+	}
+	dropCounts.Do(func(kv expvar.KeyValue) {
+		m, _ := kv.Value.(*expvar.Map)
+		if m == nil {
+			return
+		}
+		m.Do(func(kv expvar.KeyValue) {
+			if v, ok := kv.Value.(*expvar.Int); ok {
+				c.Dropped += v.Value()
+			}
+		})
+	})
+	return c
+}
+
+// recordWrite records a write of an entry to the log output
+// that wrote n bytes and returned err.
+func recordWrite(n int, err error) {
+	atomic.AddInt64(&statBytes, int64(n))
+	if err != nil {
+		atomic.AddInt64(&statWriteErrors, 1)
+		return
+	}
+	atomic.AddInt64(&statEntries, 1)
+}
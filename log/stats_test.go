@@ -0,0 +1,94 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSeverityCounts(t *testing.T) {
+	defer SetOutput(Output())
+	SetOutput(ioutil.Discard)
+
+	get := func(name string) int64 {
+		v, _ := entryCounts.Get(name).(*expvar.Int)
+		if v == nil {
+			return 0
+		}
+		return v.Value()
+	}
+	info0, warn0, err0, logErr0 := get("info"), get("warning"), get("error"), get(keyLogError)
+
+	ctx := context.Background()
+	Printkv(ctx, "message", "hello")
+	Printkv(ctx, "warning", "careful")
+	Error(ctx, errors.New("boom"))
+	Printkv(ctx, "odd")
+
+	cases := []struct {
+		name string
+		got  int64
+		want int64
+	}{
+		{"info", get("info") - info0, 2}, // includes the odd-length entry
+		{"warning", get("warning") - warn0, 1},
+		{"error", get("error") - err0, 1},
+		{keyLogError, get(keyLogError) - logErr0, 1},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("count[%s] increased by %d want %d", c.name, c.got, c.want)
+		}
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) { return 0, errors.New("broken") }
+
+func TestDropCount(t *testing.T) {
+	defer SetOutput(Output())
+	SetOutput(errWriter{})
+
+	info0 := droppedCount(dropWrite, severityInfo)
+	err0 := droppedCount(dropWrite, severityError)
+	Printkv(context.Background(), "message", "lost")
+	Error(context.Background(), errors.New("lost"))
+	if got := droppedCount(dropWrite, severityInfo) - info0; got != 1 {
+		t.Errorf("dropped info count increased by %d want 1", got)
+	}
+	if got := droppedCount(dropWrite, severityError) - err0; got != 1 {
+		t.Errorf("dropped error count increased by %d want 1", got)
+	}
+}
+
+func TestStats(t *testing.T) {
+	defer SetOutput(Output())
+	ctx := context.Background()
+
+	SetOutput(ioutil.Discard)
+	s0 := Stats()
+	Printkv(ctx, "message", "kept")
+	s1 := Stats()
+	if n := s1.Entries - s0.Entries; n != 1 {
+		t.Errorf("Entries increased by %d want 1", n)
+	}
+	if s1.Bytes <= s0.Bytes {
+		t.Errorf("Bytes = %d, want more than %d", s1.Bytes, s0.Bytes)
+	}
+
+	SetOutput(errWriter{})
+	Printkv(ctx, "message", "lost")
+	s2 := Stats()
+	if n := s2.WriteErrors - s1.WriteErrors; n != 1 {
+		t.Errorf("WriteErrors increased by %d want 1", n)
+	}
+	if n := s2.Dropped - s1.Dropped; n != 1 {
+		t.Errorf("Dropped increased by %d want 1", n)
+	}
+	if s2.Entries != s1.Entries {
+		t.Errorf("Entries = %d after a failed write, want %d", s2.Entries, s1.Entries)
+	}
+}
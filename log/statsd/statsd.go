@@ -0,0 +1,184 @@
+// Package statsd exports the counters maintained by
+// package log to a StatsD or DogStatsD server.
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"chain/log"
+)
+
+// maxPacket is the largest datagram sent.
+// It fits in the smallest common MTU after IP and UDP headers.
+const maxPacket = 1432
+
+// A metric describes how to export one of the log
+// package's expvars. The expvar is a map, nested once
+// per label, with integer leaves.
+type metric struct {
+	expvar string
+	name   string
+	labels []string
+	gauge  bool // otherwise a counter
+}
+
+var metrics = []metric{
+	{"log_entries", "log.entries", []string{"severity"}, false},
+	{"log_dropped", "log.dropped", []string{"cause", "severity"}, false},
+	{"log_sink_healthy", "log.sink.healthy", []string{"sink"}, true},
+	{"log_sink_failures", "log.sink.failures", []string{"sink"}, true},
+	{"log_sink_dropped", "log.sink.dropped", []string{"sink"}, false},
+	{"log_sink_bytes", "log.sink.bytes", []string{"sink"}, false},
+}
+
+// An Exporter periodically sends the log package's
+// severity counts, drop counts, and sink stats to
+// a StatsD server.
+type Exporter struct {
+	w         io.Writer // each Write is one packet
+	prefix    string
+	dogstatsd bool
+	last      map[string]int64 // previous counter values
+}
+
+// New returns an Exporter that sends metrics over UDP to addr.
+// Each metric name is prefixed with prefix.
+// If dogstatsd is true, labels such as the severity and
+// sink name are sent as DogStatsD tags; otherwise they
+// are appended to the metric name, separated by dots.
+func New(addr, prefix string, dogstatsd bool) (*Exporter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newExporter(conn, prefix, dogstatsd), nil
+}
+
+func newExporter(w io.Writer, prefix string, dogstatsd bool) *Exporter {
+	return &Exporter{
+		w:         w,
+		prefix:    prefix,
+		dogstatsd: dogstatsd,
+		last:      make(map[string]int64),
+	}
+}
+
+// Run sends the current metrics once per interval
+// until ctx is done.
+// Counters are sent as the change since the previous send;
+// gauges are sent every time.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.flush(); err != nil {
+				log.Error(ctx, err, "sending statsd metrics")
+			}
+		}
+	}
+}
+
+func (e *Exporter) flush() error {
+	var lines [][]byte
+	for _, m := range metrics {
+		v, _ := expvar.Get(m.expvar).(*expvar.Map)
+		if v == nil {
+			continue
+		}
+		walk(v, nil, func(labels []string, n int64) {
+			if len(labels) != len(m.labels) {
+				return
+			}
+			if line := e.line(m, labels, n); line != nil {
+				lines = append(lines, line)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+1+len(line) > maxPacket {
+			if _, err := e.w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(line)
+	}
+	if buf.Len() > 0 {
+		_, err := e.w.Write(buf.Bytes())
+		return err
+	}
+	return nil
+}
+
+// line formats a single StatsD line for m with the given
+// label values, or returns nil if there is nothing to send.
+func (e *Exporter) line(m metric, labels []string, n int64) []byte {
+	name := e.prefix + m.name
+	var tags []byte
+	for i, l := range labels {
+		if e.dogstatsd {
+			if len(tags) > 0 {
+				tags = append(tags, ',')
+			}
+			tags = append(tags, m.labels[i]...)
+			tags = append(tags, ':')
+			tags = append(tags, l...)
+		} else {
+			name += "." + l
+		}
+	}
+
+	typ := "g"
+	if !m.gauge {
+		typ = "c"
+		key := name + "|" + string(tags)
+		n, e.last[key] = n-e.last[key], n
+		if n == 0 {
+			return nil
+		}
+	}
+
+	b := []byte(name)
+	b = append(b, ':')
+	b = strconv.AppendInt(b, n, 10)
+	b = append(b, '|')
+	b = append(b, typ...)
+	if len(tags) > 0 {
+		b = append(b, "|#"...)
+		b = append(b, tags...)
+	}
+	return b
+}
+
+// walk calls f for each integer leaf in m, in key order,
+// with the keys leading to it.
+func walk(m *expvar.Map, keys []string, f func([]string, int64)) {
+	var names []string
+	m.Do(func(kv expvar.KeyValue) { names = append(names, kv.Key) })
+	sort.Strings(names)
+	for _, k := range names {
+		path := append(keys[:len(keys):len(keys)], k)
+		switch v := m.Get(k).(type) {
+		case *expvar.Int:
+			f(path, v.Value())
+		case *expvar.Map:
+			walk(v, path, f)
+		}
+	}
+}
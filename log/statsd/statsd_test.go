@@ -0,0 +1,107 @@
+package statsd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"chain/log"
+	"chain/log/logtest"
+)
+
+type packets [][]byte
+
+func (p *packets) Write(b []byte) (int, error) {
+	*p = append(*p, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func (p packets) lines() []string {
+	var a []string
+	for _, b := range p {
+		a = append(a, strings.Split(string(b), "\n")...)
+	}
+	return a
+}
+
+func contains(lines []string, s string) bool {
+	for _, l := range lines {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFlush(t *testing.T) {
+	logtest.Capture(t)
+
+	ctx := context.Background()
+	log.Printkv(ctx, "message", "warm up") // ensure the counter exists
+
+	var p packets
+	e := newExporter(&p, "chain.", false)
+	if err := e.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	log.Printkv(ctx, "message", "a")
+	log.Printkv(ctx, "message", "b")
+	log.Error(ctx, errors.New("c"))
+	p = nil
+	if err := e.flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := p.lines()
+	for _, want := range []string{"chain.log.entries.info:2|c", "chain.log.entries.error:1|c"} {
+		if !contains(lines, want) {
+			t.Errorf("lines = %q, want %q", lines, want)
+		}
+	}
+
+	// Counters that haven't changed are not sent.
+	p = nil
+	if err := e.flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range p.lines() {
+		if strings.HasPrefix(l, "chain.log.entries.") {
+			t.Errorf("unchanged counter sent: %q", l)
+		}
+	}
+}
+
+func TestDogStatsD(t *testing.T) {
+	e := newExporter(nil, "", true)
+	m := metric{"x", "log.dropped", []string{"cause", "severity"}, false}
+	got := string(e.line(m, []string{"write", "info"}, 3))
+	if want := "log.dropped:3|c|#cause:write,severity:info"; got != want {
+		t.Errorf("line = %q want %q", got, want)
+	}
+
+	g := metric{"x", "log.sink.healthy", []string{"sink"}, true}
+	got = string(e.line(g, []string{"stdout"}, 1))
+	if want := "log.sink.healthy:1|g|#sink:stdout"; got != want {
+		t.Errorf("line = %q want %q", got, want)
+	}
+}
+
+func TestPacketSize(t *testing.T) {
+	var p packets
+	e := newExporter(&p, strings.Repeat("x", 700), false)
+	logtest.Capture(t)
+	log.Printkv(context.Background())
+	log.Error(context.Background(), errors.New("e"))
+	if err := e.flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range p {
+		if len(b) > maxPacket {
+			t.Errorf("packet length = %d, want at most %d", len(b), maxPacket)
+		}
+	}
+	if len(p) < 2 {
+		t.Errorf("got %d packets, want at least 2", len(p))
+	}
+}
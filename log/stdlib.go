@@ -0,0 +1,45 @@
+package log
+
+import (
+	"context"
+	stdlog "log"
+	"strings"
+)
+
+// KeySource identifies entries captured from another logging
+// package, such as the standard library's, by CaptureStdlib.
+const KeySource = "source"
+
+// CaptureStdlib redirects the output of the standard library's
+// default logger, used by net/http internals and many
+// third-party packages, to the package-level output.
+// Each line becomes an entry with a KeySource field
+// containing source and a message containing the line's text.
+// The at=[file:line] field is the location reported
+// by the standard logger.
+//
+// CaptureStdlib replaces the standard logger's prefix and flags.
+// Writers used as the package-level output must not write
+// to the standard logger.
+func CaptureStdlib(source string) {
+	stdlog.SetPrefix("")
+	stdlog.SetFlags(stdlog.Lshortfile)
+	stdlog.SetOutput(&stdlibWriter{source: source})
+}
+
+type stdlibWriter struct {
+	source string
+}
+
+func (w *stdlibWriter) Write(p []byte) (int, error) {
+	// With flag Lshortfile, lines have the form
+	// "file.go:123: message".
+	line := strings.TrimSuffix(string(p), "\n")
+	loc := "?:?"
+	if i := strings.Index(line, ": "); i > 0 {
+		loc, line = line[:i], line[i+2:]
+	}
+	where := func() (string, string) { return w.source, loc }
+	printkv(context.Background(), where, "", []interface{}{KeySource, w.source, KeyMessage, line})
+	return len(p), nil
+}
@@ -0,0 +1,28 @@
+package log
+
+import (
+	stdlog "log"
+	"os"
+	"strings"
+	"testing"
+
+	"chain/log/internal/capture"
+)
+
+func TestCaptureStdlib(t *testing.T) {
+	out := captureOutput(t)
+	CaptureStdlib("stdlib")
+	defer stdlog.SetOutput(os.Stderr)
+	defer stdlog.SetFlags(stdlog.LstdFlags)
+
+	stdlog.Printf("http: TLS handshake error from %s", "10.0.0.1:5000")
+
+	entries := out.Entries()
+	want := capture.Entry{KeySource: "stdlib", KeyMessage: "http: TLS handshake error from 10.0.0.1:5000"}
+	if len(entries) != 1 || !entries[0].Matches(want) {
+		t.Fatalf("entries = %v, want %v", entries, want)
+	}
+	if at := entries[0][KeyCaller]; !strings.HasPrefix(at, "stdlib_test.go:") {
+		t.Errorf("at = %q, want stdlib_test.go:*", at)
+	}
+}
@@ -0,0 +1,31 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+// summary returns a short description of an entry
+// with the given fields, for formats that need one:
+// its KeyMessage, KeyError, or "warning" field,
+// in that order of preference, or else its fields
+// in the KV format. It is never empty.
+func summary(fields []interface{}) string {
+	for _, key := range []string{KeyMessage, KeyError, "warning"} {
+		for i := 0; i < len(fields); i += 2 {
+			if fields[i] == key {
+				if s := fmt.Sprint(fields[i+1]); s != "" {
+					return s
+				}
+			}
+		}
+	}
+	var a []string
+	for i := 0; i < len(fields); i += 2 {
+		a = append(a, formatKey(fields[i])+"="+formatValue(fields[i+1]))
+	}
+	if len(a) == 0 {
+		return "-"
+	}
+	return strings.Join(a, " ")
+}
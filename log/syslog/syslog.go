@@ -0,0 +1,239 @@
+// Package syslog sends log entries in the RFC5424 format
+// (see chain/log.RFC5424) to the local syslog daemon
+// or to a remote syslog server.
+package syslog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// DialTimeout limits how long a write will block
+	// while dialing the syslog server.
+	DialTimeout = 50 * time.Millisecond
+
+	// WriteTimeout limits how long a write will block
+	// sending data on a stream connection.
+	// As in package chain/log/gelf, it is deliberately
+	// small, so a slow server drops entries rather
+	// than blocking the process.
+	WriteTimeout = 100 * time.Microsecond
+)
+
+// Facilities, as defined in RFC 5424.
+const (
+	Kern = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	LPR
+	News
+	UUCP
+	Cron
+	AuthPriv
+	FTP
+	_ // NTP
+	_ // log audit
+	_ // log alert
+	_ // clock daemon
+	Local0
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+var facilityNames = map[string]int{
+	"kern":     Kern,
+	"user":     User,
+	"mail":     Mail,
+	"daemon":   Daemon,
+	"auth":     Auth,
+	"syslog":   Syslog,
+	"lpr":      LPR,
+	"news":     News,
+	"uucp":     UUCP,
+	"cron":     Cron,
+	"authpriv": AuthPriv,
+	"ftp":      FTP,
+	"local0":   Local0,
+	"local1":   Local1,
+	"local2":   Local2,
+	"local3":   Local3,
+	"local4":   Local4,
+	"local5":   Local5,
+	"local6":   Local6,
+	"local7":   Local7,
+}
+
+// ParseFacility returns the facility with the given name,
+// such as "daemon" or "local0".
+func ParseFacility(name string) (int, error) {
+	f, ok := facilityNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %q", name)
+	}
+	return f, nil
+}
+
+var hostname, _ = os.Hostname()
+
+// localAddrs are the usual paths of the local syslog socket.
+var localAddrs = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// ErrNoLocal is returned by a local writer
+// if no syslog socket was found.
+var ErrNoLocal = errors.New("no local syslog socket")
+
+type writer struct {
+	network  string
+	addr     string
+	facility int
+	tag      string
+	conn     net.Conn
+	stream   bool
+}
+
+// New creates a new writer that sends each written
+// RFC5424 message, as one entry formatted by chain/log,
+// to the given address, replacing the message's facility
+// with facility and, if tag is not empty, its APP-NAME with tag.
+// The message's severity is kept, so entries
+// with an error are sent with severity err,
+// warnings with severity warning,
+// and other entries with severity informational.
+// Written data that is not an RFC5424 message
+// is sent as the MSG of one, with severity informational.
+//
+// The network must be "udp", "tcp", "tls", or empty.
+// If it is empty, the writer sends to the local
+// syslog daemon and addr is ignored.
+//
+// It connects on the first call to Write,
+// and reconnects after a write error.
+// Over TCP and TLS, messages are framed by octet counting,
+// as in RFC 5425 and RFC 6587, and writes have
+// a timeout of WriteTimeout.
+func New(network, addr string, facility int, tag string) io.Writer {
+	return &writer{network: network, addr: addr, facility: facility, tag: tag}
+}
+
+func (w *writer) Write(p []byte) (n int, err error) {
+	if w.conn == nil {
+		err = w.dial()
+		if err != nil {
+			return 0, err
+		}
+	}
+	msg := w.message(bytes.TrimRight(p, "\n"))
+	if w.stream {
+		w.conn.SetDeadline(time.Now().Add(WriteTimeout))
+	}
+	_, err = w.conn.Write(msg)
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection, if any.
+// A later Write reconnects.
+func (w *writer) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (w *writer) dial() (err error) {
+	switch w.network {
+	case "":
+		for _, addr := range localAddrs {
+			for _, network := range []string{"unixgram", "unix"} {
+				w.conn, err = net.DialTimeout(network, addr, DialTimeout)
+				if err == nil {
+					w.stream = network == "unix"
+					return nil
+				}
+			}
+		}
+		return ErrNoLocal
+	case "tls":
+		d := &net.Dialer{Timeout: DialTimeout}
+		w.conn, err = tls.DialWithDialer(d, "tcp", w.addr, nil)
+		w.stream = true
+	default:
+		w.conn, err = net.DialTimeout(w.network, w.addr, DialTimeout)
+		w.stream = w.network != "udp"
+	}
+	return err
+}
+
+// message returns entry with w's facility and tag,
+// framed for w's connection.
+func (w *writer) message(entry []byte) []byte {
+	var buf bytes.Buffer
+	sev, fields, ok := parse(entry)
+	if !ok {
+		sev = 6
+		fields = [][]byte{
+			[]byte(time.Now().UTC().Format(time.RFC3339Nano)),
+			[]byte(hostname),
+			[]byte("-"),
+			append([]byte(strconv.Itoa(os.Getpid())+" - - "), entry...),
+		}
+	}
+	if w.tag != "" {
+		fields[2] = []byte(w.tag)
+	}
+	fmt.Fprintf(&buf, "<%d>1 ", w.facility*8+sev)
+	buf.Write(bytes.Join(fields, []byte(" ")))
+
+	switch {
+	case w.stream && w.network == "":
+		buf.WriteByte('\n')
+	case w.stream:
+		return append([]byte(strconv.Itoa(buf.Len())+" "), buf.Bytes()...)
+	}
+	return buf.Bytes()
+}
+
+// parse parses the PRI and VERSION at the start
+// of an RFC5424 message, returning the message's
+// severity and the rest of the message split into
+// TIMESTAMP, HOSTNAME, APP-NAME, and the remainder.
+func parse(entry []byte) (sev int, fields [][]byte, ok bool) {
+	if len(entry) == 0 || entry[0] != '<' {
+		return 0, nil, false
+	}
+	i := bytes.Index(entry, []byte(">1 "))
+	if i < 2 || i > 4 {
+		return 0, nil, false
+	}
+	pri, err := strconv.Atoi(string(entry[1:i]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, false
+	}
+	fields = bytes.SplitN(entry[i+3:], []byte(" "), 4)
+	if len(fields) < 4 {
+		return 0, nil, false
+	}
+	return pri % 8, fields, true
+}
@@ -0,0 +1,76 @@
+package syslog
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	const entry = `<27>1 2017-03-01T00:00:00.000000Z host cored 42 - [fields@32473 at="a.go:1" error="x y"] x y`
+	cases := []struct {
+		w    *writer
+		in   string
+		want string
+	}{{
+		w:    &writer{network: "udp", facility: Local0},
+		in:   entry,
+		want: `<131>1 2017-03-01T00:00:00.000000Z host cored 42 - [fields@32473 at="a.go:1" error="x y"] x y`,
+	}, {
+		w:    &writer{network: "udp", facility: Daemon, tag: "chain"},
+		in:   entry,
+		want: `<27>1 2017-03-01T00:00:00.000000Z host chain 42 - [fields@32473 at="a.go:1" error="x y"] x y`,
+	}, {
+		w:    &writer{network: "tcp", stream: true, facility: Daemon},
+		in:   `<30>1 t h a 1 - - m`,
+		want: `19 <30>1 t h a 1 - - m`,
+	}, {
+		w:    &writer{stream: true, facility: Daemon},
+		in:   `<30>1 t h a 1 - - m`,
+		want: "<30>1 t h a 1 - - m\n",
+	}}
+	for _, c := range cases {
+		got := string(c.w.message([]byte(c.in)))
+		if got != c.want {
+			t.Errorf("message(%q) = %q want %q", c.in, got, c.want)
+		}
+	}
+
+	w := &writer{network: "udp", facility: User, tag: "chain"}
+	got := string(w.message([]byte("t=now message=hi")))
+	if !strings.HasPrefix(got, "<14>1 ") || !strings.Contains(got, " chain ") || !strings.HasSuffix(got, " - - t=now message=hi") {
+		t.Errorf("message(non-RFC5424) = %q", got)
+	}
+}
+
+func TestWriteUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := New("udp", conn.LocalAddr().String(), Local7, "")
+	_, err = w.Write([]byte("<27>1 t h a 1 - - m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 100)
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "<187>1 t h a 1 - - m"
+	if got := string(b[:n]); got != want {
+		t.Errorf("got %q want %q", got, want)
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	if f, err := ParseFacility("local3"); err != nil || f != 19 {
+		t.Errorf("ParseFacility(local3) = %d, %v want 19", f, err)
+	}
+	if _, err := ParseFacility("bogus"); err == nil {
+		t.Error("ParseFacility(bogus) err = nil")
+	}
+}
@@ -0,0 +1,128 @@
+package log
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Layouts for SetTimeFormat that are not time package layouts.
+const (
+	TimeEpochMillis = "epochmillis" // milliseconds since the Unix epoch
+	TimeEpochNanos  = "epochnanos"  // nanoseconds since the Unix epoch
+)
+
+// timeFormat holds the *timeConfig set by SetTimeFormat.
+var timeFormat atomic.Value
+
+type timeConfig struct {
+	layout string
+	loc    *time.Location
+
+	// If the layout's only element finer than a second is
+	// one fraction of a second with a fixed number of digits,
+	// or there is none, head and tail are the layout
+	// before and after it, and digits is its length.
+	// Otherwise, cached is false.
+	cached     bool
+	head, tail string
+	digits     int
+
+	last atomic.Value // the *secondText of the latest second formatted
+}
+
+// A secondText is the text of a layout's head and tail
+// at one second.
+type secondText struct {
+	sec        int64 // since the Unix epoch
+	head, tail string
+}
+
+func init() {
+	SetTimeFormat(rfc3339NanoFixed, time.UTC)
+}
+
+// SetTimeFormat sets the encoding of the KeyTime field
+// in the KV format. Layout is either a layout
+// as for time.Time.Format, in which case the time
+// is first converted to loc, or one of TimeEpochMillis
+// and TimeEpochNanos, in which case loc is ignored.
+// The default is RFC 3339 with nanoseconds, in UTC.
+func SetTimeFormat(layout string, loc *time.Location) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	c := &timeConfig{layout: layout, loc: loc}
+	c.head, c.tail, c.digits, c.cached = splitFraction(layout)
+	timeFormat.Store(c)
+}
+
+// splitFraction returns layout before and after its
+// fraction of a second, such as ".000", and the fraction's
+// number of digits, if the fraction is the only element finer
+// than a second, and has a fixed number of digits, or there
+// is none, and reports whether it is.
+func splitFraction(layout string) (head, tail string, digits int, ok bool) {
+	start := -1
+	for i := 0; i+1 < len(layout); i++ {
+		if c := layout[i]; c != '.' && c != ',' {
+			continue
+		}
+		d := layout[i+1]
+		if d != '0' && d != '9' {
+			continue
+		}
+		j := i + 1
+		for j < len(layout) && layout[j] == d {
+			j++
+		}
+		if j < len(layout) && layout[j] >= '0' && layout[j] <= '9' {
+			continue // not a fraction, as in time.Format
+		}
+		if d == '9' || start >= 0 || j-i-1 > 9 {
+			return "", "", 0, false // trimmed, or more than one
+		}
+		start, digits = i, j-i-1
+		i = j - 1
+	}
+	if start < 0 {
+		return layout, "", 0, true
+	}
+	return layout[:start+1], layout[start+1+digits:], digits, true
+}
+
+// formatTime returns t encoded as set by SetTimeFormat.
+// For layouts that allow it, the text of the
+// current second is formatted only once.
+func formatTime(t time.Time) string {
+	c := timeFormat.Load().(*timeConfig)
+	switch c.layout {
+	case TimeEpochMillis:
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case TimeEpochNanos:
+		return strconv.FormatInt(t.UnixNano(), 10)
+	}
+	t = t.In(c.loc)
+	if !c.cached {
+		return t.Format(c.layout)
+	}
+	sec := t.Unix()
+	st, _ := c.last.Load().(*secondText)
+	if st == nil || st.sec != sec {
+		st = &secondText{sec: sec, head: t.Format(c.head)}
+		if c.tail != "" {
+			st.tail = t.Format(c.tail)
+		}
+		c.last.Store(st)
+	}
+	if c.digits == 0 {
+		return st.head
+	}
+	var frac [9]byte
+	ns := t.Nanosecond()
+	for i := 8; i >= 0; i-- {
+		frac[i] = byte('0' + ns%10)
+		ns /= 10
+	}
+	return st.head + string(frac[:c.digits]) + st.tail
+}
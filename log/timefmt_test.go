@@ -0,0 +1,61 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatTime(t *testing.T) {
+	defer SetTimeFormat(rfc3339NanoFixed, time.UTC)
+	est := time.FixedZone("EST", -5*60*60)
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	cases := []struct {
+		layout string
+		loc    *time.Location
+		want   string
+	}{
+		{rfc3339NanoFixed, time.UTC, "2017-03-01T13:04:05.006000000Z"},
+		{time.RFC3339, est, "2017-03-01T08:04:05-05:00"},
+		{time.RFC3339Nano, nil, "2017-03-01T13:04:05.006Z"},
+		{TimeEpochMillis, est, "1488373445006"},
+		{TimeEpochNanos, nil, "1488373445006000000"},
+	}
+	for _, c := range cases {
+		SetTimeFormat(c.layout, c.loc)
+		if got := formatTime(ts); got != c.want {
+			t.Errorf("formatTime with (%q, %v) = %q want %q", c.layout, c.loc, got, c.want)
+		}
+	}
+}
+
+func TestFormatTimeCached(t *testing.T) {
+	defer SetTimeFormat(rfc3339NanoFixed, time.UTC)
+	est := time.FixedZone("EST", -5*60*60)
+	layouts := []string{
+		rfc3339NanoFixed,
+		time.RFC3339,
+		time.RFC3339Nano, // trimmed fraction, not cached
+		"2006-01-02 15:04:05.000 MST",
+		"15:04:05,000000 Jan 2",
+		"Mon 2006.01.02 3:04PM", // dots that aren't fractions
+		time.StampMicro,
+	}
+	t0 := time.Date(2017, 3, 1, 13, 4, 5, 6e6, time.UTC)
+	times := []time.Time{t0, t0.Add(123456789), t0.Add(time.Second), t0.Add(time.Second + 1), t0}
+	for _, layout := range layouts {
+		SetTimeFormat(layout, est)
+		for _, ts := range times {
+			if got, want := formatTime(ts), ts.In(est).Format(layout); got != want {
+				t.Errorf("formatTime(%v) with %q = %q want %q", ts, layout, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkFormatTime(b *testing.B) {
+	t0 := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		formatTime(t0.Add(time.Duration(i)))
+	}
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"chain/metrics"
+)
+
+// timingLimit is the largest duration recorded precisely
+// in the histograms created by RecordSince.
+const timingLimit = 10 * time.Second
+
+var (
+	timingsMu sync.Mutex
+	timings   = map[string]*metrics.RotatingLatency{}
+)
+
+// RecordSince writes an entry with the time elapsed since t0
+// and records the same duration in a rotating latency
+// histogram published with metrics.PublishLatency
+// under name.
+// The entry has fields "timing" (name) and "duration",
+// followed by keyvals.
+//
+// It returns the elapsed time.
+func RecordSince(ctx context.Context, name string, t0 time.Time, keyvals ...interface{}) time.Duration {
+	d := time.Since(t0)
+	timing(name).Record(d)
+	Printkv(ctx, append([]interface{}{"timing", name, "duration", d}, keyvals...)...)
+	return d
+}
+
+// Since returns a new context with a prefix field, key,
+// holding the time elapsed since t0, so that the duration
+// of each step of an operation is logged with the
+// operation's later entries:
+//
+//	t0 := time.Now()
+//	err := validate(block)
+//	ctx = log.Since(ctx, t0, "validate")
+//
+// The duration is encoded as set by SetDurationUnit.
+func Since(ctx context.Context, t0 time.Time, key string) context.Context {
+	return AddPrefixkv(ctx, key, time.Since(t0))
+}
+
+func timing(name string) *metrics.RotatingLatency {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	l := timings[name]
+	if l == nil {
+		l = metrics.NewRotatingLatency(5, timingLimit)
+		timings[name] = l
+		metrics.PublishLatency(name, l)
+	}
+	return l
+}
@@ -0,0 +1,35 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordSince(t *testing.T) {
+	out := captureOutput(t)
+
+	t0 := time.Now().Add(-time.Second)
+	d := RecordSince(context.Background(), "test.op", t0, "height", 7)
+	if d < time.Second {
+		t.Errorf("duration = %v, want at least 1s", d)
+	}
+	entries := out.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want 1", entries)
+	}
+	e := entries[0]
+	if e["timing"] != "test.op" || e["height"] != "7" {
+		t.Errorf("entry = %v, want timing=test.op height=7", e)
+	}
+	if got, err := time.ParseDuration(e["duration"]); err != nil || got != d {
+		t.Errorf("duration = %q, want %v", e["duration"], d)
+	}
+	if at := e[KeyCaller]; !strings.HasPrefix(at, "timing_test.go:") {
+		t.Errorf("at = %q, want timing_test.go:*", at)
+	}
+	if q := timing("test.op").Quantiles(100)[0]; q < time.Second {
+		t.Errorf("recorded max = %v, want at least 1s", q)
+	}
+}
@@ -0,0 +1,121 @@
+package log
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	debugBufSize int32 // set by SetDebugBuffer; 0 disables buffering
+
+	debugBufMu         sync.Mutex // protects the following
+	debugBufs          map[string]*debugRing
+	debugBufLastPruned time.Time
+)
+
+// A debugRing holds the latest debug entries of one request.
+type debugRing struct {
+	entries [][]byte // formatted, oldest first
+	last    time.Time
+}
+
+// SetDebugBuffer keeps, for each request, the latest n entries
+// at LevelDebug that the minimum level would discard,
+// and writes them when the request logs an error,
+// just before the error itself. This gives the full context
+// of a failed request without writing the debug entries
+// of every request that succeeds.
+// A request is identified by the "reqid" prefix field of its
+// Context (see chain/net/http/reqid); entries without one,
+// and entries older than a minute, are discarded as usual.
+// It has no effect on a Logger with an output of its own.
+// An n of zero disables buffering, the default.
+func SetDebugBuffer(n int) {
+	debugBufMu.Lock()
+	defer debugBufMu.Unlock()
+	for _, r := range debugBufs {
+		for range r.entries {
+			countDrop(dropLevel, severityDebug)
+		}
+	}
+	debugBufs = nil
+	atomic.StoreInt32(&debugBufSize, int32(n))
+}
+
+// debugBufferEnabled reports whether SetDebugBuffer
+// has enabled buffering.
+func debugBufferEnabled() bool {
+	return atomic.LoadInt32(&debugBufSize) > 0
+}
+
+// bufferDebug keeps a debug entry, with the caller's location
+// provided by where, for the request in ctx,
+// and reports whether it did.
+func bufferDebug(ctx context.Context, where func() (fn, loc string), keyvals []interface{}) bool {
+	id := requestID(ctx)
+	if id == "" {
+		return false
+	}
+	t := time.Now().UTC()
+	_, loc := where()
+	keepDebug(id, t, formatEntry(ctx, currentFormat(), processPrefix(), severityDebug, t, loc, keyvals))
+	return true
+}
+
+// keepDebug keeps entry, a debug entry logged at time t
+// for the request with ID id.
+func keepDebug(id string, t time.Time, entry []byte) {
+	debugBufMu.Lock()
+	defer debugBufMu.Unlock()
+	n := int(atomic.LoadInt32(&debugBufSize))
+	if n <= 0 {
+		countDrop(dropLevel, severityDebug) // disabled meanwhile
+		return
+	}
+	if debugBufs == nil {
+		debugBufs = make(map[string]*debugRing)
+	}
+	if t.Sub(debugBufLastPruned) >= reqIdle {
+		for id, r := range debugBufs {
+			if t.Sub(r.last) >= reqIdle {
+				for range r.entries {
+					countDrop(dropLevel, severityDebug)
+				}
+				delete(debugBufs, id)
+			}
+		}
+		debugBufLastPruned = t
+	}
+	r := debugBufs[id]
+	if r == nil {
+		r = new(debugRing)
+		debugBufs[id] = r
+	}
+	if len(r.entries) >= n {
+		countDrop(dropLevel, severityDebug)
+		r.entries = append(r.entries[:0], r.entries[len(r.entries)-n+1:]...)
+	}
+	r.entries = append(r.entries, entry)
+	r.last = t
+}
+
+// flushDebug writes the buffered debug entries
+// of the request with ID id.
+func flushDebug(id string) {
+	if id == "" {
+		return
+	}
+	debugBufMu.Lock()
+	r := debugBufs[id]
+	delete(debugBufs, id)
+	debugBufMu.Unlock()
+	if r == nil {
+		return
+	}
+	for _, entry := range r.entries {
+		countEntry(severityDebug, false)
+		writeEntry(r.last, severityDebug, entry)
+	}
+}
@@ -0,0 +1,47 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDebugBuffer(t *testing.T) {
+	out := captureOutput(t)
+	SetDebugBuffer(3)
+	defer SetDebugBuffer(0)
+
+	ok := AddPrefixkv(context.Background(), "reqid", "ok")
+	failed := AddPrefixkv(context.Background(), "reqid", "failed")
+	for i := 0; i < 5; i++ {
+		Debugf(ok, "step %d", i)
+		Debugf(failed, "step %d", i)
+	}
+	Debugf(context.Background(), "no request")
+	if got := out.Entries(); len(got) != 0 {
+		t.Fatalf("entries = %v, want nothing before an error", got)
+	}
+
+	// The last 3 debug entries of the failed request,
+	// oldest first, then the error.
+	Error(failed, errors.New("boom"))
+	entries := out.Entries()
+	want := []string{"step 2", "step 3", "step 4"}
+	if len(entries) != len(want)+1 {
+		t.Fatalf("entries = %v, want %d debug entries and the error", entries, len(want))
+	}
+	for i, w := range want {
+		if entries[i]["reqid"] != "failed" || entries[i][KeyMessage] != w {
+			t.Errorf("entry %d = %v, want reqid=failed message=%q", i, entries[i], w)
+		}
+	}
+	if e := entries[len(want)]; e[KeyError] != "boom" {
+		t.Errorf("last entry = %v, want error=boom", e)
+	}
+
+	out.Reset()
+	Error(failed, errors.New("again"))
+	if got := out.Entries(); len(got) != 1 || got[0][KeyError] != "again" {
+		t.Errorf("entries = %v, want buffered entries written once", got)
+	}
+}
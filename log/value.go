@@ -0,0 +1,257 @@
+package log
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// A BytesEncoding is an encoding of []byte field values.
+type BytesEncoding int32
+
+// Encodings for SetBytesEncoding.
+const (
+	BytesHex    BytesEncoding = iota // lower-case hexadecimal, the default
+	BytesBase64                      // standard base64, with padding
+)
+
+// DefaultBytesMax is the default limit set by SetBytesEncoding.
+const DefaultBytesMax = 64
+
+var (
+	bytesEncoding int32 = int32(BytesHex)
+	bytesMax      int32 = DefaultBytesMax
+)
+
+// SetBytesEncoding sets the encoding of []byte field values,
+// such as hashes, IDs, and keys, in every format,
+// in place of fmt's list of decimal numbers.
+// At most max bytes of a value are encoded;
+// a longer value is truncated and followed by "...".
+// A max of zero or less means no limit.
+func SetBytesEncoding(e BytesEncoding, max int) {
+	atomic.StoreInt32(&bytesEncoding, int32(e))
+	atomic.StoreInt32(&bytesMax, int32(max))
+}
+
+// bytesString returns b encoded as set by SetBytesEncoding.
+func bytesString(b []byte) string {
+	var suffix string
+	if max := int(atomic.LoadInt32(&bytesMax)); max > 0 && len(b) > max {
+		b, suffix = b[:max], "..."
+	}
+	if BytesEncoding(atomic.LoadInt32(&bytesEncoding)) == BytesBase64 {
+		return base64.StdEncoding.EncodeToString(b) + suffix
+	}
+	return hex.EncodeToString(b) + suffix
+}
+
+// durationUnit holds the unit set by SetDurationUnit.
+var durationUnit int64
+
+// SetDurationUnit sets the encoding of time.Duration field values,
+// in every format. With a unit of zero, the default, they are written
+// as by Duration.String, such as "1.5s" or "250ms". Otherwise, they
+// are written as a number of units, such as 1500 and 250 for
+// time.Millisecond, so that every latency field has the same unit
+// and can be compared and aggregated numerically;
+// the JSON format writes them as numbers.
+func SetDurationUnit(unit time.Duration) {
+	atomic.StoreInt64(&durationUnit, int64(unit))
+}
+
+// durationString returns d encoded as set by SetDurationUnit.
+func durationString(d time.Duration) string {
+	u := atomic.LoadInt64(&durationUnit)
+	if u <= 0 {
+		return d.String()
+	}
+	return strconv.FormatFloat(float64(d)/float64(u), 'f', -1, 64)
+}
+
+// valueString returns the text of a field value
+// for the formats that write values as strings.
+// Maps, slices, arrays, and structs, and pointers to them,
+// other than errors and fmt.Stringers, are encoded as
+// compact JSON, which log pipelines can parse and index,
+// rather than as fmt's %v text. Other values, and composite
+// values that JSON can't encode, are formatted as by fmt.Sprint.
+// Byte slices are encoded as set by SetBytesEncoding,
+// and durations as set by SetDurationUnit.
+// Nil and nil pointers, maps, slices, funcs, and channels,
+// even of types with methods, are written as "null",
+// which the text formats quote when it is a string.
+func valueString(v interface{}) string {
+	if isNil(v) {
+		return nullValue
+	}
+	switch v := v.(type) {
+	// Common types, without fmt's reflection.
+	case string:
+		return v
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, bool:
+		var a [24]byte
+		b, _ := appendInteger(a[:0], v)
+		return string(b)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64) // as fmt's %v
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case []byte:
+		return bytesString(v)
+	case time.Duration:
+		return durationString(v)
+	case error:
+		return v.Error() // not fmt, which hides panics
+	case fmt.Stringer:
+		return v.String()
+	}
+	if b, ok := compositeJSON(v); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// appendInteger appends the text of v to b, as fmt.Sprint
+// formats it, if v is an integer or a bool, and reports
+// whether it was. The text is the same in every format.
+func appendInteger(b []byte, v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case int:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case int8:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case int16:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case int32:
+		return strconv.AppendInt(b, int64(v), 10), true
+	case int64:
+		return strconv.AppendInt(b, v, 10), true
+	case uint:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case uint8:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case uint16:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case uint32:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case uint64:
+		return strconv.AppendUint(b, v, 10), true
+	case uintptr:
+		return strconv.AppendUint(b, uint64(v), 10), true
+	case bool:
+		return strconv.AppendBool(b, v), true
+	}
+	return b, false
+}
+
+// writeInteger writes v to buf, as appendInteger,
+// and reports whether it did.
+func writeInteger(buf *bytes.Buffer, v interface{}) bool {
+	var a [24]byte
+	b, ok := appendInteger(a[:0], v)
+	buf.Write(b)
+	return ok
+}
+
+// nullValue is the text of a nil value.
+const nullValue = "null"
+
+// isNil reports whether v is nil, or a nil
+// pointer, map, slice, func, or channel.
+// A nil []byte is an empty byte slice.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Func, reflect.Chan:
+		return rv.IsNil()
+	case reflect.Slice:
+		_, isBytes := v.([]byte)
+		return rv.IsNil() && !isBytes
+	}
+	return false
+}
+
+// isNullString reports whether v is a string
+// that reads as a nil value, and must be quoted.
+func isNullString(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && s == nullValue
+}
+
+// compositeJSON returns the JSON encoding of v
+// if v is a composite value that encodes
+// without losing its contents.
+func compositeJSON(v interface{}) ([]byte, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+	default:
+		return nil, false
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	if rv.Kind() == reflect.Struct && rv.NumField() > 0 && string(b) == "{}" {
+		return nil, false // only unexported fields
+	}
+	return b, true
+}
+
+// safeFields returns keyvals with its Valuers resolved
+// and each value that panics, when resolved or encoded,
+// replaced by a placeholder, along with log-error fields
+// describing the panics.
+func safeFields(keyvals []interface{}) (safe, errs []interface{}) {
+	safe = keyvals
+	copied := false
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		_, isValuer := keyvals[i+1].(Valuer)
+		v, msg := valuePanic(keyvals[i+1])
+		if msg == "" && !isValuer {
+			continue
+		}
+		if !copied {
+			safe = append([]interface{}(nil), keyvals...)
+			copied = true
+		}
+		safe[i+1] = v
+		if msg != "" {
+			safe[i+1] = "!PANIC"
+			errs = append(errs, keyLogError, msg)
+		}
+	}
+	return safe, errs
+}
+
+// valuePanic resolves v, if it is a Valuer, and encodes
+// the result as every format would. It returns the result
+// and a description of the panic if that panics,
+// or "" otherwise.
+func valuePanic(v interface{}) (resolved interface{}, msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg = fmt.Sprintf("panic encoding value of type %T: %v", v, r)
+		}
+	}()
+	resolved = v
+	if vr, ok := v.(Valuer); ok {
+		resolved = vr()
+	}
+	valueString(resolved)
+	jsonValue(resolved)
+	return resolved, ""
+}
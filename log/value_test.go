@@ -0,0 +1,192 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"chain/log/internal/capture"
+)
+
+type point struct {
+	X, Y int
+}
+
+type nilErr struct{}
+
+func (*nilErr) Error() string { return "nil error" }
+
+type opaque struct {
+	n int
+}
+
+func TestValueString(t *testing.T) {
+	cases := []struct {
+		v    interface{}
+		want string
+	}{
+		{"a b", "a b"},
+		{42, "42"},
+		{int64(-1), "-1"},
+		{true, "true"},
+		{map[string]int{"b": 2, "a": 1}, `{"a":1,"b":2}`},
+		{[]string{"x", "y"}, `["x","y"]`},
+		{[2]int{1, 2}, "[1,2]"},
+		{point{1, 2}, `{"X":1,"Y":2}`},
+		{&point{3, 4}, `{"X":3,"Y":4}`},
+		{opaque{5}, "{5}"},
+		{map[int]func(){0: nil}, "map[0:<nil>]"},
+		{errors.New("boom"), "boom"},
+		{nil, "null"},
+		{(*point)(nil), "null"},
+		{(*nilErr)(nil), "null"},
+		{map[string]int(nil), "null"},
+		{[]byte(nil), ""},
+	}
+	for _, c := range cases {
+		if got := valueString(c.v); got != c.want {
+			t.Errorf("valueString(%#v) = %q want %q", c.v, got, c.want)
+		}
+	}
+}
+
+func TestBytesString(t *testing.T) {
+	defer SetBytesEncoding(BytesHex, DefaultBytesMax)
+
+	b := []byte{0x01, 0xab, 0xff, 0x00}
+	cases := []struct {
+		e    BytesEncoding
+		max  int
+		want string
+	}{
+		{BytesHex, DefaultBytesMax, "01abff00"},
+		{BytesHex, 2, "01ab..."},
+		{BytesBase64, 0, "Aav/AA=="},
+		{BytesBase64, 3, "Aav/..."},
+	}
+	for _, c := range cases {
+		SetBytesEncoding(c.e, c.max)
+		if got := valueString(b); got != c.want {
+			t.Errorf("SetBytesEncoding(%d, %d): valueString = %q want %q", c.e, c.max, got, c.want)
+		}
+	}
+	SetBytesEncoding(BytesHex, DefaultBytesMax)
+	if got, w := string(jsonValue(b)), `"01abff00"`; got != w {
+		t.Errorf("jsonValue = %s want %s", got, w)
+	}
+}
+
+func TestDurationUnit(t *testing.T) {
+	defer SetDurationUnit(0)
+
+	d := 1500 * time.Millisecond
+	if got := valueString(d); got != "1.5s" {
+		t.Errorf("valueString(%v) = %q want 1.5s", d, got)
+	}
+	if got := string(jsonValue(d)); got != `"1.5s"` {
+		t.Errorf("jsonValue(%v) = %s want \"1.5s\"", d, got)
+	}
+	SetDurationUnit(time.Millisecond)
+	if got := valueString(d); got != "1500" {
+		t.Errorf("valueString(%v) = %q want 1500", d, got)
+	}
+	if got := valueString(250 * time.Microsecond); got != "0.25" {
+		t.Errorf("valueString(250µs) = %q want 0.25", got)
+	}
+	if got := string(jsonValue(d)); got != "1500" {
+		t.Errorf("jsonValue(%v) = %s want 1500", d, got)
+	}
+}
+
+func TestSince(t *testing.T) {
+	ctx := Since(context.Background(), time.Now().Add(-time.Second), "elapsed")
+	a := prefixFields(ctx)
+	if len(a) != 2 || a[0] != "elapsed" {
+		t.Fatalf("prefix fields = %v, want elapsed", a)
+	}
+	if d, ok := a[1].(time.Duration); !ok || d < time.Second {
+		t.Errorf("elapsed = %v, want at least 1s", a[1])
+	}
+}
+
+type panicker struct{}
+
+func (panicker) String() string { panic("boom") }
+
+func TestFormatEntryPanic(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 0, time.UTC)
+	for _, f := range []Format{KV, JSON} {
+		got := string(formatEntry(context.Background(), f, fieldPrefix{}, severityInfo, ts, "a.go:1", []interface{}{"a", 1, "p", panicker{}}))
+		if !strings.Contains(got, "a") || !strings.Contains(got, "!PANIC") {
+			t.Errorf("format %v: entry %q lacks a or placeholder", f, got)
+		}
+		if !strings.Contains(got, "panic encoding value of type log.panicker: boom") {
+			t.Errorf("format %v: entry %q lacks log-error", f, got)
+		}
+	}
+}
+
+func TestFormatEntryPanicValuerPrefix(t *testing.T) {
+	ts := time.Date(2017, 3, 1, 13, 4, 5, 0, time.UTC)
+	boom := Valuer(func() interface{} { panic("valuer boom") })
+	ctx := context.WithValue(context.Background(), prefixFieldsKey, []interface{}{"pp", panicker{}, "q", 2})
+	for _, f := range []Format{KV, JSON} {
+		got := string(formatEntry(ctx, f, fieldPrefix{}, severityInfo, ts, "a.go:1", []interface{}{"a", 1, "v", boom}))
+		e := capture.Parse(got)
+		if e["a"] != "1" || e["v"] != "!PANIC" || !strings.Contains(got, "valuer boom") {
+			t.Errorf("format %v: entry %q lacks the valuer's placeholder and log-error", f, got)
+		}
+		// Only the structured formats encode the prefix fields here.
+		if f == JSON && (e["pp"] != "!PANIC" || e["q"] != "2") {
+			t.Errorf("format %v: entry %q lacks the prefix value's placeholder", f, got)
+		}
+	}
+}
+
+func TestNullText(t *testing.T) {
+	cases := []struct {
+		v          interface{}
+		kv, logfmt string
+	}{
+		{nil, "null", "null"},
+		{(*point)(nil), "null", "null"},
+		{"null", `"null"`, `"null"`},
+		{"<nil>", "<nil>", "<nil>"},
+	}
+	for _, c := range cases {
+		if got := formatValue(c.v); got != c.kv {
+			t.Errorf("formatValue(%#v) = %s want %s", c.v, got, c.kv)
+		}
+		if got := logfmtValue(c.v); got != c.logfmt {
+			t.Errorf("logfmtValue(%#v) = %s want %s", c.v, got, c.logfmt)
+		}
+		if got, want := string(jsonValue(c.v)), c.logfmt; c.v != "<nil>" && got != want {
+			t.Errorf("jsonValue(%#v) = %s want %s", c.v, got, want)
+		}
+	}
+}
+
+func TestValueStringPrimitives(t *testing.T) {
+	vals := []interface{}{
+		0, -7, int8(-8), int16(300), int32(-1 << 31), int64(1 << 62),
+		uint(7), uint8(255), uint16(65535), uint32(1 << 31), uint64(1<<64 - 1), uintptr(42),
+		true, false,
+		0.0, -1.5, 1e-7, 123456789.0, 1e21, float32(0.1), float32(3e38),
+	}
+	for _, v := range vals {
+		if got, want := valueString(v), fmt.Sprint(v); got != want {
+			t.Errorf("valueString(%T %v) = %q want %q", v, v, got, want)
+		}
+	}
+}
+
+func BenchmarkValueString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		valueString(uint32(i))
+		valueString(1.5)
+	}
+}
@@ -0,0 +1,34 @@
+package log
+
+// A Valuer is a field value computed only when an entry
+// is written, so that an expensive value, such as
+// a serialized block, costs nothing when its entry
+// is discarded by the minimum level, sampling, or a Filter:
+//
+//	log.Printkv(ctx, log.KeyLevel, log.LevelDebug, "block", log.Valuer(func() interface{} {
+//		return b.String()
+//	}))
+//
+// A hook or filter sees the Valuer itself, not its value.
+type Valuer func() interface{}
+
+// resolveValuers returns keyvals with each Valuer value
+// replaced by its result. It doesn't modify keyvals.
+func resolveValuers(keyvals []interface{}) []interface{} {
+	var out []interface{}
+	for i := 1; i < len(keyvals); i += 2 {
+		v, ok := keyvals[i].(Valuer)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = make([]interface{}, len(keyvals))
+			copy(out, keyvals)
+		}
+		out[i] = v()
+	}
+	if out == nil {
+		return keyvals
+	}
+	return out
+}
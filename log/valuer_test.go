@@ -0,0 +1,35 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValuer(t *testing.T) {
+	out := captureOutput(t)
+
+	calls := 0
+	v := Valuer(func() interface{} {
+		calls++
+		return "expensive"
+	})
+	ctx := context.Background()
+	Printkv(ctx, KeyLevel, LevelDebug, "v", v)
+	if calls != 0 {
+		t.Errorf("Valuer called %d times for a discarded entry, want 0", calls)
+	}
+
+	Printkv(ctx, "v", v)
+	if calls != 1 {
+		t.Errorf("Valuer called %d times, want 1", calls)
+	}
+	if got := out.Entries(); len(got) != 1 || got[0]["v"] != "expensive" {
+		t.Errorf("entries = %v, want v=expensive", got)
+	}
+
+	out.Reset()
+	New(out, EntryFormat(JSON)).Printkv(ctx, "v", v)
+	if got := out.Entries(); len(got) != 1 || got[0]["v"] != "expensive" {
+		t.Errorf("JSON entries = %v, want v=expensive", got)
+	}
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// vmodule holds the rules set by SetVModule,
+// as a *vmoduleRules.
+var vmodule atomic.Value
+
+type vmoduleRules struct {
+	spec  string
+	rules []vmoduleRule
+}
+
+type vmoduleRule struct {
+	pattern string
+	tree    bool // pattern ended in "/...": match packages beneath it too
+	level   Level
+}
+
+func init() {
+	vmodule.Store(&vmoduleRules{})
+}
+
+// SetVModule sets the minimum severity of entries logged
+// from particular packages, overriding SetLevel for them,
+// so that one subsystem can log at LevelDebug
+// without the rest of the process doing so.
+// It has no effect on a Logger with an output of its own.
+//
+// The spec is a comma-separated list of pattern=level rules,
+// such as "chain/protocol/...=debug,chain/net/raft=warning".
+// A pattern is matched, as by path.Match, against the import
+// path of the package containing the caller whose file
+// and line are reported as KeyCaller. A pattern ending in "/..."
+// also matches every package beneath it.
+// The first rule that matches applies.
+// An empty spec removes every rule.
+func SetVModule(spec string) error {
+	vr := &vmoduleRules{spec: spec}
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		i := strings.LastIndex(s, "=")
+		if i < 0 {
+			return fmt.Errorf("log: vmodule rule %q: want pattern=level", s)
+		}
+		level, err := ParseLevel(s[i+1:])
+		if err != nil {
+			return fmt.Errorf("log: vmodule rule %q: %s", s, err)
+		}
+		r := vmoduleRule{pattern: s[:i], level: level}
+		if strings.HasSuffix(r.pattern, "/...") {
+			r.pattern = strings.TrimSuffix(r.pattern, "/...")
+			r.tree = true
+		}
+		if _, err := path.Match(r.pattern, ""); err != nil {
+			return fmt.Errorf("log: vmodule rule %q: %s", s, err)
+		}
+		vr.rules = append(vr.rules, r)
+	}
+	vmodule.Store(vr)
+	return nil
+}
+
+// currentVModule returns the rules set by SetVModule.
+func currentVModule() *vmoduleRules {
+	return vmodule.Load().(*vmoduleRules)
+}
+
+// level returns the minimum severity for entries
+// logged from function fn, and whether any rule matched.
+func (vr *vmoduleRules) level(fn string) (Level, bool) {
+	pkg := funcPackage(fn)
+	for _, r := range vr.rules {
+		for p := pkg; ; p = path.Dir(p) {
+			if ok, _ := path.Match(r.pattern, p); ok {
+				return r.level, true
+			}
+			if !r.tree || !strings.Contains(p, "/") {
+				break
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVModuleLevel(t *testing.T) {
+	defer SetVModule("")
+	err := SetVModule("chain/protocol/...=debug, chain/net/*=error,chain/core=warning")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		fn      string
+		want    Level
+		matched bool
+	}{
+		{"chain/protocol.(*Chain).Foo", LevelDebug, true},
+		{"chain/protocol/bc.Hash", LevelDebug, true},
+		{"chain/net/raft.(*Service).Exec", LevelError, true},
+		{"chain/net/http/reqid.New", 0, false},
+		{"chain/core.(*API).handler", LevelWarning, true},
+		{"chain/core/txdb.Get", 0, false},
+		{"chain/protocolx.Foo", 0, false},
+	}
+	vr := currentVModule()
+	for _, c := range cases {
+		got, ok := vr.level(c.fn)
+		if got != c.want || ok != c.matched {
+			t.Errorf("level(%q) = %v, %t want %v, %t", c.fn, got, ok, c.want, c.matched)
+		}
+	}
+}
+
+func TestSetVModuleErrors(t *testing.T) {
+	defer SetVModule("")
+	SetVModule("chain/log=debug")
+	for _, spec := range []string{"chain/log", "chain/log=loud", "chain/[=debug"} {
+		if err := SetVModule(spec); err == nil {
+			t.Errorf("SetVModule(%q) err = nil, want error", spec)
+		}
+	}
+	if got := currentVModule().spec; got != "chain/log=debug" {
+		t.Errorf("spec = %q after errors, want unchanged", got)
+	}
+}
+
+func TestVModule(t *testing.T) {
+	out := captureOutput(t)
+	defer SetVModule("")
+
+	ctx := context.Background()
+	SetVModule("chain/log=debug")
+	Debugf(ctx, "shown")
+	if got := out.Entries(); len(got) != 1 || got[0][KeyMessage] != "shown" {
+		t.Errorf("entries = %v, want debug entry", got)
+	}
+
+	out.Reset()
+	SetVModule("chain/log=error")
+	Printf(ctx, "hidden")
+	if got := out.Entries(); len(got) != 0 {
+		t.Errorf("entries = %v, want info entry discarded", got)
+	}
+
+	out.Reset()
+	SetVModule("chain/other=debug")
+	Debugf(ctx, "hidden")
+	Printf(ctx, "shown")
+	if got := out.Entries(); len(got) != 1 || got[0][KeyMessage] != "shown" || got[0][KeyLevel] != "info" {
+		t.Errorf("entries = %v, want only the info entry", got)
+	}
+}
@@ -0,0 +1,87 @@
+package log
+
+import (
+	"expvar"
+	"time"
+)
+
+// volumeWindow is the interval over which
+// output volume is measured.
+const volumeWindow = 10 * time.Second
+
+// Volume describes the rate of log output
+// over the most recent complete measurement window.
+type Volume struct {
+	BytesPerSec   float64
+	EntriesPerSec float64
+}
+
+var (
+	volumeVar = expvar.NewMap("log_volume")
+
+	// The following are protected by logWriterMu.
+	volStart      time.Time
+	volBytes      int64
+	volEntries    int64
+	volAlerting   bool
+	volMaxBytes   float64
+	volMaxEntries float64
+	volAlert      func(Volume)
+)
+
+func init() {
+	volumeVar.Set("bytes_per_sec", new(expvar.Float))
+	volumeVar.Set("entries_per_sec", new(expvar.Float))
+}
+
+// SetVolumeAlert registers f to be called when the rate of log
+// output rises above maxBytesPerSec or maxEntriesPerSec,
+// measured over 10-second windows.
+// A limit of zero is ignored.
+// After f is called, it will not be called again until the
+// volume has dropped back below both limits for a window.
+// It is called on its own goroutine.
+// Passing a nil f removes any registered alert.
+//
+// Regardless of any alert, the most recent rates are
+// published in expvar "log_volume".
+func SetVolumeAlert(maxBytesPerSec, maxEntriesPerSec float64, f func(Volume)) {
+	logWriterMu.Lock()
+	defer logWriterMu.Unlock()
+	volMaxBytes = maxBytesPerSec
+	volMaxEntries = maxEntriesPerSec
+	volAlert = f
+	volAlerting = false
+}
+
+// recordVolume records an entry of n bytes written at time t.
+// If the entry ends a window in which the volume crossed
+// a limit set with SetVolumeAlert, it returns the function
+// to call and its argument.
+// The caller must hold logWriterMu.
+func recordVolume(t time.Time, n int) (func(Volume), Volume) {
+	var (
+		alert func(Volume)
+		v     Volume
+	)
+	if d := t.Sub(volStart); d >= volumeWindow {
+		if !volStart.IsZero() {
+			v = Volume{
+				BytesPerSec:   float64(volBytes) / d.Seconds(),
+				EntriesPerSec: float64(volEntries) / d.Seconds(),
+			}
+			volumeVar.Get("bytes_per_sec").(*expvar.Float).Set(v.BytesPerSec)
+			volumeVar.Get("entries_per_sec").(*expvar.Float).Set(v.EntriesPerSec)
+			over := (volMaxBytes > 0 && v.BytesPerSec > volMaxBytes) ||
+				(volMaxEntries > 0 && v.EntriesPerSec > volMaxEntries)
+			if over && !volAlerting && volAlert != nil {
+				alert = volAlert
+			}
+			volAlerting = over
+		}
+		volStart, volBytes, volEntries = t, 0, 0
+	}
+	volBytes += int64(n)
+	volEntries++
+	return alert, v
+}
@@ -0,0 +1,56 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordVolume(t *testing.T) {
+	logWriterMu.Lock()
+	defer logWriterMu.Unlock()
+	defer func() {
+		volStart, volBytes, volEntries = time.Time{}, 0, 0
+		volMaxBytes, volMaxEntries, volAlert, volAlerting = 0, 0, nil, false
+	}()
+
+	var called int
+	volMaxBytes, volMaxEntries = 100, 0
+	volAlert = func(Volume) { called++ }
+	volStart, volBytes, volEntries = time.Time{}, 0, 0
+
+	t0 := time.Now()
+	recordVolume(t0, 0) // starts the first window
+	for i := 0; i < 10; i++ {
+		recordVolume(t0.Add(time.Duration(i)*time.Second), 200)
+	}
+
+	// 2000 bytes in 10 seconds is 200 bytes/sec.
+	f, v := recordVolume(t0.Add(volumeWindow), 1)
+	if f == nil {
+		t.Fatal("want alert")
+	}
+	f(v)
+	if v.BytesPerSec != 200 || v.EntriesPerSec != 1.1 {
+		t.Errorf("volume = %+v want {200 1.1}", v)
+	}
+
+	// Still over the limit; don't alert again.
+	for i := 0; i < 10; i++ {
+		recordVolume(t0.Add(volumeWindow+time.Duration(i)*time.Second), 200)
+	}
+	if f, _ := recordVolume(t0.Add(2*volumeWindow), 1); f != nil {
+		t.Error("want no repeated alert")
+	}
+
+	// Below the limit for a window, then over again.
+	recordVolume(t0.Add(3*volumeWindow), 1)
+	for i := 0; i < 10; i++ {
+		recordVolume(t0.Add(3*volumeWindow+time.Duration(i)*time.Second), 200)
+	}
+	if f, _ := recordVolume(t0.Add(4*volumeWindow), 1); f == nil {
+		t.Error("want second alert")
+	}
+	if called != 1 {
+		t.Errorf("called = %d want 1", called)
+	}
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// Writer returns an io.Writer that writes each line written to it
+// as an entry with severity level, containing the line's text
+// in a field named key, along with any prefix stored in ctx.
+// Incomplete lines are buffered until a newline is written.
+// It is useful for third-party packages that accept
+// only an io.Writer for their own logging.
+//
+// The at=[file:line] field is the location of the code
+// that called Write on the returned writer.
+func Writer(ctx context.Context, level Level, key string) io.Writer {
+	return &lineWriter{ctx: ctx, sev: level.String(), key: key}
+}
+
+type lineWriter struct {
+	ctx context.Context
+	sev string
+	key string
+
+	mu  sync.Mutex // protects buf
+	buf []byte     // partial line from last write
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(bytes.TrimSuffix(w.buf[:i], []byte{'\r'}))
+		w.buf = w.buf[i+1:]
+		if line != "" {
+			printkv(w.ctx, caller, w.sev, []interface{}{w.key, line})
+		}
+	}
+	return len(p), nil
+}
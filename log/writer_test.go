@@ -0,0 +1,42 @@
+package log
+
+import (
+	"context"
+	"expvar"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	out := captureOutput(t)
+
+	errCount := func() int64 {
+		v, _ := entryCounts.Get(severityError).(*expvar.Int)
+		if v == nil {
+			return 0
+		}
+		return v.Value()
+	}
+	err0 := errCount()
+	ctx := AddPrefixkv(context.Background(), "component", "raft")
+	w := Writer(ctx, LevelError, "raftlog")
+	w.Write([]byte("first line\nsecond "))
+	if got := out.Entries(); len(got) != 1 {
+		t.Fatalf("entries = %v, want 1 before the line is complete", got)
+	}
+	w.Write([]byte("line\n\n"))
+
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want 2", entries)
+	}
+	for i, want := range []string{"first line", "second line"} {
+		e := entries[i]
+		if e["component"] != "raft" || e["raftlog"] != want || !strings.HasPrefix(e[KeyCaller], "writer_test.go:") {
+			t.Errorf("entry %d = %v, want component=raft raftlog=%q at writer_test.go", i, e, want)
+		}
+	}
+	if got := errCount() - err0; got != 2 {
+		t.Errorf("error count = %d want 2", got)
+	}
+}
@@ -124,6 +124,28 @@ func (r *RotatingLatency) RecordSince(t0 time.Time) {
 	r.Record(time.Since(t0))
 }
 
+// Quantiles returns the durations at each of the given
+// quantiles (in the range 0 to 100), computed over all
+// buckets currently held in r.
+// Values over r's limit are not included.
+func (r *RotatingLatency) Quantiles(qs ...float64) []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var h *hdrhistogram.Histogram
+	for i := range r.l {
+		if h == nil {
+			h = hdrhistogram.Import((&r.l[i].hdr).Export())
+		} else {
+			h.Merge(&r.l[i].hdr)
+		}
+	}
+	a := make([]time.Duration, len(qs))
+	for i, q := range qs {
+		a[i] = time.Duration(h.ValueAtQuantile(q))
+	}
+	return a
+}
+
 func (r *RotatingLatency) rotate() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
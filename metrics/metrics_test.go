@@ -48,6 +48,24 @@ func TestRotString(t *testing.T) {
 	}
 }
 
+func TestRotQuantiles(t *testing.T) {
+	rot := NewRotatingLatency(2, time.Second)
+	for i := 1; i <= 100; i++ {
+		rot.Record(time.Duration(i) * time.Millisecond)
+		if i == 50 {
+			rot.rotate()
+		}
+	}
+	got := rot.Quantiles(50, 99)
+	want := []time.Duration{50 * time.Millisecond, 99 * time.Millisecond}
+	for i := range want {
+		// The histogram has two significant figures.
+		if d := got[i] - want[i]; d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("Quantiles()[%d] = %v want %v", i, got[i], want[i])
+		}
+	}
+}
+
 func jsonIsEqual(t *testing.T, a, b string) bool {
 	var av, bv interface{}
 
@@ -0,0 +1,246 @@
+// Package otlp exports the process's log counters and
+// request latency metrics to an OpenTelemetry collector
+// using OTLP over HTTP with JSON encoding, so they flow
+// through the same pipeline as traces and logs.
+//
+// Metrics are read from expvars, so this package
+// does not depend on the packages that maintain them.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"chain/log"
+)
+
+// A metric describes how to export one expvar.
+// The expvar holds a JSON object, nested once per label,
+// with numeric leaves.
+type metric struct {
+	expvar string
+	name   string
+	unit   string
+	labels []string
+	sum    bool // a monotonic cumulative sum; otherwise a gauge
+}
+
+var metrics = []metric{
+	{"log_entries", "chain.log.entries", "1", []string{"severity"}, true},
+	{"log_dropped", "chain.log.dropped", "1", []string{"cause", "severity"}, true},
+	{"log_sink_healthy", "chain.log.sink.healthy", "1", []string{"sink"}, false},
+	{"log_sink_failures", "chain.log.sink.failures", "1", []string{"sink"}, false},
+	{"log_sink_dropped", "chain.log.sink.dropped", "1", []string{"sink"}, true},
+	{"log_sink_bytes", "chain.log.sink.bytes", "By", []string{"sink"}, true},
+	{"log_sink_latency", "chain.log.sink.latency", "ns", []string{"sink", "quantile"}, false},
+	{"latency_quantiles", "chain.request.latency", "ns", []string{"path", "quantile"}, false},
+	{"accesslog_excluded", "chain.accesslog.excluded", "1", []string{"path"}, true},
+}
+
+// An Exporter periodically sends metrics to an OTLP/HTTP endpoint.
+type Exporter struct {
+	url      string
+	client   *http.Client
+	resource []keyValue
+	start    time.Time
+}
+
+// New returns an Exporter that posts metrics to url,
+// typically "http://<collector>:4318/v1/metrics".
+// The resource attribute service.name is set to service.
+func New(url, service string) *Exporter {
+	return &Exporter{
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		resource: []keyValue{stringAttr("service.name", service)},
+		start:    time.Now(),
+	}
+}
+
+// Run exports the current metrics once per interval
+// until ctx is done.
+func (e *Exporter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Export(ctx); err != nil {
+				log.Error(ctx, err, "exporting OTLP metrics")
+			}
+		}
+	}
+}
+
+// Export sends the current value of every metric.
+func (e *Exporter) Export(ctx context.Context) error {
+	body, err := json.Marshal(e.request(time.Now()))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp: %s: %s", e.url, resp.Status)
+	}
+	return nil
+}
+
+func (e *Exporter) request(now time.Time) *exportRequest {
+	var ms []otlpMetric
+	for _, m := range metrics {
+		v := expvar.Get(m.expvar)
+		if v == nil {
+			continue
+		}
+		var x interface{}
+		if json.Unmarshal([]byte(v.String()), &x) != nil {
+			continue
+		}
+		var points []dataPoint
+		walk(x, nil, func(labels []string, n float64) {
+			if len(labels) != len(m.labels) {
+				return
+			}
+			p := dataPoint{TimeUnixNano: nanos(now)}
+			for i, l := range labels {
+				p.Attributes = append(p.Attributes, stringAttr(m.labels[i], l))
+			}
+			if m.sum {
+				p.StartTimeUnixNano = nanos(e.start)
+				s := strconv.FormatInt(int64(n), 10)
+				p.AsInt = &s
+			} else {
+				p.AsDouble = &n
+			}
+			points = append(points, p)
+		})
+		if len(points) == 0 {
+			continue
+		}
+		om := otlpMetric{Name: m.name, Unit: m.unit}
+		if m.sum {
+			om.Sum = &sum{
+				DataPoints:             points,
+				AggregationTemporality: temporalityCumulative,
+				IsMonotonic:            true,
+			}
+		} else {
+			om.Gauge = &gauge{DataPoints: points}
+		}
+		ms = append(ms, om)
+	}
+	return &exportRequest{ResourceMetrics: []resourceMetrics{{
+		Resource: resource{Attributes: e.resource},
+		ScopeMetrics: []scopeMetrics{{
+			Scope:   scope{Name: "chain"},
+			Metrics: ms,
+		}},
+	}}}
+}
+
+// walk calls f for each numeric leaf in x, in key order,
+// with the keys leading to it.
+func walk(x interface{}, keys []string, f func([]string, float64)) {
+	switch x := x.(type) {
+	case float64:
+		f(keys, x)
+	case map[string]interface{}:
+		var names []string
+		for k := range x {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			walk(x[k], append(keys[:len(keys):len(keys)], k), f)
+		}
+	}
+}
+
+func nanos(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func stringAttr(k, v string) keyValue {
+	return keyValue{Key: k, Value: anyValue{StringValue: v}}
+}
+
+// The following types mirror the JSON encoding of
+// ExportMetricsServiceRequest from the OTLP protocol.
+// 64-bit integers are encoded as decimal strings.
+
+const temporalityCumulative = 2
+
+type exportRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeMetrics struct {
+	Scope   scope        `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type otlpMetric struct {
+	Name  string `json:"name"`
+	Unit  string `json:"unit,omitempty"`
+	Sum   *sum   `json:"sum,omitempty"`
+	Gauge *gauge `json:"gauge,omitempty"`
+}
+
+type sum struct {
+	DataPoints             []dataPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type gauge struct {
+	DataPoints []dataPoint `json:"dataPoints"`
+}
+
+type dataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano,omitempty"`
+	TimeUnixNano      string     `json:"timeUnixNano"`
+	AsInt             *string    `json:"asInt,omitempty"`
+	AsDouble          *float64   `json:"asDouble,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
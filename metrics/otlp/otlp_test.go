@@ -0,0 +1,82 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/log"
+	"chain/log/logtest"
+)
+
+// accessLogExcluded is normally published by package core.
+var accessLogExcluded = expvar.NewMap("accesslog_excluded")
+
+func TestExport(t *testing.T) {
+	logtest.Capture(t)
+	log.Printkv(context.Background(), "message", "hello")
+
+	n := new(expvar.Int)
+	n.Set(3)
+	accessLogExcluded.Set("/health", n)
+
+	var got exportRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if ct := req.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	err := New(srv.URL+"/v1/metrics", "cored").Export(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.ResourceMetrics) != 1 {
+		t.Fatalf("resourceMetrics = %+v, want 1", got.ResourceMetrics)
+	}
+	rm := got.ResourceMetrics[0]
+	if a := rm.Resource.Attributes; len(a) != 1 || a[0].Value.StringValue != "cored" {
+		t.Errorf("resource attributes = %+v", a)
+	}
+	byName := make(map[string]otlpMetric)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		byName[m.Name] = m
+	}
+
+	entries := byName["chain.log.entries"]
+	if entries.Sum == nil || !entries.Sum.IsMonotonic {
+		t.Fatalf("chain.log.entries = %+v, want monotonic sum", entries)
+	}
+	var found bool
+	for _, p := range entries.Sum.DataPoints {
+		if p.Attributes[0].Key == "severity" && p.Attributes[0].Value.StringValue == "info" {
+			found = p.AsInt != nil && *p.AsInt != "0"
+		}
+	}
+	if !found {
+		t.Errorf("no info data point in %+v", entries.Sum.DataPoints)
+	}
+
+	excluded := byName["chain.accesslog.excluded"]
+	if excluded.Sum == nil || len(excluded.Sum.DataPoints) != 1 || *excluded.Sum.DataPoints[0].AsInt != "3" {
+		t.Errorf("chain.accesslog.excluded = %+v, want /health=3", excluded)
+	}
+}
+
+func TestExportStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	if err := New(srv.URL, "cored").Export(context.Background()); err == nil {
+		t.Error("want error for 400 response")
+	}
+}
@@ -47,3 +47,21 @@ func Localhost(ctx context.Context) bool {
 	}
 	return false
 }
+
+// Principal returns a description of the authenticated caller
+// stored in the context, suitable for audit logs:
+// the access token ID, the subject of the client certificate,
+// or "localhost" for an otherwise unauthenticated loopback request.
+// It returns the empty string if the caller is unknown.
+func Principal(ctx context.Context) string {
+	if t := Token(ctx); t != "" {
+		return "token:" + t
+	}
+	if c := X509Certs(ctx); len(c) > 0 {
+		return "cert:" + c[0].Subject.CommonName
+	}
+	if Localhost(ctx) {
+		return "localhost"
+	}
+	return ""
+}
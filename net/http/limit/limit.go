@@ -1,10 +1,15 @@
 package limit
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
+
+	"chain/log"
 )
 
 type BucketLimiter struct {
@@ -27,6 +32,22 @@ func (b *BucketLimiter) Allow(id string) bool {
 	return b.bucket(id).Allow()
 }
 
+// Reserve is like Allow, but when it returns false
+// it also reports how long id must wait before
+// its next request will be allowed.
+func (b *BucketLimiter) Reserve(id string) (ok bool, retryAfter time.Duration) {
+	now := time.Now()
+	r := b.bucket(id).ReserveN(now, 1)
+	if !r.OK() {
+		return false, 0 // burst is zero; will never be allowed
+	}
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now) // give the token back; we're rejecting the request
+		return false, d
+	}
+	return true, 0
+}
+
 func (b *BucketLimiter) bucket(id string) *rate.Limiter {
 	b.bucketMu.Lock()
 	bucket, ok := b.buckets[id]
@@ -57,7 +78,19 @@ func Handler(next, limited http.Handler, freq, burst int, f func(*http.Request)
 
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	id := h.f(r)
-	if !h.limiter.Allow(id) {
+	if ok, retryAfter := h.limiter.Reserve(id); !ok {
+		log.Printkv(r.Context(),
+			log.KeyMessage, "rate limited",
+			"principal", id,
+			"endpoint", r.URL.Path,
+			"limit", float64(h.limiter.freq),
+			"burst", h.limiter.burst,
+			"retryafter", retryAfter,
+		)
+		if retryAfter > 0 {
+			secs := int(math.Ceil(retryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+		}
 		h.limited.ServeHTTP(w, r)
 		return
 	}
@@ -0,0 +1,50 @@
+package limit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chain/log/logtest"
+)
+
+func TestHandlerRejectionLog(t *testing.T) {
+	out := logtest.Capture(t)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	limited := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	h := Handler(ok, limited, 1, 1, RemoteAddrID)
+
+	var codes []int
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("POST", "/build-transaction", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+		if i > 0 && rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("request %d: Retry-After = %q want 1", i, rec.Header().Get("Retry-After"))
+		}
+	}
+	if codes[0] != 200 || codes[1] != 429 || codes[2] != 429 {
+		t.Errorf("status codes = %v want [200 429 429]", codes)
+	}
+
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want 2", entries)
+	}
+	want := logtest.Entry{
+		"message":   "rate limited",
+		"principal": "192.0.2.1:1234",
+		"endpoint":  "/build-transaction",
+		"limit":     "1",
+		"burst":     "1",
+	}
+	for i, e := range entries {
+		if !e.Matches(want) || e["retryafter"] == "" {
+			t.Errorf("entry %d = %v, want %v and a retryafter", i, e, want)
+		}
+	}
+}
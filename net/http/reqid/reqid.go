@@ -82,6 +82,13 @@ func Handler(handler http.Handler) http.Handler {
 		id := New()
 		ctx = NewContext(ctx, id)
 
+		// Another core calling us over RPC sends its own
+		// request ID (see chain/core/rpc). Log it too,
+		// so the two sides of the call can be matched up.
+		if callerID := req.Header.Get("Request-ID"); callerID != "" {
+			ctx = log.AddPrefixkv(ctx, "callerreqid", callerID)
+		}
+
 		defer func() {
 			if err := recover(); err != nil {
 				log.Printkv(ctx,
@@ -0,0 +1,193 @@
+package serverlog
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"chain/log"
+)
+
+// ConnState returns a function suitable for http.Server.ConnState
+// that calls each of fs in turn.
+func ConnState(fs ...func(net.Conn, http.ConnState)) func(net.Conn, http.ConnState) {
+	return func(c net.Conn, state http.ConnState) {
+		for _, f := range fs {
+			f(c, state)
+		}
+	}
+}
+
+// ConnStats tracks the connections accepted by an http.Server.
+// Install its ConnState method as the server's ConnState hook
+// (possibly combined with others using func ConnState),
+// and call Run to log the collected stats periodically.
+//
+// To also track connection reuse, wrap the server's
+// handler with Handler.
+//
+// Its methods are safe to call concurrently.
+type ConnStats struct {
+	mu     sync.Mutex
+	conns  map[string]*connInfo // keyed by remote addr
+	states map[http.ConnState]int
+	tls    int // open TLS connections
+
+	// The following are reset after each log entry.
+	accepted   int
+	closed     int
+	requests   int
+	reused     int // requests on a previously-used connection
+	maxStreams int // max concurrent requests on one connection
+	last       time.Time
+}
+
+type connInfo struct {
+	state    http.ConnState
+	tls      bool
+	requests int // served so far, including in-flight
+	inflight int
+}
+
+// NewConnStats returns a new, empty ConnStats.
+func NewConnStats() *ConnStats {
+	return &ConnStats{
+		conns:  make(map[string]*connInfo),
+		states: make(map[http.ConnState]int),
+		last:   time.Now(),
+	}
+}
+
+// ConnState records a connection state change.
+// It is suitable for use as http.Server.ConnState.
+func (s *ConnStats) ConnState(c net.Conn, state http.ConnState) {
+	addr := c.RemoteAddr().String()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ci := s.conns[addr]
+	if ci != nil {
+		s.states[ci.state]--
+	} else if state == http.StateNew {
+		_, isTLS := c.(*tls.Conn)
+		ci = &connInfo{tls: isTLS}
+		s.accepted++
+		if isTLS {
+			s.tls++
+		}
+	}
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		if ci != nil {
+			delete(s.conns, addr)
+			if ci.tls {
+				s.tls--
+			}
+		}
+		s.closed++
+	default:
+		if ci != nil {
+			ci.state = state
+			s.conns[addr] = ci
+			s.states[state]++
+		}
+	}
+}
+
+// Handler returns a handler that records, for each request,
+// whether its connection was reused and how many requests
+// are in flight on that connection, then calls next.
+//
+// If logRequests is true, it also adds these as fields
+// (connreused, streams, and proto)
+// to the log context of each request.
+func (s *ConnStats) Handler(next http.Handler, logRequests bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		reused, streams := s.startRequest(req.RemoteAddr)
+		defer s.endRequest(req.RemoteAddr)
+		if logRequests {
+			ctx := log.AddPrefixkv(req.Context(),
+				"proto", req.Proto,
+				"connreused", reused,
+				"streams", streams,
+			)
+			req = req.WithContext(ctx)
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (s *ConnStats) startRequest(addr string) (reused bool, streams int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	ci := s.conns[addr]
+	if ci == nil {
+		return false, 1 // not tracked (e.g. hook not installed)
+	}
+	ci.requests++
+	ci.inflight++
+	reused = ci.requests > 1
+	if reused {
+		s.reused++
+	}
+	if ci.inflight > s.maxStreams {
+		s.maxStreams = ci.inflight
+	}
+	return reused, ci.inflight
+}
+
+func (s *ConnStats) endRequest(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ci := s.conns[addr]; ci != nil {
+		ci.inflight--
+	}
+}
+
+// Run logs the current connection stats once per interval
+// until ctx is canceled.
+func (s *ConnStats) Run(ctx context.Context, interval time.Duration) {
+	ticks := time.NewTicker(interval)
+	defer ticks.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks.C:
+			log.Printkv(ctx, s.keyvals(time.Now())...)
+		}
+	}
+}
+
+// keyvals returns the current stats as log fields
+// and resets the per-interval counters.
+func (s *ConnStats) keyvals(now time.Time) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var rate float64
+	if d := now.Sub(s.last).Seconds(); d > 0 {
+		rate = float64(s.accepted) / d
+	}
+	open := len(s.conns)
+	keyvals := []interface{}{
+		log.KeyMessage, "connection stats",
+		"open", open,
+		"tls", s.tls,
+		"plaintext", open - s.tls,
+		"accepted", s.accepted,
+		"acceptrate", float64(int(rate*100)) / 100,
+		"closed", s.closed,
+		"new", s.states[http.StateNew],
+		"active", s.states[http.StateActive],
+		"idle", s.states[http.StateIdle],
+		"requests", s.requests,
+		"reusedrequests", s.reused,
+		"maxstreams", s.maxStreams,
+	}
+	s.accepted, s.closed, s.last = 0, 0, now
+	s.requests, s.reused, s.maxStreams = 0, 0, 0
+	return keyvals
+}
@@ -0,0 +1,97 @@
+package serverlog
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chain/log"
+	"chain/log/logtest"
+	"chain/testutil"
+)
+
+type fakeConn struct {
+	net.Conn
+	addr string
+}
+
+func (c fakeConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: len(c.addr)}
+}
+
+func TestConnStats(t *testing.T) {
+	s := NewConnStats()
+	t0 := s.last
+
+	a := fakeConn{addr: "a"}
+	b := fakeConn{addr: "bb"}
+
+	hook := ConnState(s.ConnState)
+	hook(a, http.StateNew)
+	hook(b, http.StateNew)
+	hook(a, http.StateActive)
+	hook(b, http.StateActive)
+	hook(b, http.StateIdle)
+	hook(a, http.StateClosed)
+
+	got := s.keyvals(t0.Add(2 * time.Second))
+	want := []interface{}{
+		"message", "connection stats",
+		"open", 1,
+		"tls", 0,
+		"plaintext", 1,
+		"accepted", 2,
+		"acceptrate", 1.0,
+		"closed", 1,
+		"new", 0,
+		"active", 0,
+		"idle", 1,
+		"requests", 0,
+		"reusedrequests", 0,
+		"maxstreams", 0,
+	}
+	if !testutil.DeepEqual(got, want) {
+		t.Errorf("keyvals = %v want %v", got, want)
+	}
+
+	// Interval counters are reset after each entry.
+	got = s.keyvals(t0.Add(3 * time.Second))
+	if got[9] != 0 || got[13] != 0 {
+		t.Errorf("accepted = %v closed = %v, want 0 0", got[9], got[13])
+	}
+}
+
+func TestConnStatsReuse(t *testing.T) {
+	out := logtest.Capture(t)
+
+	s := NewConnStats()
+	c := fakeConn{addr: "a"}
+	s.ConnState(c, http.StateNew)
+
+	h := s.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		log.Printkv(req.Context())
+	}), true)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.RemoteAddr().String()
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want 2", entries)
+	}
+	if w := (logtest.Entry{"proto": "HTTP/1.1", "connreused": "false", "streams": "1"}); !entries[0].Matches(w) {
+		t.Errorf("first entry = %v, want %v", entries[0], w)
+	}
+	if w := (logtest.Entry{"connreused": "true", "streams": "1"}); !entries[1].Matches(w) {
+		t.Errorf("second entry = %v, want %v", entries[1], w)
+	}
+
+	got := s.keyvals(time.Now())
+	if got[21] != 2 || got[23] != 1 || got[25] != 1 {
+		t.Errorf("requests=%v reused=%v maxstreams=%v, want 2 1 1", got[21], got[23], got[25])
+	}
+}
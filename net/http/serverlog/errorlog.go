@@ -0,0 +1,46 @@
+package serverlog
+
+import (
+	"bytes"
+	"context"
+	stdlog "log"
+	"strings"
+
+	"chain/log"
+)
+
+// Lines written by http.Server to its ErrorLog
+// that are logged as errors rather than warnings.
+var serverErrPrefixes = []string{
+	"http: panic serving ",
+	"http: Accept error: ",
+	"http: Server.Serve: ",
+}
+
+// ErrorLog returns a logger suitable for http.Server.ErrorLog.
+// Each line the server writes becomes an entry in chain/log
+// with component=httpserver.
+// Handler panics and failures to accept connections
+// are logged as errors; all other lines, typically
+// protocol errors from clients, are logged as warnings.
+func ErrorLog() *stdlog.Logger {
+	return stdlog.New(errorLogWriter{}, "", 0)
+}
+
+type errorLogWriter struct{}
+
+func (errorLogWriter) Write(p []byte) (int, error) {
+	logServerLine(string(bytes.TrimSuffix(p, []byte{'\n'})))
+	return len(p), nil
+}
+
+func logServerLine(line string) {
+	key := "warning"
+	for _, prefix := range serverErrPrefixes {
+		if strings.HasPrefix(line, prefix) {
+			key = log.KeyError
+			break
+		}
+	}
+	log.Printkv(context.Background(), "component", "httpserver", key, line)
+}
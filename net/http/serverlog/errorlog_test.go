@@ -0,0 +1,29 @@
+package serverlog
+
+import (
+	"testing"
+
+	"chain/log/logtest"
+)
+
+func TestErrorLog(t *testing.T) {
+	out := logtest.Capture(t)
+
+	l := ErrorLog()
+	l.Printf("http: Accept error: accept tcp [::]:1999: too many open files; retrying in 5ms")
+	l.Printf("http2: server: error reading preface from client 10.0.0.1:5000: bogus greeting")
+
+	entries := out.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("entries = %v, want 2", entries)
+	}
+	want := []logtest.Entry{
+		{"component": "httpserver", "error": "http: Accept error: accept tcp [::]:1999: too many open files; retrying in 5ms"},
+		{"component": "httpserver", "warning": "http2: server: error reading preface from client 10.0.0.1:5000: bogus greeting"},
+	}
+	for i, w := range want {
+		if !entries[i].Matches(w) {
+			t.Errorf("entry %d = %v, want %v", i, entries[i], w)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package serverlog writes structured log entries
+// describing the connections accepted by an http.Server.
+package serverlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	stdlog "log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"chain/log"
+)
+
+// http.Server reports a failed handshake on its ErrorLog
+// as a single line with this prefix,
+// followed by the remote address and the error.
+const handshakeErrPrefix = "http: TLS handshake error from "
+
+// A TLSHandshakes logs failed TLS handshakes on a server
+// as structured entries, including what the client offered
+// in its ClientHello.
+// Without it, a failed handshake shows up only as a terse
+// line on the server's ErrorLog and as a connection reset
+// on the client.
+//
+// Its methods are safe to call concurrently.
+type TLSHandshakes struct {
+	mu     sync.Mutex
+	hellos map[string]*tls.ClientHelloInfo // keyed by remote addr
+}
+
+// NewTLSHandshakes returns a new TLSHandshakes that records
+// incoming ClientHello messages through config.
+// It sets config.GetConfigForClient, which must be nil.
+//
+// To log failures, the server using config must also
+// use the returned value as both its ErrorLog
+// (see ErrorLog) and its ConnState hook.
+func NewTLSHandshakes(config *tls.Config) *TLSHandshakes {
+	h := &TLSHandshakes{hellos: make(map[string]*tls.ClientHelloInfo)}
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		h.mu.Lock()
+		h.hellos[hello.Conn.RemoteAddr().String()] = hello
+		h.mu.Unlock()
+		return nil, nil // use config unchanged
+	}
+	return h
+}
+
+// ErrorLog returns a logger suitable for http.Server.ErrorLog.
+// Handshake failures are written to chain/log with details
+// of the ClientHello; all other lines are logged
+// as by the package-level ErrorLog.
+func (h *TLSHandshakes) ErrorLog() *stdlog.Logger {
+	return stdlog.New(h, "", 0)
+}
+
+// ConnState discards the ClientHello recorded for c
+// once the connection is established or closed.
+// It is suitable for use as http.Server.ConnState.
+func (h *TLSHandshakes) ConnState(c net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateActive, http.StateHijacked, http.StateClosed:
+		h.mu.Lock()
+		delete(h.hellos, c.RemoteAddr().String())
+		h.mu.Unlock()
+	}
+}
+
+// Write implements io.Writer for the logger returned by ErrorLog.
+func (h *TLSHandshakes) Write(p []byte) (int, error) {
+	line := string(bytes.TrimSuffix(p, []byte{'\n'}))
+	if !strings.HasPrefix(line, handshakeErrPrefix) {
+		logServerLine(line)
+		return len(p), nil
+	}
+	line = strings.TrimPrefix(line, handshakeErrPrefix)
+	addr, msg := line, ""
+	if i := strings.Index(line, ": "); i >= 0 {
+		addr, msg = line[:i], line[i+2:]
+	}
+
+	h.mu.Lock()
+	hello := h.hellos[addr]
+	delete(h.hellos, addr)
+	h.mu.Unlock()
+
+	keyvals := []interface{}{
+		"component", "httpserver",
+		log.KeyMessage, "tls handshake failed",
+		"remoteaddr", addr,
+	}
+	if hello != nil {
+		keyvals = append(keyvals,
+			"sni", hello.ServerName,
+			"tlsversions", versionNames(hello.SupportedVersions),
+			"ciphers", cipherNames(hello.CipherSuites),
+		)
+	}
+	if strings.Contains(msg, "certificate") || strings.Contains(msg, "x509") {
+		keyvals = append(keyvals, "certerror", true)
+	}
+	keyvals = append(keyvals, log.KeyError, msg)
+	log.Printkv(context.Background(), keyvals...)
+	return len(p), nil
+}
+
+func versionNames(vs []uint16) string {
+	a := make([]string, len(vs))
+	for i, v := range vs {
+		a[i] = tls.VersionName(v)
+	}
+	return strings.Join(a, ",")
+}
+
+func cipherNames(cs []uint16) string {
+	a := make([]string, len(cs))
+	for i, c := range cs {
+		a[i] = tls.CipherSuiteName(c)
+	}
+	return strings.Join(a, ",")
+}
@@ -0,0 +1,71 @@
+package serverlog
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"testing"
+
+	"chain/log/logtest"
+)
+
+func TestTLSHandshakeFailure(t *testing.T) {
+	out := logtest.Capture(t)
+
+	config := new(tls.Config)
+	h := NewTLSHandshakes(config)
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	addr := c1.RemoteAddr().String()
+	_, err := config.GetConfigForClient(&tls.ClientHelloInfo{
+		ServerName:        "core.example.com",
+		SupportedVersions: []uint16{tls.VersionTLS12},
+		CipherSuites:      []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		Conn:              c1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.ErrorLog().Printf("http: TLS handshake error from %s: remote error: tls: bad certificate", addr)
+
+	entries := out.Find("message", "tls handshake failed")
+	if len(entries) != 1 {
+		t.Fatalf("entries = %v, want 1 tls handshake failure", out.Entries())
+	}
+	want := logtest.Entry{
+		"remoteaddr":  addr,
+		"sni":         "core.example.com",
+		"tlsversions": "TLS 1.2",
+		"ciphers":     "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"certerror":   "true",
+		"error":       "remote error: tls: bad certificate",
+	}
+	if !entries[0].Matches(want) {
+		t.Errorf("entry = %v, want %v", entries[0], want)
+	}
+	if len(h.hellos) != 0 {
+		t.Errorf("len(hellos) = %d want 0", len(h.hellos))
+	}
+}
+
+func TestTLSHandshakeConnState(t *testing.T) {
+	config := new(tls.Config)
+	h := NewTLSHandshakes(config)
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	config.GetConfigForClient(&tls.ClientHelloInfo{Conn: c1})
+
+	h.ConnState(c1, http.StateNew)
+	if len(h.hellos) != 1 {
+		t.Fatalf("len(hellos) = %d want 1", len(h.hellos))
+	}
+	h.ConnState(c1, http.StateActive)
+	if len(h.hellos) != 0 {
+		t.Errorf("len(hellos) = %d want 0", len(h.hellos))
+	}
+}
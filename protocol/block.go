@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -72,33 +73,37 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 
 	var txEntries []*bc.Tx
 
-	for _, tx := range txs {
+	for i, tx := range txs {
 		if len(b.Transactions) >= maxBlockTxs {
+			log.Printkv(ctx,
+				"txrejected", RejectBlockFull,
+				"count", len(txs)-i,
+			)
 			break
 		}
 
 		// Filter out transactions that are not well-formed.
 		err := c.ValidateTx(tx.Tx)
 		if err != nil {
-			// TODO(bobg): log this?
+			logRejected(ctx, tx, RejectInvalid, err)
 			continue
 		}
 
 		// Filter out transactions that are not yet valid, or no longer
 		// valid, per the block's timestamp.
 		if tx.Tx.MinTimeMs > 0 && tx.Tx.MinTimeMs > b.TimestampMS {
-			// TODO(bobg): log this?
+			logRejected(ctx, tx, RejectNotYetValid, nil)
 			continue
 		}
 		if tx.Tx.MaxTimeMs > 0 && tx.Tx.MaxTimeMs < b.TimestampMS {
-			// TODO(bobg): log this?
+			logRejected(ctx, tx, RejectExpired, nil)
 			continue
 		}
 
 		// Filter out double-spends etc.
 		err = newSnapshot.ApplyTx(tx.Tx)
 		if err != nil {
-			// TODO(bobg): log this?
+			logRejected(ctx, tx, RejectConflict, err)
 			continue
 		}
 
@@ -118,6 +123,41 @@ func (c *Chain) GenerateBlock(ctx context.Context, prev *legacy.Block, snapshot
 	return b, newSnapshot, nil
 }
 
+// Reason codes logged in the "txrejected" field when GenerateBlock
+// leaves a pending transaction out of a block.
+const (
+	RejectInvalid     = "invalid"       // not well-formed
+	RejectNotYetValid = "not_yet_valid" // before the tx's min time
+	RejectExpired     = "expired"       // after the tx's max time
+	RejectConflict    = "conflict"      // double-spend or other state conflict
+	RejectBlockFull   = "block_full"    // the block reached maxBlockTxs
+)
+
+type txSourcesKey struct{}
+
+// WithTxSources returns a context that makes GenerateBlock
+// log, as the field "source" of each rejection, the source
+// of the rejected transaction, such as the ID of the request
+// that submitted it, looked up by tx ID in sources.
+func WithTxSources(ctx context.Context, sources map[bc.Hash]string) context.Context {
+	return context.WithValue(ctx, txSourcesKey{}, sources)
+}
+
+func logRejected(ctx context.Context, tx *legacy.Tx, reason string, err error) {
+	keyvals := []interface{}{
+		"txrejected", reason,
+		"txid", hex.EncodeToString(tx.ID.Bytes()),
+	}
+	sources, _ := ctx.Value(txSourcesKey{}).(map[bc.Hash]string)
+	if s := sources[tx.ID]; s != "" {
+		keyvals = append(keyvals, "source", s)
+	}
+	if err != nil {
+		keyvals = append(keyvals, "detail", err.Error())
+	}
+	log.Printkv(ctx, keyvals...)
+}
+
 // ValidateBlock validates an incoming block in advance of committing
 // it to the blockchain (with CommitBlock).
 func (c *Chain) ValidateBlock(block, prev *legacy.Block) error {
@@ -137,10 +177,12 @@ func (c *Chain) ValidateBlock(block, prev *legacy.Block) error {
 // sets c's state. Unlike CommitBlock, it accepts an already applied
 // snapshot. CommitAppliedBlock is idempotent.
 func (c *Chain) CommitAppliedBlock(ctx context.Context, block *legacy.Block, snapshot *state.Snapshot) error {
+	t0 := time.Now()
 	err := c.store.SaveBlock(ctx, block)
 	if err != nil {
 		return errors.Wrap(err, "storing block")
 	}
+	tStored := time.Now()
 	curBlock, _ := c.State()
 
 	// CommitAppliedBlock needs to be idempotent. If block's height is less than or
@@ -150,17 +192,26 @@ func (c *Chain) CommitAppliedBlock(ctx context.Context, block *legacy.Block, sna
 	if curBlock != nil && block.Height <= curBlock.Height {
 		return nil
 	}
-	return c.finalizeCommitBlock(ctx, block, snapshot)
+	err = c.finalizeCommitBlock(ctx, block, snapshot)
+	if err == nil {
+		logCommit(ctx, block, t0,
+			"store", tStored.Sub(t0),
+			"finalize", time.Since(tStored),
+		)
+	}
+	return err
 }
 
 // CommitBlock takes a block, commits it to persistent storage and applies
 // it to c. CommitBlock is idempotent. A duplicate call with a previously
 // committed block will succeed.
 func (c *Chain) CommitBlock(ctx context.Context, block *legacy.Block) error {
+	t0 := time.Now()
 	err := c.store.SaveBlock(ctx, block)
 	if err != nil {
 		return errors.Wrap(err, "storing block")
 	}
+	tStored := time.Now()
 	curBlock, curSnapshot := c.State()
 
 	// CommitBlock needs to be idempotent. If block's height is less than or
@@ -179,7 +230,32 @@ func (c *Chain) CommitBlock(ctx context.Context, block *legacy.Block) error {
 	if block.AssetsMerkleRoot != snapshot.Tree.RootHash() {
 		return ErrBadStateRoot
 	}
-	return c.finalizeCommitBlock(ctx, block, snapshot)
+	tApplied := time.Now()
+	err = c.finalizeCommitBlock(ctx, block, snapshot)
+	if err == nil {
+		logCommit(ctx, block, t0,
+			"store", tStored.Sub(t0),
+			"apply", tApplied.Sub(tStored),
+			"finalize", time.Since(tApplied),
+		)
+	}
+	return err
+}
+
+// logCommit writes an entry for a newly committed block
+// with the duration of each stage of committing it,
+// given as alternating stage names and durations.
+// Stages that happen before the commit, such as validation,
+// are included by callers in ctx's log prefix.
+func logCommit(ctx context.Context, block *legacy.Block, t0 time.Time, stages ...interface{}) {
+	keyvals := []interface{}{
+		"message", "block committed",
+		"height", block.Height,
+		"txs", len(block.Transactions),
+	}
+	keyvals = append(keyvals, stages...)
+	keyvals = append(keyvals, "total", time.Since(t0))
+	log.Printkv(ctx, keyvals...)
 }
 
 func (c *Chain) finalizeCommitBlock(ctx context.Context, block *legacy.Block, snapshot *state.Snapshot) error {
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"chain/log/logtest"
 	"chain/protocol/bc"
 	"chain/protocol/bc/legacy"
 	"chain/protocol/prottest/memstore"
@@ -195,6 +196,37 @@ func TestGenerateBlock(t *testing.T) {
 	}
 }
 
+func TestGenerateBlockRejected(t *testing.T) {
+	out := logtest.Capture(t)
+
+	ctx := context.Background()
+	now := time.Unix(233400000, 0)
+	c, b1 := newTestChain(t, now)
+
+	tx := legacy.NewTx(legacy.TxData{Version: 1}) // no inputs
+	got, _, err := c.GenerateBlock(ctx, b1, state.Empty(), now, []*legacy.Tx{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Transactions) != 0 {
+		t.Errorf("block has %d txs, want 0", len(got.Transactions))
+	}
+	want := logtest.Entry{"txrejected": RejectInvalid, "txid": hex.EncodeToString(tx.ID.Bytes())}
+	if e := out.Find("txrejected", RejectInvalid); len(e) != 1 || !e[0].Matches(want) {
+		t.Errorf("entries = %v, want %v", out.Entries(), want)
+	}
+
+	out.Reset()
+	ctx = WithTxSources(ctx, map[bc.Hash]string{tx.ID: "req1"})
+	_, _, err = c.GenerateBlock(ctx, b1, state.Empty(), now, []*legacy.Tx{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := out.Find("source", "req1"); len(e) != 1 {
+		t.Errorf("entries = %v, want one with source=req1", out.Entries())
+	}
+}
+
 func TestValidateBlockForSig(t *testing.T) {
 	initialBlock, err := NewInitialBlock(testutil.TestPubs, 1, time.Now())
 	if err != nil {
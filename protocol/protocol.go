@@ -178,9 +178,16 @@ func NewChain(ctx context.Context, initialBlockHash bc.Hash, store Store, height
 			case <-ctx.Done():
 				return
 			case ps := <-c.pendingSnapshots:
+				t0 := time.Now()
 				err = store.SaveSnapshot(ctx, ps.height, ps.snapshot)
 				if err != nil {
 					log.Error(ctx, err, "at", "saving snapshot")
+				} else {
+					log.Printkv(ctx,
+						"message", "snapshot saved",
+						"height", ps.height,
+						"duration", time.Since(t0),
+					)
 				}
 			}
 		}
@@ -0,0 +1,101 @@
+// Package job runs background jobs and logs what they do.
+//
+// Every entry written while a job is running,
+// by the job itself or by this package,
+// has fields "job" (its name) and "jobrun",
+// a random ID unique to one run of the job.
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"time"
+
+	"chain/log"
+)
+
+// Run runs f synchronously as the job with the given name.
+// It writes an entry when f starts and another
+// when it finishes, with its duration and error, if any.
+// If f panics, Run recovers, logs the panic with
+// a stack trace, and returns an error describing it.
+func Run(ctx context.Context, name string, f func(context.Context) error) error {
+	ctx = newRunContext(ctx, name)
+	log.Printkv(ctx, log.KeyMessage, "job started")
+	t0 := time.Now()
+	err := call(ctx, f)
+	if err != nil {
+		log.Printkv(ctx, log.KeyMessage, "job failed", log.KeyError, err, "duration", time.Since(t0))
+		return err
+	}
+	log.Printkv(ctx, log.KeyMessage, "job finished", "duration", time.Since(t0))
+	return nil
+}
+
+// Go calls Run in a new goroutine.
+func Go(ctx context.Context, name string, f func(context.Context) error) {
+	go Run(ctx, name, f)
+}
+
+// Every calls f once per period until ctx is done,
+// beginning one period after it is called.
+// Each call is a separate run, with its own ID.
+//
+// To keep frequent jobs from flooding the log, Every
+// writes an entry for a run only when it fails or panics;
+// a failed run does not stop later ones.
+// It also writes an entry when it starts and another
+// when ctx is done, with the number of runs and failures.
+func Every(ctx context.Context, name string, period time.Duration, f func(context.Context) error) {
+	loopCtx := log.AddPrefixkv(ctx, "job", name)
+	log.Printkv(loopCtx, log.KeyMessage, "periodic job started", "period", period)
+	var runs, failures int
+	defer func() {
+		log.Printkv(loopCtx, log.KeyMessage, "periodic job stopped", "runs", runs, "failures", failures)
+	}()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		runCtx := newRunContext(ctx, name)
+		t0 := time.Now()
+		err := call(runCtx, f)
+		runs++
+		if err != nil && ctx.Err() == nil {
+			failures++
+			log.Printkv(runCtx, log.KeyMessage, "job failed", log.KeyError, err, "duration", time.Since(t0))
+		}
+	}
+}
+
+// call calls f, converting a panic into an error.
+func call(ctx context.Context, f func(context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			buf := make([]byte, 64<<10)
+			buf = buf[:runtime.Stack(buf, false)]
+			log.Printkv(ctx, log.KeyMessage, "job panicked", log.KeyError, v, log.KeyStack, buf)
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return f(ctx)
+}
+
+func newRunContext(ctx context.Context, name string) context.Context {
+	return log.AddPrefixkv(ctx, "job", name, "jobrun", newRunID())
+}
+
+// newRunID returns a random ID for one run of a job.
+func newRunID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,89 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"chain/log"
+	"chain/log/logtest"
+)
+
+func TestRun(t *testing.T) {
+	out := logtest.Capture(t)
+
+	var got string
+	err := Run(context.Background(), "test", func(ctx context.Context) error {
+		log.Printkv(ctx, "step", 1)
+		return errors.New("boom")
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("Run error = %v want boom", err)
+	}
+
+	entries := out.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v, want 3", entries)
+	}
+	runID := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	for _, e := range entries {
+		if e["job"] != "test" || !runID.MatchString(e["jobrun"]) {
+			t.Fatalf("entry %v lacks job prefix", e)
+		}
+		if got == "" {
+			got = e["jobrun"]
+		} else if e["jobrun"] != got {
+			t.Errorf("jobrun = %s want %s", e["jobrun"], got)
+		}
+	}
+	if e := entries[2]; !e.Matches(logtest.Entry{"message": "job failed", "error": "boom"}) || e["duration"] == "" {
+		t.Errorf("last entry = %v", e)
+	}
+}
+
+func TestRunPanic(t *testing.T) {
+	out := logtest.Capture(t)
+
+	err := Run(context.Background(), "test", func(context.Context) error {
+		panic("oops")
+	})
+	if err == nil || err.Error() != "panic: oops" {
+		t.Errorf("Run error = %v want panic: oops", err)
+	}
+	if e := out.Find("message", "job panicked"); len(e) != 1 || e[0]["error"] != "oops" {
+		t.Errorf("entries = %v, want panic entry", out.Entries())
+	}
+}
+
+func TestEvery(t *testing.T) {
+	out := logtest.Capture(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n := 0
+	done := make(chan struct{})
+	go func() {
+		Every(ctx, "test", time.Millisecond, func(context.Context) error {
+			n++
+			switch n {
+			case 2:
+				return errors.New("boom")
+			case 3:
+				cancel()
+			}
+			return nil
+		})
+		close(done)
+	}()
+	<-done
+
+	entries := out.Entries()
+	if len(entries) != 3 {
+		t.Errorf("entries = %v, want 3", entries)
+	}
+	want := logtest.Entry{"message": "periodic job stopped", "runs": "3", "failures": "1"}
+	if e := out.Find("message", "periodic job stopped"); len(e) != 1 || !e[0].Matches(want) {
+		t.Errorf("entries = %v, want %v", entries, want)
+	}
+}
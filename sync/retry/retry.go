@@ -0,0 +1,101 @@
+// Package retry calls functions until they succeed,
+// waiting longer after each failure,
+// and logs each attempt.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"chain/log"
+)
+
+// A Policy says how many times to call a function
+// and how long to wait between calls.
+// The zero value is a valid Policy that
+// retries until its context is done,
+// waiting between 0 and 10ms after the first failure,
+// twice as long after each subsequent one,
+// to a maximum of 10s.
+type Policy struct {
+	Attempts int           // maximum calls, including the first; 0 means no limit
+	Initial  time.Duration // upper bound on the first wait; default 10ms
+	Max      time.Duration // upper bound on every wait; default 10s
+
+	// Logger, if non-nil, receives the entries
+	// written by Do in place of the package-level
+	// log output. Log sinks, which must not write
+	// to themselves, set it.
+	Logger *log.Logger
+}
+
+type permanent struct{ error }
+
+// Permanent wraps err so that Do returns it
+// immediately, without retrying.
+func Permanent(err error) error {
+	return permanent{err}
+}
+
+// Do calls f until it succeeds, it returns an error
+// wrapped with Permanent, ctx is done, or p.Attempts
+// calls have failed. It returns the last error from f,
+// unwrapped, or ctx.Err() if ctx finished first.
+//
+// Each wait is chosen at random up to a bound that
+// doubles after every failure. For every failed attempt,
+// Do writes an entry with fields "retry" (name), "attempt",
+// "error", and "backoff" (the wait, if not the last attempt).
+// If f failed at least once, Do writes a final entry
+// with the outcome, the number of attempts, and the
+// total duration.
+func Do(ctx context.Context, name string, p Policy, f func(context.Context) error) error {
+	bound := p.Initial
+	if bound <= 0 {
+		bound = 10 * time.Millisecond
+	}
+	max := p.Max
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	printkv := log.Printkv
+	if p.Logger != nil {
+		printkv = p.Logger.Printkv
+	}
+
+	t0 := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil {
+			if attempt > 1 {
+				printkv(ctx, log.KeyMessage, "retry succeeded", "retry", name, "attempts", attempt, "duration", time.Since(t0))
+			}
+			return nil
+		}
+		perm, isPerm := err.(permanent)
+		if isPerm {
+			err = perm.error
+		}
+		if isPerm || attempt == p.Attempts {
+			printkv(ctx, "retry", name, "attempt", attempt, log.KeyError, err)
+			printkv(ctx, log.KeyMessage, "retry exhausted", "retry", name, "attempts", attempt, "duration", time.Since(t0), log.KeyError, err)
+			return err
+		}
+
+		wait := time.Duration(rand.Int63n(int64(bound)))
+		printkv(ctx, "retry", name, "attempt", attempt, log.KeyError, err, "backoff", wait)
+		if bound *= 2; bound > max {
+			bound = max
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			printkv(ctx, log.KeyMessage, "retry canceled", "retry", name, "attempts", attempt, "duration", time.Since(t0), log.KeyError, ctx.Err())
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
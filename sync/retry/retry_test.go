@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chain/log"
+	"chain/log/logtest"
+)
+
+func TestDo(t *testing.T) {
+	boom := errors.New("boom")
+	cases := []struct {
+		policy Policy
+		fails  int // failures before success
+		perm   bool
+		calls  int
+		err    error
+		final  logtest.Entry
+	}{
+		{Policy{}, 0, false, 1, nil, nil},
+		{Policy{}, 2, false, 3, nil, logtest.Entry{"message": "retry succeeded", "retry": "test", "attempts": "3"}},
+		{Policy{Attempts: 2}, 5, false, 2, boom, logtest.Entry{"message": "retry exhausted", "retry": "test", "attempts": "2"}},
+		{Policy{}, 5, true, 1, boom, logtest.Entry{"message": "retry exhausted", "retry": "test", "attempts": "1"}},
+	}
+	for i, c := range cases {
+		out := new(logtest.Recorder)
+		c.policy.Logger = log.New(out)
+		c.policy.Initial = time.Microsecond
+		calls := 0
+		err := Do(context.Background(), "test", c.policy, func(context.Context) error {
+			calls++
+			if calls <= c.fails {
+				if c.perm {
+					return Permanent(boom)
+				}
+				return boom
+			}
+			return nil
+		})
+		if err != c.err {
+			t.Errorf("%d: err = %v want %v", i, err, c.err)
+		}
+		if calls != c.calls {
+			t.Errorf("%d: calls = %d want %d", i, calls, c.calls)
+		}
+		entries := out.Entries()
+		if c.final == nil {
+			if len(entries) > 0 {
+				t.Errorf("%d: entries = %v want none", i, entries)
+			}
+			continue
+		}
+		failed := c.calls
+		if c.err == nil {
+			failed--
+		}
+		attempts := 0
+		for _, e := range entries {
+			if _, ok := e["attempt"]; ok && e["retry"] == "test" {
+				attempts++
+			}
+		}
+		if attempts != failed {
+			t.Errorf("%d: %d attempt entries want %d", i, attempts, failed)
+		}
+		if n := len(entries); n == 0 || !entries[n-1].Matches(c.final) {
+			t.Errorf("%d: entries = %v want last %v", i, entries, c.final)
+		}
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{Initial: time.Hour, Logger: log.New(new(bytes.Buffer))}
+	err := Do(ctx, "test", p, func(context.Context) error {
+		cancel()
+		return errors.New("boom")
+	})
+	if err != context.Canceled {
+		t.Errorf("err = %v want %v", err, context.Canceled)
+	}
+}